@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/RuLap/trackmus-api/internal/app/auth"
 	mail_services "github.com/RuLap/trackmus-api/internal/app/mail/services"
@@ -13,6 +14,7 @@ import (
 	"github.com/RuLap/trackmus-api/internal/pkg/jwthelper"
 	"github.com/RuLap/trackmus-api/internal/pkg/logger"
 	"github.com/RuLap/trackmus-api/internal/pkg/middleware"
+	"github.com/RuLap/trackmus-api/internal/pkg/pdf"
 	"github.com/RuLap/trackmus-api/internal/pkg/rabbitmq"
 	"github.com/RuLap/trackmus-api/internal/pkg/redis"
 	"github.com/RuLap/trackmus-api/internal/pkg/server"
@@ -72,16 +74,21 @@ func main() {
 
 	minioClient, err := minio.New(&cfg.MinioConfig)
 	if err != nil {
-		logger.Error("failed to init MinIO: %w", err)
+		logger.Error("failed to init MinIO", "error", err)
 	}
 
 	minioService := minio.NewService(minioClient)
 
+	pdfFont, err := pdf.LoadFont(cfg.Reports.FontPath)
+	if err != nil {
+		logger.Warn("failed to load PDF font, reports will be transliterated", "path", cfg.Reports.FontPath, "error", err)
+	}
+
 	//Modules----------------------------------------------------------------------------------------------------------
 
 	authModule := auth.NewModule(logger, storage.Database(), jwtHelper, &cfg.GoogleOAuth, redisService, mqService)
 	userModule := user.NewModule(logger, storage.Database(), minioService)
-	taskModule := task.NewModule(logger, storage.Database(), minioService)
+	taskModule := task.NewModule(logger, storage.Database(), minioService, mqService, redisService, pdfFont)
 
 	var mailService *mail_services.MailService
 	if mqService != nil {
@@ -102,9 +109,32 @@ func main() {
 	}
 	logger.Info("Init mail service successfully")
 
+	go func() {
+		logger.Info("starting session janitor")
+		taskModule.StartSessionJanitor(context.Background(), &cfg.Sessions)
+	}()
+
+	go func() {
+		logger.Info("starting import worker")
+		taskModule.StartImportWorker(context.Background(), &cfg.Imports)
+	}()
+
+	go func() {
+		logger.Info("starting trend analyzer")
+		taskModule.StartTrendAnalyzer(context.Background(), &cfg.Trends)
+	}()
+
+	go func() {
+		logger.Info("starting workload monitor")
+		taskModule.StartWorkloadMonitor(context.Background(), &cfg.Workload)
+	}()
+
 	//Router-----------------------------------------------------------------------------------------------------------
 
 	router := chi.NewRouter()
+	idempotency := middleware.Idempotency(redisService, &cfg.Idempotency, logger, middleware.MaxIdempotentBodySize)
+	uploadIdempotency := middleware.Idempotency(redisService, &cfg.Idempotency, logger, task.MaxUploadSize)
+	publicIdempotency := middleware.PublicIdempotency(redisService, &cfg.Idempotency, logger, middleware.MaxIdempotentBodySize)
 
 	router.Use(chi_middleware.RequestID)
 	router.Use(chi_middleware.RealIP)
@@ -113,29 +143,31 @@ func main() {
 	router.Use(chi_middleware.Timeout(60 * time.Second))
 
 	router.Route("/auth", func(r chi.Router) {
-		r.Post("/register", authModule.Handler.Register)
-		r.Post("/login", authModule.Handler.Login)
-		r.Post("/google", authModule.Handler.GoogleAuth)
+		r.With(publicIdempotency).Post("/register", authModule.Handler.Register)
+		r.With(publicIdempotency).Post("/login", authModule.Handler.Login)
+		r.With(publicIdempotency).Post("/google", authModule.Handler.GoogleAuth)
 		r.Get("/google/url", authModule.Handler.GoogleAuthURL)
-		r.Post("/refresh", authModule.Handler.RefreshTokens)
+		r.With(publicIdempotency).Post("/refresh", authModule.Handler.RefreshTokens)
 
 		r.Get("/google/callback", authModule.Handler.GoogleCallback)
 
 		r.Route("/email", func(r chi.Router) {
-			r.Post("/confirm", authModule.Handler.ConfirmEmail)
-			r.With(middleware.AuthMiddleware(jwtHelper)).
+			r.With(publicIdempotency).Post("/confirm", authModule.Handler.ConfirmEmail)
+			r.With(middleware.AuthMiddleware(jwtHelper), idempotency).
 				Post("/send-confirmation", authModule.Handler.SendConfirmationLink)
 			r.With(middleware.AuthMiddleware(jwtHelper)).
 				Get("/confirmed", authModule.Handler.CheckEmailConfirmed)
 		})
 
-		r.With(middleware.AuthMiddleware(jwtHelper)).Post("/logout", authModule.Handler.Logout)
+		r.With(middleware.AuthMiddleware(jwtHelper), idempotency).Post("/logout", authModule.Handler.Logout)
 	})
 
 	router.Route("/users", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
 
 		r.Get("/{id}", userModule.Handler.GetUserByID)
+		r.Put("/", userModule.Handler.UpdateUser)
 		r.Get("/avatar/upload-url", userModule.Handler.GetAvatarUploadURL)
 		r.Post("/avatar", userModule.Handler.ConfirmAvatarUpload)
 	})
@@ -143,27 +175,71 @@ func main() {
 	router.Route("/tasks", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(jwtHelper))
 
-		r.Get("/active", taskModule.Handler.GetActiveTasks)
-		r.Get("/completed", taskModule.Handler.GetCompletedTasks)
-		r.Get("/{id}", taskModule.Handler.GetTaskByID)
-		r.Post("/", taskModule.Handler.CreateTask)
-		r.Put("/{id}/complete", taskModule.Handler.CompleteTask)
-		r.Put("/{id}", taskModule.Handler.UpdateTask)
-		r.Get("/{task_id}/media/upload-url", taskModule.Handler.GetMediaUploadURL)
+		r.With(uploadIdempotency).Post("/import/musicxml", taskModule.Handler.ImportMusicXML)
+		r.With(uploadIdempotency).Post("/import/midi", taskModule.Handler.ImportMIDI)
+
+		r.Group(func(r chi.Router) {
+			r.Use(idempotency)
+
+			r.Get("/active", taskModule.Handler.GetActiveTasks)
+			r.Get("/completed", taskModule.Handler.GetCompletedTasks)
+			r.Get("/{id}", taskModule.Handler.GetTaskByID)
+			r.Post("/", taskModule.Handler.CreateTask)
+			r.Put("/{id}/complete", taskModule.Handler.CompleteTask)
+			r.Put("/{id}", taskModule.Handler.UpdateTask)
+			r.Post("/{id}/share", taskModule.Handler.ShareTask)
+			r.Delete("/{id}/share", taskModule.Handler.UnshareTask)
+			r.Get("/{task_id}/media/upload-url", taskModule.Handler.GetMediaUploadURL)
+
+			r.Post("/{task_id}/sessions", taskModule.Handler.CreateSession)
+			r.Post("/{task_id}/sessions/start", taskModule.Handler.StartSession)
+			r.Get("/{task_id}/recommendation", taskModule.Handler.GetTempoRecommendation)
+
+			r.Post("/{task_id}/links", taskModule.Handler.CreateLink)
+
+			r.Get("/{task_id}/charts", taskModule.Handler.GetCharts)
+			r.Post("/{task_id}/charts", taskModule.Handler.CreateChart)
+		})
+	})
+
+	router.Route("/charts", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/{id}", taskModule.Handler.GetChartByID)
+		r.Put("/{id}", taskModule.Handler.UpdateChart)
+		r.Delete("/{id}", taskModule.Handler.RemoveChart)
+	})
 
-		r.Post("/{task_id}/sessions", taskModule.Handler.CreateSession)
+	router.Route("/ensembles", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
 
-		r.Post("/{task_id}/links", taskModule.Handler.CreateLink)
+		r.Get("/", taskModule.Handler.GetEnsembles)
+		r.Post("/", taskModule.Handler.CreateEnsemble)
+		r.Post("/invitations/accept", taskModule.Handler.AcceptEnsembleInvitation)
+		r.Get("/{id}", taskModule.Handler.GetEnsembleByID)
+		r.Post("/{id}/invitations", taskModule.Handler.InviteEnsembleMember)
 	})
 
 	router.Route("/sessions", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
 
+		r.Post("/batch", taskModule.Handler.CreateSessionsBatch)
 		r.Get("/{id}", taskModule.Handler.GetSessionByID)
+		r.Put("/{id}", taskModule.Handler.UpdateSession)
+		r.Delete("/{id}", taskModule.Handler.RemoveSession)
+		r.Post("/{id}/pause", taskModule.Handler.PauseSession)
+		r.Post("/{id}/resume", taskModule.Handler.ResumeSession)
+		r.Post("/{id}/heartbeat", taskModule.Handler.HeartbeatSession)
+		r.Post("/{id}/finish", taskModule.Handler.FinishSession)
+		r.Post("/{id}/reps", taskModule.Handler.AddSessionReps)
 	})
 
 	router.Route("/media", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
 
 		r.Post("/{id}", taskModule.Handler.ConfirmMediaUpload)
 		r.Delete("/{id}", taskModule.Handler.RemoveMedia)
@@ -171,10 +247,86 @@ func main() {
 
 	router.Route("/links", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
 
+		r.Put("/{id}", taskModule.Handler.UpdateLink)
 		r.Delete("/{id}", taskModule.Handler.RemoveLink)
 	})
 
+	router.Route("/sync", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/", taskModule.Handler.GetChanges)
+		r.Post("/", taskModule.Handler.PushChanges)
+	})
+
+	router.Route("/stats", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+
+		r.Get("/", taskModule.Handler.GetStats)
+	})
+
+	router.Route("/recommendations", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+
+		r.Get("/", taskModule.Handler.GetRecommendations)
+	})
+
+	router.Route("/export", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/calendar", taskModule.Handler.GetCalendarFeed)
+		r.Post("/calendar", taskModule.Handler.CreateCalendarFeed)
+		r.Delete("/calendar", taskModule.Handler.RemoveCalendarFeed)
+		r.Get("/{entity}", taskModule.Handler.Export)
+	})
+
+	router.Get("/calendar/{token}.ics", taskModule.Handler.GetCalendar)
+
+	router.Route("/imports", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(uploadIdempotency)
+
+		r.Post("/preview", taskModule.Handler.PreviewImport)
+		r.Post("/", taskModule.Handler.CreateImport)
+		r.Get("/{id}", taskModule.Handler.GetImport)
+	})
+
+	router.Route("/journal", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/", taskModule.Handler.GetJournalEntries)
+		r.Post("/", taskModule.Handler.CreateJournalEntry)
+		r.Get("/{id}", taskModule.Handler.GetJournalEntryByID)
+		r.Put("/{id}", taskModule.Handler.UpdateJournalEntry)
+		r.Delete("/{id}", taskModule.Handler.RemoveJournalEntry)
+	})
+
+	router.Route("/share", func(r chi.Router) {
+		r.Get("/{token}/progress.svg", taskModule.Handler.GetSharedProgress)
+		r.Get("/{token}/bpm.svg", taskModule.Handler.GetSharedBPMChart)
+	})
+
+	router.Route("/reports", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/", taskModule.Handler.GetReport)
+		r.Get("/practice.pdf", taskModule.Handler.GetPracticePDF)
+		r.Post("/email", taskModule.Handler.SendReport)
+	})
+
+	router.Route("/goals", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(jwtHelper))
+		r.Use(idempotency)
+
+		r.Get("/", taskModule.Handler.GetGoals)
+		r.Put("/", taskModule.Handler.SaveGoals)
+	})
+
 	//Server-----------------------------------------------------------------------------------------------------------
 
 	srv := server.New(router, cfg.HTTPServer)
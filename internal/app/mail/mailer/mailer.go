@@ -2,8 +2,11 @@ package mailer
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"path/filepath"
 	"text/template"
 )
@@ -13,6 +16,15 @@ type MailMessage struct {
 	Subject string
 	Type    string
 	Params  map[string]interface{}
+	Inline  []InlineFile
+}
+
+// InlineFile is an image sent along with the message. The template refers to
+// it as "cid:" followed by its ContentID.
+type InlineFile struct {
+	ContentID   string
+	ContentType string
+	Data        []byte
 }
 
 type Mailer struct {
@@ -45,16 +57,27 @@ func (m *Mailer) Send(msg MailMessage) error {
 		return fmt.Errorf("failed to parse template %s: %w", templatePath, err)
 	}
 
-	msgHeader := []byte("From: " + m.FromName + " <" + m.FromAddress + ">\r\n" +
-		"To: " + msg.Email + "\r\n" +
-		"Subject: " + msg.Subject + "\r\n" +
-		"MIME-version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";\r\n\r\n")
-
 	var body bytes.Buffer
 	if err := template.Execute(&body, msg.Params); err != nil {
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
 
+	contentType := "text/html; charset=\"UTF-8\""
+	if len(msg.Inline) > 0 {
+		related, boundary, err := relatedBody(body.Bytes(), msg.Inline)
+		if err != nil {
+			return fmt.Errorf("failed to build message: %w", err)
+		}
+		body.Reset()
+		body.Write(related)
+		contentType = "multipart/related; boundary=\"" + boundary + "\""
+	}
+
+	msgHeader := []byte("From: " + m.FromName + " <" + m.FromAddress + ">\r\n" +
+		"To: " + msg.Email + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-version: 1.0;\r\nContent-Type: " + contentType + ";\r\n\r\n")
+
 	bodyWithHeader := append(msgHeader, body.Bytes()...)
 
 	auth := smtp.PlainAuth("", m.User, m.Password, m.SMTPHost)
@@ -66,3 +89,49 @@ func (m *Mailer) Send(msg MailMessage) error {
 
 	return nil
 }
+
+// relatedBody puts the HTML and the inline files it refers to into one
+// multipart/related body, the way mail clients expect embedded images.
+func relatedBody(html []byte, files []InlineFile) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/html; charset=\"UTF-8\""},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(html); err != nil {
+		return nil, "", err
+	}
+
+	for _, file := range files {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {file.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + file.ContentID + ">"},
+			"Content-Disposition":       {"inline"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(file.Data)
+		for len(encoded) > 76 {
+			if _, err := part.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+				return nil, "", err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err := part.Write([]byte(encoded + "\r\n")); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), writer.Boundary(), nil
+}
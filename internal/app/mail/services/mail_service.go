@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 
@@ -52,6 +53,14 @@ func (s *MailService) handleEmailEvent(event events.EmailEvent) error {
 		return s.sendPasswordResetEmail(event)
 	case "welcome":
 		return s.sendWelcomeEmail(event)
+	case "ensemble_invitation":
+		return s.sendEnsembleInvitationEmail(event)
+	case "practice_report":
+		return s.sendPracticeReportEmail(event)
+	case "practice_trend":
+		return s.sendPracticeTrendEmail(event)
+	case "practice_workload":
+		return s.sendPracticeWorkloadEmail(event)
 	default:
 		s.log.Warn("unknown email template", "template", event.Template)
 		return fmt.Errorf("unknown email template: %s", event.Template)
@@ -130,3 +139,155 @@ func (s *MailService) sendWelcomeEmail(event events.EmailEvent) error {
 
 	return nil
 }
+
+func (s *MailService) sendEnsembleInvitationEmail(event events.EmailEvent) error {
+	s.log.Info("sending ensemble invitation email", "to", event.To)
+
+	inviteURL, _ := event.Data["invite_url"].(string)
+	userEmail, _ := event.Data["user_email"].(string)
+	ensembleTitle, _ := event.Data["ensemble_title"].(string)
+	inviterName, _ := event.Data["inviter_name"].(string)
+	partName, _ := event.Data["part_name"].(string)
+
+	msg := mailer.MailMessage{
+		Email:   userEmail,
+		Subject: "Приглашение в ансамбль",
+		Type:    "ensemble_invitation",
+		Params: map[string]interface{}{
+			"InviteURL":     inviteURL,
+			"UserEmail":     userEmail,
+			"EnsembleTitle": ensembleTitle,
+			"InviterName":   inviterName,
+			"PartName":      partName,
+		},
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("failed to send ensemble invitation email", "error", err, "to", userEmail)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MailService) sendPracticeReportEmail(event events.EmailEvent) error {
+	s.log.Info("sending practice report email", "to", event.To)
+
+	params := map[string]interface{}{}
+	for key, name := range map[string]string{
+		"user_email":           "UserEmail",
+		"from":                 "From",
+		"to":                   "To",
+		"total_hours":          "TotalHours",
+		"sessions":             "Sessions",
+		"days_practiced":       "DaysPracticed",
+		"longest_streak":       "LongestStreak",
+		"tasks_completed":      "TasksCompleted",
+		"most_practiced":       "MostPracticed",
+		"most_practiced_hours": "MostPracticedHours",
+		"bpm_jump_title":       "BPMJumpTitle",
+		"bpm_jump":             "BPMJump",
+		"busiest_month":        "BusiestMonth",
+	} {
+		value, _ := event.Data[key].(string)
+		params[name] = value
+	}
+
+	userEmail, _ := event.Data["user_email"].(string)
+
+	msg := mailer.MailMessage{
+		Email:   userEmail,
+		Subject: "Ваши итоги практики",
+		Type:    "practice_report",
+		Params:  params,
+	}
+
+	// The chart is a base64 PNG, sent as an inline attachment since mail
+	// clients drop data URIs.
+	params["BPMChart"] = ""
+	if encoded, _ := event.Data["bpm_chart"].(string); encoded != "" {
+		if chart, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			msg.Inline = append(msg.Inline, mailer.InlineFile{
+				ContentID:   "bpm-chart",
+				ContentType: "image/png",
+				Data:        chart,
+			})
+			params["BPMChart"] = "cid:bpm-chart"
+		} else {
+			s.log.Warn("failed to decode practice report chart", "error", err, "to", userEmail)
+		}
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("failed to send practice report email", "error", err, "to", userEmail)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MailService) sendPracticeTrendEmail(event events.EmailEvent) error {
+	s.log.Info("sending practice trend email", "to", event.To)
+
+	params := map[string]interface{}{}
+	for key, name := range map[string]string{
+		"user_email":        "UserEmail",
+		"task_title":        "TaskTitle",
+		"status":            "Status",
+		"reason":            "Reason",
+		"best_bpm":          "BestBPM",
+		"previous_best_bpm": "PreviousBestBPM",
+		"target_bpm":        "TargetBPM",
+	} {
+		value, _ := event.Data[key].(string)
+		params[name] = value
+	}
+
+	userEmail, _ := event.Data["user_email"].(string)
+
+	msg := mailer.MailMessage{
+		Email:   userEmail,
+		Subject: event.Subject,
+		Type:    "practice_trend",
+		Params:  params,
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("failed to send practice trend email", "error", err, "to", userEmail)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MailService) sendPracticeWorkloadEmail(event events.EmailEvent) error {
+	s.log.Info("sending practice workload email", "to", event.To)
+
+	params := map[string]interface{}{}
+	for key, name := range map[string]string{
+		"user_email":      "UserEmail",
+		"acute_minutes":   "AcuteMinutes",
+		"chronic_minutes": "ChronicMinutes",
+		"load_spike":      "LoadSpike",
+		"pain":            "Pain",
+	} {
+		value, _ := event.Data[key].(string)
+		params[name] = value
+	}
+
+	userEmail, _ := event.Data["user_email"].(string)
+
+	msg := mailer.MailMessage{
+		Email:   userEmail,
+		Subject: event.Subject,
+		Type:    "practice_workload",
+		Params:  params,
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("failed to send practice workload email", "error", err, "to", userEmail)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/pdf"
+	"github.com/RuLap/trackmus-api/internal/pkg/rabbitmq"
+	"github.com/RuLap/trackmus-api/internal/pkg/redis"
+	"github.com/google/uuid"
+)
+
+// AnalyticsService derives statistics, reports and practice advice from the
+// recorded sessions. It only reads tasks and sessions.
+type AnalyticsService interface {
+	GetStats(ctx context.Context, req *GetStatsRequest, userID uuid.UUID) (*GetStatsResponse, error)
+	GetReport(ctx context.Context, req *GetReportRequest, userID uuid.UUID) (*GetReportResponse, error)
+	SendReport(ctx context.Context, req *GetReportRequest, userID uuid.UUID, email string) error
+	RenderPracticePDF(ctx context.Context, req *GetReportRequest, userID uuid.UUID) ([]byte, error)
+
+	AnalyzeTrends(ctx context.Context, window time.Duration, minSessions int) (int, error)
+	CheckWorkloads(ctx context.Context, cooldown time.Duration) (int, error)
+	GetTempoRecommendation(ctx context.Context, req *GetTempoRecommendationRequest, taskID, userID uuid.UUID) (*GetTempoRecommendationResponse, error)
+	GetRecommendations(ctx context.Context, userID uuid.UUID) (*GetRecommendationsResponse, error)
+
+	taskAnalytics
+}
+
+type analyticsService struct {
+	log          *slog.Logger
+	rabbitmq     *rabbitmq.Service
+	redis        *redis.Service
+	pdfFont      *pdf.Font
+	taskRepo     TaskRepository
+	sessionRepo  SessionRepository
+	statsRepo    StatsRepository
+	goalRepo     GoalRepository
+	trendRepo    TrendRepository
+	workloadRepo WorkloadRepository
+}
+
+func NewAnalyticsService(
+	log *slog.Logger,
+	rabbitmq *rabbitmq.Service,
+	redis *redis.Service,
+	pdfFont *pdf.Font,
+	taskRepo TaskRepository,
+	sessionRepo SessionRepository,
+	statsRepo StatsRepository,
+	goalRepo GoalRepository,
+	trendRepo TrendRepository,
+	workloadRepo WorkloadRepository,
+) AnalyticsService {
+	return &analyticsService{
+		log:          log,
+		rabbitmq:     rabbitmq,
+		redis:        redis,
+		pdfFont:      pdfFont,
+		taskRepo:     taskRepo,
+		sessionRepo:  sessionRepo,
+		statsRepo:    statsRepo,
+		goalRepo:     goalRepo,
+		trendRepo:    trendRepo,
+		workloadRepo: workloadRepo,
+	}
+}
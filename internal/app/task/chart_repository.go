@@ -0,0 +1,166 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ChartRepository interface {
+	GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Chart, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Chart, error)
+	Create(ctx context.Context, model *Chart) (*Chart, error)
+	Update(ctx context.Context, model *Chart) (*Chart, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type chartRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewChartRepository(pool *pgxpool.Pool) ChartRepository {
+	return &chartRepository{pool}
+}
+
+func (r *chartRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Chart, error) {
+	query := `
+		SELECT id, task_id, title, COALESCE(key, ''), content, version, created_at, updated_at
+		FROM charts
+		WHERE task_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	charts := make([]Chart, 0)
+	for rows.Next() {
+		var chart Chart
+		err := rows.Scan(
+			&chart.ID,
+			&chart.TaskID,
+			&chart.Title,
+			&chart.Key,
+			&chart.Content,
+			&chart.Version,
+			&chart.CreatedAt,
+			&chart.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chart: %w", err)
+		}
+
+		charts = append(charts, chart)
+	}
+
+	return charts, nil
+}
+
+func (r *chartRepository) GetByID(ctx context.Context, id uuid.UUID) (*Chart, error) {
+	query := `
+		SELECT id, task_id, title, COALESCE(key, ''), content, version, created_at, updated_at
+		FROM charts
+		WHERE id = $1
+	`
+
+	var chart Chart
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&chart.ID,
+		&chart.TaskID,
+		&chart.Title,
+		&chart.Key,
+		&chart.Content,
+		&chart.Version,
+		&chart.CreatedAt,
+		&chart.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chart: %w", err)
+	}
+
+	return &chart, nil
+}
+
+func (r *chartRepository) Create(ctx context.Context, model *Chart) (*Chart, error) {
+	query := `
+		INSERT INTO charts(task_id, title, key, content)
+		VALUES($1, $2, $3, $4)
+		RETURNING id, version, created_at, updated_at
+	`
+
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		model.TaskID,
+		model.Title,
+		model.Key,
+		model.Content,
+	).Scan(
+		&model.ID,
+		&model.Version,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chart: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *chartRepository) Update(ctx context.Context, model *Chart) (*Chart, error) {
+	query := `
+		UPDATE charts
+		SET title = $2,
+			key = $3,
+			content = $4,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $5
+	`
+
+	tag, err := r.pool.Exec(
+		ctx,
+		query,
+		model.ID,
+		model.Title,
+		model.Key,
+		model.Content,
+		model.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update chart: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, errors.ErrVersionConflict
+	}
+
+	model.Version++
+
+	return model, nil
+}
+
+func (r *chartRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM charts
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete chart: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/chordpro"
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const defaultChartTitle = "Без названия"
+
+func (s *service) GetCharts(ctx context.Context, taskID, userID uuid.UUID) ([]GetChartShortResponse, error) {
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	charts, err := s.chartRepo.GetByTaskID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get charts from repository", "taskID", taskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	result := make([]GetChartShortResponse, 0)
+	for _, c := range charts {
+		dto := ChartToGetShortResponse(&c)
+		result = append(result, dto)
+	}
+
+	return result, nil
+}
+
+func (s *service) GetChartByID(ctx context.Context, req *RenderChartRequest, id, userID uuid.UUID) (*GetChartResponse, error) {
+	chart, err := s.getOwnChart(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.renderChart(chart, req)
+}
+
+func (s *service) CreateChart(ctx context.Context, req *SaveChartRequest, taskID, userID uuid.UUID) (*GetChartResponse, error) {
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	model := SaveRequestToChart(req, taskID)
+	if err := s.parseChart(&model); err != nil {
+		return nil, err
+	}
+
+	chart, err := s.chartRepo.Create(ctx, &model)
+	if err != nil {
+		s.log.Error("failed to create chart in repository",
+			"taskID", taskID,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.renderChart(chart, &RenderChartRequest{})
+}
+
+func (s *service) UpdateChart(ctx context.Context, req *SaveChartRequest, id, userID uuid.UUID) (*GetChartResponse, error) {
+	current, err := s.getOwnChart(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	model := SaveRequestToChart(req, current.TaskID)
+	model.ID = id
+	if err := s.parseChart(&model); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.chartRepo.Update(ctx, &model); err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("chart version conflict", "id", id, "version", req.Version)
+			return nil, s.chartConflict(ctx, id)
+		}
+		s.log.Error("failed to update chart in repository",
+			"id", id,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.GetChartByID(ctx, &RenderChartRequest{}, id, userID)
+}
+
+func (s *service) RemoveChart(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwnChart(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.chartRepo.Delete(ctx, id); err != nil {
+		s.log.Error("failed to delete chart from repository", "id", id, "error", err)
+		return fmt.Errorf(errors.ErrFailedToDeleteData)
+	}
+
+	return nil
+}
+
+func (s *service) getOwnChart(ctx context.Context, id, userID uuid.UUID) (*Chart, error) {
+	chart, err := s.chartRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get chart from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if err := s.checkTaskOwner(ctx, chart.TaskID, userID); err != nil {
+		return nil, err
+	}
+
+	return chart, nil
+}
+
+// parseChart validates the ChordPro content and fills the fields derived from
+// it. Parse errors are returned as chordpro.Errors so the client can show them
+// next to the offending lines.
+func (s *service) parseChart(chart *Chart) error {
+	song, err := chordpro.Parse(chart.Content)
+	if err != nil {
+		if list, ok := err.(chordpro.Errors); ok {
+			return list
+		}
+		s.log.Error("failed to read chart content", "error", err)
+		return fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	chart.Title = truncate(firstNonEmpty(chart.Title, song.Title, defaultChartTitle), 100)
+	chart.Key = truncate(song.Key, 10)
+
+	return nil
+}
+
+// renderChart transposes the chart for display. The chords of a chart with a
+// capo are already written as shapes for it, so they move by req.Transpose
+// and only change further when req.Capo asks for a different capo.
+func (s *service) renderChart(chart *Chart, req *RenderChartRequest) (*GetChartResponse, error) {
+	song, err := chordpro.Parse(chart.Content)
+	if err != nil {
+		s.log.Error("failed to parse stored chart", "id", chart.ID, "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidChart)
+	}
+
+	capo := song.Capo
+	if req.Capo != nil {
+		capo = *req.Capo
+	}
+
+	key := song.Key
+	if key != "" {
+		key, _ = chordpro.TransposeKey(key, req.Transpose)
+	}
+
+	shapes := song.Transpose(req.Transpose + song.Capo - capo)
+	if song.Key != "" {
+		shapes.Key, _ = chordpro.TransposeKey(song.Key, req.Transpose-capo)
+	}
+	shapes.Title = chart.Title
+	shapes.Capo = capo
+
+	result := ChartToGetResponse(chart, shapes, key, req.Transpose, capo)
+	if req.Format == "html" {
+		result.HTML = chordpro.RenderHTML(shapes)
+	}
+
+	return &result, nil
+}
+
+func (s *service) chartConflict(ctx context.Context, id uuid.UUID) error {
+	chart, err := s.chartRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get chart from repository", "id", id, "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	current, err := s.renderChart(chart, &RenderChartRequest{})
+	if err != nil {
+		return err
+	}
+
+	return &errors.ConflictError{Current: current}
+}
@@ -0,0 +1,105 @@
+package task
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestRenderChartTransposeAndCapo(t *testing.T) {
+	const content = "{title: Song}\n{key: A}\n{capo: 2}\n[G]One [C]two [D]three\n"
+
+	capo := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		req       RenderChartRequest
+		wantCapo  int
+		wantKey   string
+		wantShape string
+		wantChord []string
+	}{
+		{
+			name:      "capo of the chart",
+			req:       RenderChartRequest{},
+			wantCapo:  2,
+			wantKey:   "A",
+			wantShape: "G",
+			wantChord: []string{"G", "C", "D"},
+		},
+		{
+			name:      "transposed with the same capo",
+			req:       RenderChartRequest{Transpose: 2},
+			wantCapo:  2,
+			wantKey:   "B",
+			wantShape: "A",
+			wantChord: []string{"A", "D", "E"},
+		},
+		{
+			name:      "without capo",
+			req:       RenderChartRequest{Capo: capo(0)},
+			wantCapo:  0,
+			wantKey:   "A",
+			wantShape: "A",
+			wantChord: []string{"A", "D", "E"},
+		},
+		{
+			name:      "higher capo",
+			req:       RenderChartRequest{Capo: capo(4)},
+			wantCapo:  4,
+			wantKey:   "A",
+			wantShape: "F",
+			wantChord: []string{"F", "A#", "C"},
+		},
+		{
+			name:      "transposed down with another capo",
+			req:       RenderChartRequest{Transpose: -2, Capo: capo(0)},
+			wantCapo:  0,
+			wantKey:   "G",
+			wantShape: "G",
+			wantChord: []string{"G", "C", "D"},
+		},
+	}
+
+	s := &service{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.renderChart(&Chart{Content: content}, &tt.req)
+			if err != nil {
+				t.Fatalf("renderChart() error = %v", err)
+			}
+
+			if result.Capo != tt.wantCapo {
+				t.Errorf("Capo = %d, want %d", result.Capo, tt.wantCapo)
+			}
+			if result.Key != tt.wantKey {
+				t.Errorf("Key = %q, want %q", result.Key, tt.wantKey)
+			}
+			if result.ChordKey != tt.wantShape {
+				t.Errorf("ChordKey = %q, want %q", result.ChordKey, tt.wantShape)
+			}
+
+			chords := make([]string, 0)
+			for _, section := range result.Sections {
+				for _, line := range section.Lines {
+					for _, segment := range line.Segments {
+						if segment.Chord != "" {
+							chords = append(chords, segment.Chord)
+						}
+					}
+				}
+			}
+
+			if len(chords) != len(tt.wantChord) {
+				t.Fatalf("chords = %v, want %v", chords, tt.wantChord)
+			}
+			for i := range chords {
+				if chords[i] != tt.wantChord[i] {
+					t.Errorf("chords = %v, want %v", chords, tt.wantChord)
+					break
+				}
+			}
+		})
+	}
+}
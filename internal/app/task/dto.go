@@ -2,47 +2,212 @@ package task
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Task -------------------------------------------------------------------------------------
 type GetTaskShortResponse struct {
-	ID        string  `json:"id"`
-	Title     string  `json:"title"`
-	TargetBPM int     `json:"target_bpm"`
-	Progress  float64 `json:"progress"`
+	ID        string                `json:"id"`
+	Title     string                `json:"title"`
+	TargetBPM int                   `json:"target_bpm"`
+	Progress  float64               `json:"progress"`
+	Tags      []string              `json:"tags"`
+	Trend     *GetTaskTrendResponse `json:"trend,omitempty"`
+	Version   int                   `json:"version"`
+}
+
+// GetTaskTrendResponse is set on tasks whose tempo has stalled or dropped.
+type GetTaskTrendResponse struct {
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	BestBPM            int       `json:"best_bpm"`
+	PreviousBestBPM    int       `json:"previous_best_bpm"`
+	Confidence         float64   `json:"confidence"`
+	PreviousConfidence float64   `json:"previous_confidence"`
+	DetectedAt         time.Time `json:"detected_at"`
 }
 
 type GetTaskResponse struct {
-	ID        string               `json:"id"`
-	Title     string               `json:"title"`
-	TargetBPM int                  `json:"target_bpm"`
-	Sessions  []GetSessionResponse `json:"sessions"`
-	Media     []GetMediaResponse   `json:"media"`
-	Links     []GetLinkResponse    `json:"links"`
+	ID            string                 `json:"id"`
+	Title         string                 `json:"title"`
+	TargetBPM     int                    `json:"target_bpm"`
+	Composer      string                 `json:"composer"`
+	TimeSignature string                 `json:"time_signature"`
+	Duration      int                    `json:"duration"`
+	CleanRepsGoal int                    `json:"clean_reps_goal"`
+	Tags          []string               `json:"tags"`
+	Sections      []GetSectionResponse   `json:"sections"`
+	TempoMarks    []GetTempoMarkResponse `json:"tempo_marks"`
+	Sessions      []GetSessionResponse   `json:"sessions"`
+	Media         []GetMediaResponse     `json:"media"`
+	Links         []GetLinkResponse      `json:"links"`
+	DueDate       *string                `json:"due_date"`
+	Forecast      *GetForecastResponse   `json:"forecast"`
+	Version       int                    `json:"version"`
 }
 
+// GetForecastResponse estimates the day the task reaches its target tempo,
+// in the user's time zone. ETAEarliest and ETALatest bound it; ETALatest is
+// empty when the slow end of the estimate never gets there. DueMarginDays is
+// how many days the ETA is ahead of the due date, negative when behind.
+type GetForecastResponse struct {
+	Status        string `json:"status"`
+	CurrentBPM    int    `json:"current_bpm"`
+	TargetBPM     int    `json:"target_bpm"`
+	ETA           string `json:"eta,omitempty"`
+	ETAEarliest   string `json:"eta_earliest,omitempty"`
+	ETALatest     string `json:"eta_latest,omitempty"`
+	DueDate       string `json:"due_date,omitempty"`
+	OnSchedule    *bool  `json:"on_schedule,omitempty"`
+	DueMarginDays *int   `json:"due_margin_days,omitempty"`
+	PracticeDays  int    `json:"practice_days"`
+}
+
+// SaveTaskRequest leaves the clean reps goal, the tags and the due date of a
+// task as they are when omitted; an empty list or due date clears them.
 type SaveTaskRequest struct {
-	Title     string `json:"title" validate:"required,min=1,max=50"`
-	TargetBPM int    `json:"target_bpm" validate:"required,number"`
+	Title         string   `json:"title" validate:"required,min=1,max=50"`
+	TargetBPM     int      `json:"target_bpm" validate:"required,number"`
+	CleanRepsGoal *int     `json:"clean_reps_goal" validate:"omitnil,min=0,max=100"`
+	Tags          []string `json:"tags" validate:"max=10,dive,min=1,max=30"`
+	DueDate       *string  `json:"due_date" validate:"omitnil,len=0|datetime=2006-01-02"`
+	Version       int      `json:"version"`
+}
+
+type ImportScoreRequest struct {
+	Title     string `validate:"max=50"`
+	TargetBPM int    `validate:"min=0"`
+}
+
+// Score -------------------------------------------------------------------------------------
+type GetSectionResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	StartBar int    `json:"start_bar"`
+	EndBar   int    `json:"end_bar"`
+}
+
+type GetTempoMarkResponse struct {
+	Bar  int     `json:"bar"`
+	BPM  float64 `json:"bpm"`
+	Text string  `json:"text"`
 }
 
 // Session -------------------------------------------------------------------------------------
 type GetSessionResponse struct {
-	ID         string    `json:"id"`
-	BPM        int       `json:"bpm"`
-	Note       string    `json:"note"`
-	Confidence int       `json:"confidence"`
-	StartTime  time.Time `json:"start_time"`
-	EndTime    time.Time `json:"end_time"`
-	Duration   int       `json:"duration"`
+	ID         string                    `json:"id"`
+	BPM        int                       `json:"bpm"`
+	Note       string                    `json:"note"`
+	Confidence int                       `json:"confidence"`
+	Pain       int                       `json:"pain"`
+	Tension    int                       `json:"tension"`
+	Status     string                    `json:"status"`
+	StartTime  time.Time                 `json:"start_time"`
+	EndTime    *time.Time                `json:"end_time"`
+	Duration   int                       `json:"duration"`
+	Pauses     []GetSessionPauseResponse `json:"pauses"`
+	Segments   []GetSegmentResponse      `json:"segments"`
+	Reps       []GetRepResponse          `json:"reps"`
+	RepStats   GetRepStatsResponse       `json:"rep_stats"`
+	Version    int                       `json:"version"`
 }
 
+type GetRepResponse struct {
+	Clean    bool       `json:"clean"`
+	PlayedAt *time.Time `json:"played_at"`
+}
+
+type GetRepStatsResponse struct {
+	Total              int     `json:"total"`
+	Clean              int     `json:"clean"`
+	Flawed             int     `json:"flawed"`
+	LongestCleanStreak int     `json:"longest_clean_streak"`
+	CurrentCleanStreak int     `json:"current_clean_streak"`
+	SuccessRatio       float64 `json:"success_ratio"`
+}
+
+type GetSegmentResponse struct {
+	BPM        int     `json:"bpm"`
+	Duration   int     `json:"duration"`
+	Reps       int     `json:"reps"`
+	Confidence int     `json:"confidence"`
+	SectionID  *string `json:"section_id"`
+}
+
+type GetSessionPauseResponse struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+}
+
+// SaveSessionRequest takes either a single bpm and confidence or a list of
+// segments they are derived from. Pain and tension are optional self-reports
+// from 1 to 5. An update leaves the segments and reps of the session as they
+// are when omitted; an empty list clears them.
 type SaveSessionRequest struct {
-	BPM        int       `json:"bpm" validate:"required,number"`
-	Note       string    `json:"note"`
-	Confidence int       `json:"confidence" validate:"required,number,min=1,max=5"`
-	StartTime  time.Time `json:"start_time" validate:"required"`
-	EndTime    time.Time `json:"end_time" validate:"required"`
+	BPM        int                  `json:"bpm" validate:"required_without=Segments"`
+	Note       string               `json:"note"`
+	Confidence int                  `json:"confidence" validate:"required_without=Segments,omitempty,min=1,max=5"`
+	Pain       int                  `json:"pain" validate:"min=0,max=5"`
+	Tension    int                  `json:"tension" validate:"min=0,max=5"`
+	StartTime  time.Time            `json:"start_time" validate:"required"`
+	EndTime    time.Time            `json:"end_time" validate:"required,gtfield=StartTime"`
+	Segments   []SaveSegmentRequest `json:"segments" validate:"max=50,dive"`
+	Reps       []SaveRepRequest     `json:"reps" validate:"max=500"`
+	Version    int                  `json:"version"`
+}
+
+type BatchSessionsRequest struct {
+	Sessions []BatchSessionItemRequest `json:"sessions" validate:"required,min=1,max=200"`
+}
+
+// BatchSessionItemRequest is a session recorded offline. ClientID is the
+// temporary id the client gave it; uploading the same item again does not
+// create a duplicate.
+type BatchSessionItemRequest struct {
+	ClientID string    `json:"client_id" validate:"required,max=100"`
+	TaskID   uuid.UUID `json:"task_id" validate:"required"`
+	SaveSessionRequest
+}
+
+type BatchSessionsResponse struct {
+	Results []BatchSessionResult `json:"results"`
+	Created int                  `json:"created"`
+	Failed  int                  `json:"failed"`
+}
+
+type BatchSessionResult struct {
+	ClientID string            `json:"client_id"`
+	ID       string            `json:"id,omitempty"`
+	Status   string            `json:"status"`
+	Message  string            `json:"message,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+type SaveSegmentRequest struct {
+	BPM        int        `json:"bpm" validate:"required,number"`
+	Duration   int        `json:"duration" validate:"min=0"`
+	Reps       int        `json:"reps" validate:"min=0"`
+	Confidence int        `json:"confidence" validate:"required,number,min=1,max=5"`
+	SectionID  *uuid.UUID `json:"section_id"`
+}
+
+type SaveRepRequest struct {
+	Clean    bool       `json:"clean"`
+	PlayedAt *time.Time `json:"played_at"`
+}
+
+type AddRepsRequest struct {
+	Reps []SaveRepRequest `json:"reps" validate:"required,min=1,max=100"`
+}
+
+type FinishSessionRequest struct {
+	BPM        int                  `json:"bpm" validate:"required_without=Segments"`
+	Note       string               `json:"note"`
+	Confidence int                  `json:"confidence" validate:"required_without=Segments,omitempty,min=1,max=5"`
+	Pain       int                  `json:"pain" validate:"min=0,max=5"`
+	Tension    int                  `json:"tension" validate:"min=0,max=5"`
+	Segments   []SaveSegmentRequest `json:"segments" validate:"max=50,dive"`
 }
 
 // Media -------------------------------------------------------------------------------------
@@ -62,6 +227,7 @@ type GetUploadURLResponse struct {
 }
 
 type ConfirmMediaUploadRequest struct {
+	TaskID   uuid.UUID `json:"task_id" validate:"required"`
 	Type     MediaType `json:"type" validate:"required"`
 	Filename string    `json:"filename" validate:"required,min=1"`
 	Size     int64     `json:"size" validate:"required,number"`
@@ -74,10 +240,639 @@ type GetLinkResponse struct {
 	URL       string    `json:"url"`
 	Title     string    `json:"title"`
 	Type      string    `json:"type"`
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type SaveLinkRequest struct {
-	Title string   `json:"title" validate:"required,min=1,max=50"`
-	Type  LinkType `json:"type" validate:"required,min=1,max=50"`
+	Title   string   `json:"title" validate:"required,min=1,max=50"`
+	Type    LinkType `json:"type" validate:"required,min=1,max=50"`
+	Version int      `json:"version"`
+}
+
+// Chart -------------------------------------------------------------------------------------
+type GetChartShortResponse struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Key       string    `json:"key"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetChartResponse struct {
+	ID        string                    `json:"id"`
+	TaskID    string                    `json:"task_id"`
+	Title     string                    `json:"title"`
+	Subtitle  string                    `json:"subtitle"`
+	Artist    string                    `json:"artist"`
+	Key       string                    `json:"key"`
+	ChordKey  string                    `json:"chord_key"`
+	Transpose int                       `json:"transpose"`
+	Capo      int                       `json:"capo"`
+	Tempo     int                       `json:"tempo"`
+	Time      string                    `json:"time"`
+	Sections  []GetChartSectionResponse `json:"sections"`
+	Content   string                    `json:"content"`
+	HTML      string                    `json:"html,omitempty"`
+	Version   int                       `json:"version"`
+	CreatedAt time.Time                 `json:"created_at"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+}
+
+type GetChartSectionResponse struct {
+	Type  string                 `json:"type"`
+	Label string                 `json:"label"`
+	Lines []GetChartLineResponse `json:"lines"`
+}
+
+type GetChartLineResponse struct {
+	Type     string                    `json:"type"`
+	Text     string                    `json:"text,omitempty"`
+	Segments []GetChartSegmentResponse `json:"segments,omitempty"`
+}
+
+type GetChartSegmentResponse struct {
+	Chord  string `json:"chord"`
+	Lyrics string `json:"lyrics"`
+}
+
+type SaveChartRequest struct {
+	Title   string `json:"title" validate:"max=100"`
+	Content string `json:"content" validate:"required,max=100000"`
+	Version int    `json:"version"`
+}
+
+// RenderChartRequest describes how a chart is shown: Transpose shifts the
+// sounding key, Capo overrides the capo of the chart when set.
+type RenderChartRequest struct {
+	Transpose int    `validate:"min=-11,max=11"`
+	Capo      *int   `validate:"omitempty,min=0,max=11"`
+	Format    string `validate:"omitempty,oneof=json html"`
+}
+
+// Sync -------------------------------------------------------------------------------------
+type GetSyncResponse struct {
+	Token    string                   `json:"token"`
+	Tasks    []GetSyncTaskResponse    `json:"tasks"`
+	Sessions []GetSyncSessionResponse `json:"sessions"`
+	Media    []GetSyncMediaResponse   `json:"media"`
+	Links    []GetSyncLinkResponse    `json:"links"`
+	Deleted  []GetTombstoneResponse   `json:"deleted"`
+}
+
+type GetSyncTaskResponse struct {
+	GetTaskShortResponse
+	Composer      string    `json:"composer"`
+	TimeSignature string    `json:"time_signature"`
+	Duration      int       `json:"duration"`
+	CleanRepsGoal int       `json:"clean_reps_goal"`
+	DueDate       *string   `json:"due_date"`
+	IsCompleted   bool      `json:"is_completed"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type GetSyncSessionResponse struct {
+	GetSessionResponse
+	TaskID    string    `json:"task_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetSyncMediaResponse struct {
+	GetMediaResponse
+	TaskID    string    `json:"task_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetSyncLinkResponse struct {
+	GetLinkResponse
+	TaskID    string    `json:"task_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetTombstoneResponse struct {
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncPushRequest carries the changes an offline client made. Items without
+// an id are created, the others are updated against the version they carry.
+type SyncPushRequest struct {
+	Tasks    []SyncTaskChange    `json:"tasks" validate:"max=200,dive"`
+	Sessions []SyncSessionChange `json:"sessions" validate:"max=200,dive"`
+	Links    []SyncLinkChange    `json:"links" validate:"max=200,dive"`
+	Deleted  []SyncDeletion      `json:"deleted" validate:"max=200,dive"`
+}
+
+type SyncTaskChange struct {
+	ID *uuid.UUID `json:"id"`
+	SaveTaskRequest
+}
+
+type SyncSessionChange struct {
+	ID       *uuid.UUID `json:"id"`
+	ClientID string     `json:"client_id" validate:"required_without=ID,max=100"`
+	TaskID   uuid.UUID  `json:"task_id" validate:"required"`
+	SaveSessionRequest
+}
+
+type SyncLinkChange struct {
+	ID     *uuid.UUID `json:"id"`
+	TaskID uuid.UUID  `json:"task_id" validate:"required"`
+	SaveLinkRequest
+}
+
+// SyncDeletion takes the version of the deleted session or link; media has
+// no version.
+type SyncDeletion struct {
+	Type    EntityType `json:"type" validate:"required,oneof=session media link"`
+	ID      uuid.UUID  `json:"id" validate:"required"`
+	Version int        `json:"version"`
+}
+
+type SyncPushResponse struct {
+	Results   []SyncChangeResult `json:"results"`
+	Applied   int                `json:"applied"`
+	Conflicts int                `json:"conflicts"`
+	Failed    int                `json:"failed"`
+}
+
+// SyncChangeResult reports the outcome of one pushed change. On conflict
+// Current holds the server state the client has to merge with.
+type SyncChangeResult struct {
+	Type     string      `json:"type"`
+	ID       string      `json:"id,omitempty"`
+	ClientID string      `json:"client_id,omitempty"`
+	Status   string      `json:"status"`
+	Message  string      `json:"message,omitempty"`
+	Current  interface{} `json:"current,omitempty"`
+}
+
+// Stats -------------------------------------------------------------------------------------
+
+// GetStatsRequest selects the range and grouping of the statistics. Dates are
+// days in TimeZone, which defaults to the time zone of the user.
+type GetStatsRequest struct {
+	Period   string `validate:"omitempty,oneof=day week month"`
+	From     string `validate:"omitempty,datetime=2006-01-02"`
+	To       string `validate:"omitempty,datetime=2006-01-02"`
+	Year     int    `validate:"omitempty,min=2000,max=2100"`
+	TimeZone string `validate:"omitempty,timezone"`
+}
+
+type GetStatsResponse struct {
+	TimeZone string                   `json:"time_zone"`
+	Period   string                   `json:"period"`
+	From     string                   `json:"from"`
+	To       string                   `json:"to"`
+	Totals   GetStatsTotalsResponse   `json:"totals"`
+	Periods  []GetStatsPeriodResponse `json:"periods"`
+	Tasks    []GetTaskStatsResponse   `json:"tasks"`
+	Tags     []GetTagStatsResponse    `json:"tags"`
+	Heatmap  GetHeatmapResponse       `json:"heatmap"`
+	Workload GetWorkloadResponse      `json:"workload"`
+}
+
+type GetStatsTotalsResponse struct {
+	PracticeSeconds   int     `json:"practice_seconds"`
+	Sessions          int     `json:"sessions"`
+	AverageConfidence float64 `json:"average_confidence"`
+	BPMGain           int     `json:"bpm_gain"`
+}
+
+type GetStatsPeriodResponse struct {
+	Start string `json:"start"`
+	GetStatsTotalsResponse
+}
+
+type GetTaskStatsResponse struct {
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	StartBPM int    `json:"start_bpm"`
+	BestBPM  int    `json:"best_bpm"`
+	GetStatsTotalsResponse
+}
+
+type GetTagStatsResponse struct {
+	Tag string `json:"tag"`
+	GetStatsTotalsResponse
+}
+
+type GetHeatmapResponse struct {
+	Year       int                     `json:"year"`
+	MaxMinutes int                     `json:"max_minutes"`
+	Days       []GetHeatmapDayResponse `json:"days"`
+}
+
+type GetHeatmapDayResponse struct {
+	Date    string `json:"date"`
+	Minutes int    `json:"minutes"`
+}
+
+// GetWorkloadResponse compares the practice of the last week with the weekly
+// average of the last four. Ratio is 0 when there is no earlier practice to
+// compare with.
+type GetWorkloadResponse struct {
+	AcuteMinutes   int                          `json:"acute_minutes"`
+	ChronicMinutes int                          `json:"chronic_minutes"`
+	Ratio          float64                      `json:"ratio"`
+	PainReports    int                          `json:"pain_reports"`
+	Warnings       []GetWorkloadWarningResponse `json:"warnings"`
+}
+
+type GetWorkloadWarningResponse struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Goals -------------------------------------------------------------------------------------
+type GetGoalsResponse struct {
+	Goals    GetGoalSettingsResponse `json:"goals"`
+	TimeZone string                  `json:"time_zone"`
+	Today    GetGoalDayResponse      `json:"today"`
+	Week     GetGoalWeekResponse     `json:"week"`
+	Streak   GetStreakResponse       `json:"streak"`
+	Version  int                     `json:"version"`
+}
+
+type GetGoalSettingsResponse struct {
+	MinutesPerDay   int  `json:"minutes_per_day"`
+	DaysPerWeek     int  `json:"days_per_week"`
+	SessionsPerWeek int  `json:"sessions_per_week"`
+	GraceDay        bool `json:"grace_day"`
+}
+
+type GetGoalDayResponse struct {
+	Date     string  `json:"date"`
+	Minutes  int     `json:"minutes"`
+	Sessions int     `json:"sessions"`
+	Progress float64 `json:"progress"`
+	Met      bool    `json:"met"`
+}
+
+type GetGoalWeekResponse struct {
+	Start            string  `json:"start"`
+	Minutes          int     `json:"minutes"`
+	DaysPracticed    int     `json:"days_practiced"`
+	Sessions         int     `json:"sessions"`
+	DaysProgress     float64 `json:"days_progress"`
+	SessionsProgress float64 `json:"sessions_progress"`
+	Met              bool    `json:"met"`
+}
+
+// GetStreakResponse counts days in a row on which the daily goal was met, or
+// on which the user practiced at all when there is no daily goal. Today only
+// extends the streak; AtRisk tells that the streak ends unless the user
+// practices today.
+type GetStreakResponse struct {
+	Current          int    `json:"current"`
+	Longest          int    `json:"longest"`
+	PracticedToday   bool   `json:"practiced_today"`
+	AtRisk           bool   `json:"at_risk"`
+	LastPracticeDate string `json:"last_practice_date,omitempty"`
+}
+
+type SaveGoalsRequest struct {
+	MinutesPerDay   int  `json:"minutes_per_day" validate:"min=0,max=1440"`
+	DaysPerWeek     int  `json:"days_per_week" validate:"min=0,max=7"`
+	SessionsPerWeek int  `json:"sessions_per_week" validate:"min=0,max=100"`
+	GraceDay        bool `json:"grace_day"`
+	Version         int  `json:"version"`
+}
+
+// Report ------------------------------------------------------------------------------------
+type GetReportRequest struct {
+	From     string `validate:"omitempty,datetime=2006-01-02"`
+	To       string `validate:"omitempty,datetime=2006-01-02"`
+	Year     int    `validate:"omitempty,min=2000,max=2100"`
+	TimeZone string `validate:"omitempty,timezone"`
+}
+
+type GetReportResponse struct {
+	TimeZone        string                       `json:"time_zone"`
+	From            string                       `json:"from"`
+	To              string                       `json:"to"`
+	GeneratedAt     time.Time                    `json:"generated_at"`
+	PracticeSeconds int                          `json:"practice_seconds"`
+	TotalHours      float64                      `json:"total_hours"`
+	Sessions        int                          `json:"sessions"`
+	DaysPracticed   int                          `json:"days_practiced"`
+	LongestStreak   int                          `json:"longest_streak"`
+	TasksCompleted  int                          `json:"tasks_completed"`
+	MostPracticed   *GetReportTaskResponse       `json:"most_practiced,omitempty"`
+	BiggestBPMJump  *GetReportBPMJumpResponse    `json:"biggest_bpm_jump,omitempty"`
+	BusiestMonth    *GetReportMonthResponse      `json:"busiest_month,omitempty"`
+	CompletedTasks  []GetReportCompletedResponse `json:"completed_tasks"`
+}
+
+type GetReportTaskResponse struct {
+	TaskID          string `json:"task_id"`
+	Title           string `json:"title"`
+	PracticeSeconds int    `json:"practice_seconds"`
+	Sessions        int    `json:"sessions"`
+}
+
+type GetReportBPMJumpResponse struct {
+	TaskID  string `json:"task_id"`
+	Title   string `json:"title"`
+	FromBPM int    `json:"from_bpm"`
+	ToBPM   int    `json:"to_bpm"`
+	Gain    int    `json:"gain"`
+}
+
+type GetReportMonthResponse struct {
+	Month           string `json:"month"`
+	PracticeSeconds int    `json:"practice_seconds"`
+	Sessions        int    `json:"sessions"`
+}
+
+type GetReportCompletedResponse struct {
+	TaskID      string    `json:"task_id"`
+	Title       string    `json:"title"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Share -------------------------------------------------------------------------------------
+type GetTaskShareResponse struct {
+	TaskID      string    `json:"task_id"`
+	Token       string    `json:"token"`
+	ProgressURL string    `json:"progress_url"`
+	BPMChartURL string    `json:"bpm_chart_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Export ------------------------------------------------------------------------------------
+type ExportRequest struct {
+	Entity string `validate:"required,oneof=tasks sessions links media journal"`
+	Format string `validate:"required,oneof=csv json"`
+}
+
+type ExportTaskResponse struct {
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Composer      string     `json:"composer"`
+	TimeSignature string     `json:"time_signature"`
+	TargetBPM     int        `json:"target_bpm"`
+	Duration      int        `json:"duration"`
+	CleanRepsGoal int        `json:"clean_reps_goal"`
+	Tags          []string   `json:"tags"`
+	IsCompleted   bool       `json:"is_completed"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type ExportSessionResponse struct {
+	ID              string    `json:"id"`
+	TaskID          string    `json:"task_id"`
+	TaskTitle       string    `json:"task_title"`
+	Status          string    `json:"status"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationSeconds int       `json:"duration_seconds"`
+	BPM             int       `json:"bpm"`
+	Confidence      int       `json:"confidence"`
+	Note            string    `json:"note"`
+}
+
+type ExportLinkResponse struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Title     string    `json:"title"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportMediaResponse struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Type      string    `json:"type"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Duration  int       `json:"duration"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportJournalEntryResponse struct {
+	ID         string    `json:"id"`
+	Date       string    `json:"date"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	Mood       int       `json:"mood"`
+	Energy     int       `json:"energy"`
+	TaskIDs    []string  `json:"task_ids"`
+	SessionIDs []string  `json:"session_ids"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type GetCalendarFeedResponse struct {
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Recommendation ----------------------------------------------------------------------------
+// GetTempoRecommendationRequest overrides the aggressiveness of the user's
+// profile when set.
+type GetTempoRecommendationRequest struct {
+	Aggressiveness string `validate:"omitempty,oneof=cautious moderate aggressive"`
+}
+
+type GetTempoRecommendationResponse struct {
+	Action          string  `json:"action"`
+	BPM             int     `json:"bpm"`
+	CurrentBPM      int     `json:"current_bpm"`
+	TargetBPM       int     `json:"target_bpm"`
+	DurationMinutes int     `json:"duration_minutes"`
+	Confidence      float64 `json:"confidence"`
+	Sessions        int     `json:"sessions"`
+	Aggressiveness  string  `json:"aggressiveness"`
+	Explanation     string  `json:"explanation"`
+}
+
+// GetRecommendationsResponse lists the weakest tags of the user's active tasks,
+// weakest first, and what to practice for each of them.
+type GetRecommendationsResponse struct {
+	WeakAreas []GetWeakAreaResponse           `json:"weak_areas"`
+	Items     []GetRecommendationItemResponse `json:"items"`
+}
+
+// GetWeakAreaResponse describes a tag. Score is from 0 (strong) to 1 (weak);
+// Progress is the average share of the target tempo reached.
+type GetWeakAreaResponse struct {
+	Tag        string  `json:"tag"`
+	Score      float64 `json:"score"`
+	Confidence float64 `json:"confidence"`
+	Progress   float64 `json:"progress"`
+	Tasks      int     `json:"tasks"`
+	Stalled    int     `json:"stalled"`
+	Rationale  string  `json:"rationale"`
+}
+
+type GetRecommendationItemResponse struct {
+	Kind        string `json:"kind"`
+	Tag         string `json:"tag"`
+	TaskID      string `json:"task_id,omitempty"`
+	ExerciseID  string `json:"exercise_id,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	TargetBPM   int    `json:"target_bpm"`
+	Rationale   string `json:"rationale"`
+}
+
+// Journal -----------------------------------------------------------------------------------
+type SaveJournalEntryRequest struct {
+	Date       string   `json:"date" validate:"required,datetime=2006-01-02"`
+	Title      string   `json:"title" validate:"max=100"`
+	Body       string   `json:"body" validate:"required,max=20000"`
+	Mood       int      `json:"mood" validate:"min=0,max=5"`
+	Energy     int      `json:"energy" validate:"min=0,max=5"`
+	TaskIDs    []string `json:"task_ids" validate:"max=20,dive,uuid"`
+	SessionIDs []string `json:"session_ids" validate:"max=20,dive,uuid"`
+	Version    int      `json:"version"`
+}
+
+// GetJournalEntriesRequest lists the entries dated from-to, both inclusive.
+// Query is a full-text search over the title and the body.
+type GetJournalEntriesRequest struct {
+	From   string `validate:"omitempty,datetime=2006-01-02"`
+	To     string `validate:"omitempty,datetime=2006-01-02"`
+	Query  string `validate:"max=200"`
+	TaskID string `validate:"omitempty,uuid"`
+	Format string `validate:"omitempty,oneof=html"`
+}
+
+type RenderJournalEntryRequest struct {
+	Format string `validate:"omitempty,oneof=html"`
+}
+
+type GetJournalEntryResponse struct {
+	ID         string    `json:"id"`
+	Date       string    `json:"date"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	HTML       string    `json:"html,omitempty"`
+	Mood       int       `json:"mood"`
+	Energy     int       `json:"energy"`
+	TaskIDs    []string  `json:"task_ids"`
+	SessionIDs []string  `json:"session_ids"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Import ------------------------------------------------------------------------------------
+type ImportMappingRequest struct {
+	Task         string `json:"task" validate:"required,max=100"`
+	Date         string `json:"date" validate:"required,max=100"`
+	Time         string `json:"time" validate:"max=100"`
+	End          string `json:"end" validate:"required_without=Duration,max=100"`
+	Duration     string `json:"duration" validate:"required_without=End,max=100"`
+	BPM          string `json:"bpm" validate:"max=100"`
+	Confidence   string `json:"confidence" validate:"max=100"`
+	Note         string `json:"note" validate:"max=100"`
+	DateFormat   string `json:"date_format" validate:"max=30"`
+	DurationUnit string `json:"duration_unit" validate:"omitempty,oneof=seconds minutes hours"`
+	Delimiter    string `json:"delimiter" validate:"omitempty,len=1"`
+	TimeZone     string `json:"time_zone" validate:"omitempty,timezone"`
+}
+
+type GetImportPreviewResponse struct {
+	Columns     []string               `json:"columns"`
+	Rows        []GetImportRowResponse `json:"rows"`
+	TotalRows   int                    `json:"total_rows"`
+	ValidRows   int                    `json:"valid_rows"`
+	InvalidRows int                    `json:"invalid_rows"`
+	NewTasks    []string               `json:"new_tasks"`
+}
+
+type GetImportRowResponse struct {
+	Row             int        `json:"row"`
+	Task            string     `json:"task"`
+	NewTask         bool       `json:"new_task"`
+	StartTime       *time.Time `json:"start_time"`
+	EndTime         *time.Time `json:"end_time"`
+	DurationSeconds int        `json:"duration_seconds"`
+	BPM             int        `json:"bpm"`
+	Confidence      int        `json:"confidence"`
+	Note            string     `json:"note"`
+	Error           string     `json:"error,omitempty"`
+}
+
+type GetImportResponse struct {
+	ID               string                   `json:"id"`
+	Status           string                   `json:"status"`
+	Filename         string                   `json:"filename"`
+	TotalRows        int                      `json:"total_rows"`
+	ProcessedRows    int                      `json:"processed_rows"`
+	ImportedSessions int                      `json:"imported_sessions"`
+	SkippedSessions  int                      `json:"skipped_sessions"`
+	FailedRows       int                      `json:"failed_rows"`
+	CreatedTasks     int                      `json:"created_tasks"`
+	Errors           []GetImportErrorResponse `json:"errors"`
+	Message          string                   `json:"message,omitempty"`
+	CreatedAt        time.Time                `json:"created_at"`
+	FinishedAt       *time.Time               `json:"finished_at"`
+}
+
+type GetImportErrorResponse struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Ensemble -------------------------------------------------------------------------------------
+type GetEnsembleShortResponse struct {
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	PartName  string  `json:"part_name"`
+	TaskID    string  `json:"task_id"`
+	Readiness float64 `json:"readiness"`
+}
+
+type GetEnsembleResponse struct {
+	ID              string                      `json:"id"`
+	Title           string                      `json:"title"`
+	OwnerID         string                      `json:"owner_id"`
+	Readiness       float64                     `json:"readiness"`
+	AverageProgress float64                     `json:"average_progress"`
+	Members         []GetEnsembleMemberResponse `json:"members"`
+	CreatedAt       time.Time                   `json:"created_at"`
+}
+
+type GetEnsembleMemberResponse struct {
+	UserID    string               `json:"user_id"`
+	Username  string               `json:"username"`
+	PartName  string               `json:"part_name"`
+	TaskID    string               `json:"task_id"`
+	TargetBPM int                  `json:"target_bpm"`
+	Progress  float64              `json:"progress"`
+	Sessions  []GetSessionResponse `json:"sessions"`
+}
+
+type SaveEnsembleRequest struct {
+	Title     string `json:"title" validate:"required,min=1,max=50"`
+	PartName  string `json:"part_name" validate:"required,min=1,max=50"`
+	TargetBPM int    `json:"target_bpm" validate:"required,number"`
+}
+
+type InviteEnsembleMemberRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	PartName  string `json:"part_name" validate:"required,min=1,max=50"`
+	TargetBPM int    `json:"target_bpm" validate:"required,number"`
+}
+
+type GetEnsembleInvitationResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	PartName  string    `json:"part_name"`
+	TargetBPM int       `json:"target_bpm"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type AcceptEnsembleInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
 }
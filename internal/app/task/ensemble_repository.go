@@ -0,0 +1,357 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EnsembleRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*Ensemble, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]Ensemble, error)
+	Create(ctx context.Context, model *Ensemble, owner *EnsembleMember, task *Task) (*Ensemble, error)
+
+	GetMembers(ctx context.Context, ensembleID uuid.UUID) ([]EnsembleMember, error)
+	GetMember(ctx context.Context, ensembleID, userID uuid.UUID) (*EnsembleMember, error)
+	HasMemberEmail(ctx context.Context, ensembleID uuid.UUID, email string) (bool, error)
+
+	CreateInvitation(ctx context.Context, model *EnsembleInvitation) (*EnsembleInvitation, error)
+	GetInvitationByToken(ctx context.Context, token string) (*EnsembleInvitation, error)
+	AcceptInvitation(ctx context.Context, invitationID uuid.UUID, member *EnsembleMember, task *Task) (*EnsembleMember, error)
+}
+
+type ensembleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewEnsembleRepository(pool *pgxpool.Pool) EnsembleRepository {
+	return &ensembleRepository{pool}
+}
+
+func (r *ensembleRepository) GetByID(ctx context.Context, id uuid.UUID) (*Ensemble, error) {
+	query := `
+		SELECT id, owner_id, title, created_at
+		FROM ensembles
+		WHERE id = $1
+	`
+
+	var ensemble Ensemble
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&ensemble.ID,
+		&ensemble.OwnerID,
+		&ensemble.Title,
+		&ensemble.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan ensemble: %w", err)
+	}
+
+	return &ensemble, nil
+}
+
+func (r *ensembleRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Ensemble, error) {
+	query := `
+		SELECT e.id, e.owner_id, e.title, e.created_at
+		FROM ensembles e
+		JOIN ensemble_members m ON m.ensemble_id = e.id
+		WHERE m.user_id = $1
+		ORDER BY e.created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	ensembles := make([]Ensemble, 0)
+	for rows.Next() {
+		var ensemble Ensemble
+		err := rows.Scan(
+			&ensemble.ID,
+			&ensemble.OwnerID,
+			&ensemble.Title,
+			&ensemble.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ensemble: %w", err)
+		}
+
+		ensembles = append(ensembles, ensemble)
+	}
+
+	return ensembles, nil
+}
+
+// Create stores the ensemble together with the owner's membership and the
+// owner's own task for the piece in a single transaction.
+func (r *ensembleRepository) Create(ctx context.Context, model *Ensemble, owner *EnsembleMember, task *Task) (*Ensemble, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO ensembles(owner_id, title)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		model.OwnerID,
+		model.Title,
+	).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ensemble: %w", err)
+	}
+
+	owner.EnsembleID = model.ID
+	if err := r.addMember(ctx, tx, owner, task); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *ensembleRepository) GetMembers(ctx context.Context, ensembleID uuid.UUID) ([]EnsembleMember, error) {
+	query := `
+		SELECT m.id, m.ensemble_id, m.user_id, m.task_id, m.part_name, COALESCE(u.username, ''), m.joined_at
+		FROM ensemble_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.ensemble_id = $1
+		ORDER BY m.joined_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, ensembleID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]EnsembleMember, 0)
+	for rows.Next() {
+		var member EnsembleMember
+		err := rows.Scan(
+			&member.ID,
+			&member.EnsembleID,
+			&member.UserID,
+			&member.TaskID,
+			&member.PartName,
+			&member.Username,
+			&member.JoinedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ensemble member: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (r *ensembleRepository) GetMember(ctx context.Context, ensembleID, userID uuid.UUID) (*EnsembleMember, error) {
+	query := `
+		SELECT m.id, m.ensemble_id, m.user_id, m.task_id, m.part_name, COALESCE(u.username, ''), m.joined_at
+		FROM ensemble_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.ensemble_id = $1 AND m.user_id = $2
+	`
+
+	var member EnsembleMember
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		ensembleID,
+		userID,
+	).Scan(
+		&member.ID,
+		&member.EnsembleID,
+		&member.UserID,
+		&member.TaskID,
+		&member.PartName,
+		&member.Username,
+		&member.JoinedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan ensemble member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// HasMemberEmail reports whether a member of the ensemble has the email,
+// compared case-insensitively.
+func (r *ensembleRepository) HasMemberEmail(ctx context.Context, ensembleID uuid.UUID, email string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM ensemble_members m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.ensemble_id = $1 AND LOWER(u.email) = LOWER($2)
+		)
+	`
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, ensembleID, email).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check ensemble member email: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *ensembleRepository) CreateInvitation(ctx context.Context, model *EnsembleInvitation) (*EnsembleInvitation, error) {
+	query := `
+		INSERT INTO ensemble_invitations(ensemble_id, invited_by, email, part_name, target_bpm, token, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		model.EnsembleID,
+		model.InvitedBy,
+		model.Email,
+		model.PartName,
+		model.TargetBPM,
+		model.Token,
+		model.Status,
+	).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ensemble invitation: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *ensembleRepository) GetInvitationByToken(ctx context.Context, token string) (*EnsembleInvitation, error) {
+	query := `
+		SELECT id, ensemble_id, invited_by, email, part_name, target_bpm, token, status, created_at
+		FROM ensemble_invitations
+		WHERE token = $1
+	`
+
+	var invitation EnsembleInvitation
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		token,
+	).Scan(
+		&invitation.ID,
+		&invitation.EnsembleID,
+		&invitation.InvitedBy,
+		&invitation.Email,
+		&invitation.PartName,
+		&invitation.TargetBPM,
+		&invitation.Token,
+		&invitation.Status,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan ensemble invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// AcceptInvitation marks a pending invitation as accepted and creates the
+// member's task and membership in a single transaction.
+func (r *ensembleRepository) AcceptInvitation(ctx context.Context, invitationID uuid.UUID, member *EnsembleMember, task *Task) (*EnsembleMember, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE ensemble_invitations
+		SET status = $2
+		WHERE id = $1 AND status = $3
+	`
+
+	tag, err := tx.Exec(ctx, query, invitationID, InvitationStatusAccepted, InvitationStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept ensemble invitation: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("ensemble invitation %s is not pending", invitationID)
+	}
+
+	if err := r.addMember(ctx, tx, member, task); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return member, nil
+}
+
+func (r *ensembleRepository) addMember(ctx context.Context, tx pgx.Tx, member *EnsembleMember, task *Task) error {
+	query := `
+		INSERT INTO tasks(user_id, title, target_bpm)
+		VALUES ($1, $2, $3)
+		RETURNING id, version
+	`
+
+	err := tx.QueryRow(
+		ctx,
+		query,
+		member.UserID,
+		task.Title,
+		task.TargetBPM,
+	).Scan(
+		&task.ID,
+		&task.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create member task: %w", err)
+	}
+
+	query = `
+		INSERT INTO ensemble_members(ensemble_id, user_id, task_id, part_name)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, joined_at
+	`
+
+	member.TaskID = task.ID
+	err = tx.QueryRow(
+		ctx,
+		query,
+		member.EnsembleID,
+		member.UserID,
+		member.TaskID,
+		member.PartName,
+	).Scan(
+		&member.ID,
+		&member.JoinedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add ensemble member: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package task
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/events"
+	"github.com/google/uuid"
+)
+
+func (s *service) GetEnsembles(ctx context.Context, userID uuid.UUID) ([]GetEnsembleShortResponse, error) {
+	ensembles, err := s.ensembleRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get ensembles from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	result := make([]GetEnsembleShortResponse, 0)
+	for _, ensemble := range ensembles {
+		member, err := s.ensembleRepo.GetMember(ctx, ensemble.ID, userID)
+		if err != nil {
+			s.log.Error("failed to get ensemble member from repository",
+				"ensembleID", ensemble.ID,
+				"userID", userID,
+				"error", err,
+			)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+
+		members, err := s.getEnsembleMembers(ctx, ensemble.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		readiness, _ := ensembleReadiness(members)
+		dto := EnsembleToGetShortResponse(&ensemble, member, readiness)
+
+		result = append(result, dto)
+	}
+
+	return result, nil
+}
+
+func (s *service) GetEnsembleByID(ctx context.Context, id, userID uuid.UUID) (*GetEnsembleResponse, error) {
+	ensemble, err := s.ensembleRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get ensemble from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if _, err := s.ensembleRepo.GetMember(ctx, id, userID); err != nil {
+		s.log.Warn("user is not a member of ensemble", "id", id, "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrAccessDenied)
+	}
+
+	members, err := s.getEnsembleMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	readiness, average := ensembleReadiness(members)
+	result := EnsembleToGetResponse(ensemble, members, readiness, average)
+
+	return &result, nil
+}
+
+func (s *service) CreateEnsemble(ctx context.Context, req *SaveEnsembleRequest, userID uuid.UUID) (*GetEnsembleResponse, error) {
+	model := SaveRequestToEnsemble(req, userID)
+	owner := EnsembleMember{
+		UserID:   userID,
+		PartName: req.PartName,
+	}
+	task := Task{
+		UserID:    userID,
+		Title:     req.Title,
+		TargetBPM: req.TargetBPM,
+	}
+
+	ensemble, err := s.ensembleRepo.Create(ctx, &model, &owner, &task)
+	if err != nil {
+		s.log.Error("failed to create ensemble in repository",
+			"req", req,
+			"userID", userID,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.GetEnsembleByID(ctx, ensemble.ID, userID)
+}
+
+func (s *service) InviteEnsembleMember(ctx context.Context, req *InviteEnsembleMemberRequest, ensembleID, userID uuid.UUID) (*GetEnsembleInvitationResponse, error) {
+	ensemble, err := s.ensembleRepo.GetByID(ctx, ensembleID)
+	if err != nil {
+		s.log.Error("failed to get ensemble from repository", "id", ensembleID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	inviter, err := s.ensembleRepo.GetMember(ctx, ensembleID, userID)
+	if err != nil {
+		s.log.Warn("user is not a member of ensemble", "id", ensembleID, "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrAccessDenied)
+	}
+
+	isMember, err := s.ensembleRepo.HasMemberEmail(ctx, ensembleID, req.Email)
+	if err != nil {
+		s.log.Error("failed to check ensemble member email in repository", "id", ensembleID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+	if isMember {
+		return nil, fmt.Errorf(errors.ErrAlreadyMember)
+	}
+
+	rawToken := make([]byte, 32)
+	if _, err := rand.Read(rawToken); err != nil {
+		s.log.Error("failed to generate invitation token", "error", err)
+		return nil, fmt.Errorf(errors.ErrCommon)
+	}
+	token := hex.EncodeToString(rawToken)
+
+	model := InviteRequestToEnsembleInvitation(req, ensembleID, userID, token)
+
+	invitation, err := s.ensembleRepo.CreateInvitation(ctx, &model)
+	if err != nil {
+		s.log.Error("failed to create ensemble invitation in repository",
+			"req", req,
+			"ensembleID", ensembleID,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	inviteURL := fmt.Sprintf("https://trackmus.ru/ensembles/join?token=%s", token)
+
+	if s.rabbitmq != nil {
+		event := events.EmailEvent{
+			To:       req.Email,
+			Template: "ensemble_invitation",
+			Subject:  "Приглашение в ансамбль",
+			Data: map[string]interface{}{
+				"invite_url":     inviteURL,
+				"user_email":     req.Email,
+				"ensemble_title": ensemble.Title,
+				"inviter_name":   inviter.Username,
+				"part_name":      req.PartName,
+			},
+		}
+
+		if err := s.rabbitmq.PublishEmail(event); err != nil {
+			s.log.Error("failed to publish email event", "error", err)
+		}
+	} else {
+		s.log.Warn("event service not available - email not sent")
+	}
+
+	s.log.Info("ensemble invitation sent", "ensembleID", ensembleID, "email", req.Email)
+
+	result := EnsembleInvitationToGetResponse(invitation)
+
+	return &result, nil
+}
+
+func (s *service) AcceptEnsembleInvitation(ctx context.Context, req *AcceptEnsembleInvitationRequest, userID uuid.UUID, email string) (*GetEnsembleResponse, error) {
+	invitation, err := s.ensembleRepo.GetInvitationByToken(ctx, req.Token)
+	if err != nil {
+		s.log.Warn("invalid ensemble invitation token", "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	if invitation.Status != InvitationStatusPending {
+		return nil, fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	if time.Now().After(invitation.ExpiresAt()) {
+		s.log.Info("ensemble invitation expired", "invitationID", invitation.ID, "userID", userID)
+		return nil, fmt.Errorf(errors.ErrInvitationExpired)
+	}
+
+	if !strings.EqualFold(invitation.Email, email) {
+		s.log.Warn("ensemble invitation email mismatch", "invitationID", invitation.ID, "userID", userID)
+		return nil, fmt.Errorf(errors.ErrAccessDenied)
+	}
+
+	if _, err := s.ensembleRepo.GetMember(ctx, invitation.EnsembleID, userID); err == nil {
+		return nil, fmt.Errorf(errors.ErrAlreadyMember)
+	}
+
+	ensemble, err := s.ensembleRepo.GetByID(ctx, invitation.EnsembleID)
+	if err != nil {
+		s.log.Error("failed to get ensemble from repository", "id", invitation.EnsembleID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	member := EnsembleMember{
+		EnsembleID: ensemble.ID,
+		UserID:     userID,
+		PartName:   invitation.PartName,
+	}
+	task := Task{
+		UserID:    userID,
+		Title:     ensemble.Title,
+		TargetBPM: invitation.TargetBPM,
+	}
+
+	if _, err := s.ensembleRepo.AcceptInvitation(ctx, invitation.ID, &member, &task); err != nil {
+		s.log.Error("failed to accept ensemble invitation in repository",
+			"invitationID", invitation.ID,
+			"userID", userID,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.GetEnsembleByID(ctx, ensemble.ID, userID)
+}
+
+func (s *service) getEnsembleMembers(ctx context.Context, ensembleID uuid.UUID) ([]GetEnsembleMemberResponse, error) {
+	members, err := s.ensembleRepo.GetMembers(ctx, ensembleID)
+	if err != nil {
+		s.log.Error("failed to get ensemble members from repository", "ensembleID", ensembleID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	result := make([]GetEnsembleMemberResponse, 0)
+	for _, m := range members {
+		task, err := s.taskRepo.GetByID(ctx, m.TaskID)
+		if err != nil {
+			s.log.Error("failed to get member task from repository", "taskID", m.TaskID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+
+		progress, err := s.getTaskProgress(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions, err := s.getSessionsByTaskID(ctx, m.TaskID)
+		if err != nil {
+			return nil, err
+		}
+
+		dto := EnsembleMemberToGetResponse(&m, task, progress, sessions)
+		result = append(result, dto)
+	}
+
+	return result, nil
+}
+
+// ensembleReadiness returns the progress of the least prepared part, which is
+// what limits the band, together with the average progress of all parts.
+func ensembleReadiness(members []GetEnsembleMemberResponse) (float64, float64) {
+	if len(members) == 0 {
+		return 0, 0
+	}
+
+	readiness := math.MaxFloat64
+	sum := 0.0
+	for _, m := range members {
+		readiness = math.Min(readiness, m.Progress)
+		sum += m.Progress
+	}
+
+	return readiness, sum / float64(len(members))
+}
@@ -0,0 +1,84 @@
+package task
+
+// catalogExercise is a built-in exercise suggested for a weak area. Tags are
+// the task tags it targets, compared case-insensitively.
+type catalogExercise struct {
+	ID          string
+	Title       string
+	Description string
+	Tags        []string
+	TargetBPM   int
+}
+
+var exerciseCatalog = []catalogExercise{
+	{
+		ID:          "scales-thirds",
+		Title:       "Гаммы терциями",
+		Description: "Мажорные и минорные гаммы группами по три ноты вверх и вниз, восьмыми под метроном.",
+		Tags:        []string{"гаммы", "scales", "техника", "technique"},
+		TargetBPM:   100,
+	},
+	{
+		ID:          "scales-rhythms",
+		Title:       "Гаммы в разных ритмах",
+		Description: "Одна гамма пунктиром, обращённым пунктиром и триолями, чтобы выровнять слабые пальцы.",
+		Tags:        []string{"гаммы", "scales", "ритм", "rhythm"},
+		TargetBPM:   90,
+	},
+	{
+		ID:          "arpeggios-broken",
+		Title:       "Ломаные арпеджио",
+		Description: "Трезвучия и септаккорды по всем обращениям, сначала медленно и легато.",
+		Tags:        []string{"арпеджио", "arpeggios", "техника", "technique"},
+		TargetBPM:   80,
+	},
+	{
+		ID:          "chords-changes",
+		Title:       "Смена аккордов на время",
+		Description: "Пары аккордов, между которыми переход даётся труднее всего, по четыре удара на каждый.",
+		Tags:        []string{"аккорды", "chords", "гармония", "harmony"},
+		TargetBPM:   70,
+	},
+	{
+		ID:          "rhythm-subdivisions",
+		Title:       "Дробление доли",
+		Description: "Одна нота с переходом от четвертей к восьмым, триолям и шестнадцатым без остановки.",
+		Tags:        []string{"ритм", "rhythm", "метроном", "timing"},
+		TargetBPM:   80,
+	},
+	{
+		ID:          "rhythm-offbeat",
+		Title:       "Метроном на слабую долю",
+		Description: "Знакомый отрывок с щелчком метронома только на второй и четвёртой долях.",
+		Tags:        []string{"ритм", "rhythm", "метроном", "timing"},
+		TargetBPM:   90,
+	},
+	{
+		ID:          "technique-chromatic",
+		Title:       "Хроматические упражнения",
+		Description: "Хроматическая последовательность с разной аппликатурой и упором на независимость пальцев.",
+		Tags:        []string{"техника", "technique", "беглость", "speed"},
+		TargetBPM:   100,
+	},
+	{
+		ID:          "articulation-contrast",
+		Title:       "Легато и стаккато",
+		Description: "Один пассаж попеременно легато и стаккато, чтобы управлять атакой каждой ноты.",
+		Tags:        []string{"штрихи", "articulation", "техника", "technique"},
+		TargetBPM:   80,
+	},
+	{
+		ID:          "dynamics-swells",
+		Title:       "Динамические волны",
+		Description: "Длинные ноты и гаммы с плавным крещендо и диминуэндо на четыре такта.",
+		Tags:        []string{"динамика", "dynamics", "звук", "tone"},
+		TargetBPM:   60,
+	},
+	{
+		ID:          "reading-daily",
+		Title:       "Ежедневное чтение с листа",
+		Description: "Пять минут незнакомого нотного текста чуть проще текущего уровня без остановок на ошибках.",
+		Tags:        []string{"чтение с листа", "sight reading", "сольфеджио"},
+		TargetBPM:   60,
+	},
+}
@@ -0,0 +1,319 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ExportRepository reads all data of a user row by row, so large accounts can
+// be exported without loading everything into memory. It also keeps the
+// secret tokens of the users' calendar feeds.
+type ExportRepository interface {
+	StreamTasks(ctx context.Context, userID uuid.UUID, fn func(*Task) error) error
+	StreamSessions(ctx context.Context, userID uuid.UUID, since time.Time, fn func(*ExportSession) error) error
+	StreamLinks(ctx context.Context, userID uuid.UUID, fn func(*Link) error) error
+	StreamMedia(ctx context.Context, userID uuid.UUID, fn func(*Media) error) error
+	StreamJournal(ctx context.Context, userID uuid.UUID, fn func(*JournalEntry) error) error
+
+	GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error)
+	GetCalendarFeedByToken(ctx context.Context, token string) (*CalendarFeed, error)
+	SaveCalendarFeed(ctx context.Context, model *CalendarFeed) (*CalendarFeed, error)
+	DeleteCalendarFeed(ctx context.Context, userID uuid.UUID) error
+}
+
+type exportRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewExportRepository(pool *pgxpool.Pool) ExportRepository {
+	return &exportRepository{pool}
+}
+
+func (r *exportRepository) StreamTasks(ctx context.Context, userID uuid.UUID, fn func(*Task) error) error {
+	query := `
+		SELECT id, user_id, title, target_bpm, COALESCE(composer, ''), COALESCE(time_signature, ''), COALESCE(duration, 0), COALESCE(clean_reps_goal, 0), tags, is_completed, completed_at, version, created_at, updated_at
+		FROM tasks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+		err := rows.Scan(
+			&task.ID,
+			&task.UserID,
+			&task.Title,
+			&task.TargetBPM,
+			&task.Composer,
+			&task.TimeSignature,
+			&task.Duration,
+			&task.CleanRepsGoal,
+			&task.Tags,
+			&task.IsCompleted,
+			&task.CompletedAt,
+			&task.Version,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+
+		if err := fn(&task); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamSessions passes the sessions started after since, oldest first.
+func (r *exportRepository) StreamSessions(ctx context.Context, userID uuid.UUID, since time.Time, fn func(*ExportSession) error) error {
+	query := `
+		SELECT s.id, s.task_id, t.title, s.status, s.start_time, COALESCE(s.end_time, s.start_time), COALESCE(s.bpm, 0), COALESCE(s.confidence, 0), COALESCE(s.note, ''),
+			COALESCE((
+				SELECT SUM(EXTRACT(EPOCH FROM (COALESCE(p.ended_at, s.end_time) - p.started_at)))
+				FROM session_pauses p
+				WHERE p.session_id = s.id
+			), 0)::INT
+		FROM sessions s
+		JOIN tasks t ON t.id = s.task_id
+		WHERE t.user_id = $1 AND s.start_time >= $2
+		ORDER BY s.start_time
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session ExportSession
+		err := rows.Scan(
+			&session.ID,
+			&session.TaskID,
+			&session.TaskTitle,
+			&session.Status,
+			&session.StartTime,
+			&session.EndTime,
+			&session.BPM,
+			&session.Confidence,
+			&session.Note,
+			&session.PausedSeconds,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		if err := fn(&session); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *exportRepository) StreamLinks(ctx context.Context, userID uuid.UUID, fn func(*Link) error) error {
+	query := `
+		SELECT l.id, l.task_id, COALESCE(l.title, ''), COALESCE(l.type, ''), l.version, l.created_at, l.updated_at
+		FROM links l
+		JOIN tasks t ON t.id = l.task_id
+		WHERE t.user_id = $1
+		ORDER BY l.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link Link
+		err := rows.Scan(
+			&link.ID,
+			&link.TaskID,
+			&link.Title,
+			&link.Type,
+			&link.Version,
+			&link.CreatedAt,
+			&link.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan link: %w", err)
+		}
+
+		if err := fn(&link); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *exportRepository) StreamMedia(ctx context.Context, userID uuid.UUID, fn func(*Media) error) error {
+	query := `
+		SELECT m.id, m.task_id, COALESCE(m.type, ''), COALESCE(m.filename, ''), COALESCE(m.size, 0), COALESCE(m.duration, 0), m.created_at, m.updated_at
+		FROM medias m
+		JOIN tasks t ON t.id = m.task_id
+		WHERE t.user_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var media Media
+		err := rows.Scan(
+			&media.ID,
+			&media.TaskID,
+			&media.Type,
+			&media.Filename,
+			&media.Size,
+			&media.Duration,
+			&media.CreatedAt,
+			&media.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan media: %w", err)
+		}
+
+		if err := fn(&media); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamJournal passes the journal entries of the user, oldest first.
+func (r *exportRepository) StreamJournal(ctx context.Context, userID uuid.UUID, fn func(*JournalEntry) error) error {
+	query := `
+		SELECT ` + journalEntryColumns + `
+		FROM journal_entries e
+		WHERE e.user_id = $1
+		ORDER BY e.entry_date, e.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry JournalEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Date,
+			&entry.Title,
+			&entry.Body,
+			&entry.Mood,
+			&entry.Energy,
+			&entry.TaskIDs,
+			&entry.SessionIDs,
+			&entry.Version,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetCalendarFeed returns the feed of the user, or nil when there is none.
+func (r *exportRepository) GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error) {
+	query := `
+		SELECT user_id, token, created_at
+		FROM calendar_feeds
+		WHERE user_id = $1
+	`
+
+	return r.scanCalendarFeed(r.pool.QueryRow(ctx, query, userID))
+}
+
+// GetCalendarFeedByToken returns the feed with the token, or nil when there
+// is none.
+func (r *exportRepository) GetCalendarFeedByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	query := `
+		SELECT user_id, token, created_at
+		FROM calendar_feeds
+		WHERE token = $1
+	`
+
+	return r.scanCalendarFeed(r.pool.QueryRow(ctx, query, token))
+}
+
+// SaveCalendarFeed creates the feed or replaces the token of the existing
+// one, which revokes the old URL.
+func (r *exportRepository) SaveCalendarFeed(ctx context.Context, model *CalendarFeed) (*CalendarFeed, error) {
+	query := `
+		INSERT INTO calendar_feeds(user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token = EXCLUDED.token,
+			created_at = NOW()
+		RETURNING created_at
+	`
+
+	if err := r.pool.QueryRow(ctx, query, model.UserID, model.Token).Scan(&model.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save calendar feed: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *exportRepository) DeleteCalendarFeed(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		DELETE FROM calendar_feeds
+		WHERE user_id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete calendar feed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *exportRepository) scanCalendarFeed(row pgx.Row) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	err := row.Scan(
+		&feed.UserID,
+		&feed.Token,
+		&feed.CreatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan calendar feed: %w", err)
+	}
+
+	return &feed, nil
+}
@@ -0,0 +1,333 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/ical"
+	"github.com/google/uuid"
+)
+
+// ExportService streams the user's tasks and sessions as CSV or JSON and
+// serves them as an iCalendar feed.
+type ExportService interface {
+	Export(ctx context.Context, req *ExportRequest, userID uuid.UUID, w io.Writer) error
+	GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error)
+	CreateCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error)
+	RemoveCalendarFeed(ctx context.Context, userID uuid.UUID) error
+	RenderCalendarFeed(ctx context.Context, token string, w io.Writer) error
+}
+
+type exportService struct {
+	log        *slog.Logger
+	exportRepo ExportRepository
+}
+
+func NewExportService(log *slog.Logger, exportRepo ExportRepository) ExportService {
+	return &exportService{
+		log:        log,
+		exportRepo: exportRepo,
+	}
+}
+
+const (
+	calendarBaseURL = "https://trackmus.ru/calendar"
+
+	// calendarFeedDays limits the feed to the recent sessions; calendar apps
+	// poll it regularly and do not need the whole history.
+	calendarFeedDays    = 366
+	calendarRefresh     = time.Hour
+	exportFlushInterval = 500
+)
+
+var (
+	taskExportHeader    = []string{"id", "title", "composer", "time_signature", "target_bpm", "duration", "clean_reps_goal", "tags", "is_completed", "completed_at", "created_at", "updated_at"}
+	sessionExportHeader = []string{"id", "task_id", "task_title", "status", "start_time", "end_time", "duration_seconds", "bpm", "confidence", "note"}
+	linkExportHeader    = []string{"id", "task_id", "title", "type", "created_at"}
+	mediaExportHeader   = []string{"id", "task_id", "type", "filename", "size", "duration", "created_at"}
+	journalExportHeader = []string{"id", "date", "title", "body", "mood", "energy", "task_ids", "session_ids", "created_at", "updated_at"}
+)
+
+// Export streams all records of one kind owned by the user to w as CSV or as
+// a JSON array. Rows are written as they are read from the database.
+func (s *exportService) Export(ctx context.Context, req *ExportRequest, userID uuid.UUID, w io.Writer) error {
+	format := ExportFormat(req.Format)
+
+	var err error
+	switch ExportEntity(req.Entity) {
+	case ExportEntityTasks:
+		err = streamExport(w, format, taskExportHeader, taskExportValues, func(emit func(*ExportTaskResponse) error) error {
+			return s.exportRepo.StreamTasks(ctx, userID, func(model *Task) error {
+				dto := TaskToExportResponse(model)
+				return emit(&dto)
+			})
+		})
+	case ExportEntitySessions:
+		err = streamExport(w, format, sessionExportHeader, sessionExportValues, func(emit func(*ExportSessionResponse) error) error {
+			return s.exportRepo.StreamSessions(ctx, userID, time.Time{}, func(model *ExportSession) error {
+				dto := SessionToExportResponse(model)
+				return emit(&dto)
+			})
+		})
+	case ExportEntityLinks:
+		err = streamExport(w, format, linkExportHeader, linkExportValues, func(emit func(*ExportLinkResponse) error) error {
+			return s.exportRepo.StreamLinks(ctx, userID, func(model *Link) error {
+				dto := LinkToExportResponse(model)
+				return emit(&dto)
+			})
+		})
+	case ExportEntityMedia:
+		err = streamExport(w, format, mediaExportHeader, mediaExportValues, func(emit func(*ExportMediaResponse) error) error {
+			return s.exportRepo.StreamMedia(ctx, userID, func(model *Media) error {
+				dto := MediaToExportResponse(model)
+				return emit(&dto)
+			})
+		})
+	case ExportEntityJournal:
+		err = streamExport(w, format, journalExportHeader, journalExportValues, func(emit func(*ExportJournalEntryResponse) error) error {
+			return s.exportRepo.StreamJournal(ctx, userID, func(model *JournalEntry) error {
+				dto := JournalEntryToExportResponse(model)
+				return emit(&dto)
+			})
+		})
+	default:
+		return fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	if err != nil {
+		s.log.Error("failed to export data", "entity", req.Entity, "format", req.Format, "userID", userID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	return nil
+}
+
+func (s *exportService) GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error) {
+	feed, err := s.exportRepo.GetCalendarFeed(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get calendar feed from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if feed == nil {
+		return nil, fmt.Errorf(errors.ErrShareNotFound)
+	}
+
+	result := CalendarFeedToGetResponse(feed)
+
+	return &result, nil
+}
+
+// CreateCalendarFeed issues a new secret feed URL. An existing URL stops
+// working, so a leaked one can be replaced.
+func (s *exportService) CreateCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error) {
+	rawToken := make([]byte, 24)
+	if _, err := rand.Read(rawToken); err != nil {
+		s.log.Error("failed to generate calendar feed token", "error", err)
+		return nil, fmt.Errorf(errors.ErrCommon)
+	}
+
+	feed, err := s.exportRepo.SaveCalendarFeed(ctx, &CalendarFeed{UserID: userID, Token: hex.EncodeToString(rawToken)})
+	if err != nil {
+		s.log.Error("failed to save calendar feed in repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	result := CalendarFeedToGetResponse(feed)
+
+	return &result, nil
+}
+
+func (s *exportService) RemoveCalendarFeed(ctx context.Context, userID uuid.UUID) error {
+	if err := s.exportRepo.DeleteCalendarFeed(ctx, userID); err != nil {
+		s.log.Error("failed to delete calendar feed from repository", "userID", userID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToDeleteData)
+	}
+
+	return nil
+}
+
+// RenderCalendarFeed streams the finished sessions of the feed owner as
+// iCalendar events.
+func (s *exportService) RenderCalendarFeed(ctx context.Context, token string, w io.Writer) error {
+	feed, err := s.exportRepo.GetCalendarFeedByToken(ctx, token)
+	if err != nil {
+		s.log.Error("failed to get calendar feed from repository", "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if feed == nil {
+		return fmt.Errorf(errors.ErrShareNotFound)
+	}
+
+	now := time.Now().UTC()
+	calendar := ical.NewWriter(w, ical.Calendar{
+		ProductID:       "-//Trackmus//Practice sessions//RU",
+		Name:            "Trackmus — практика",
+		RefreshInterval: calendarRefresh,
+	})
+
+	since := now.AddDate(0, 0, -calendarFeedDays)
+	err = s.exportRepo.StreamSessions(ctx, feed.UserID, since, func(session *ExportSession) error {
+		if session.Status != SessionStatusFinished {
+			return nil
+		}
+
+		return calendar.WriteEvent(SessionToCalendarEvent(session, now))
+	})
+	if err == nil {
+		err = calendar.Close()
+	}
+	if err != nil {
+		s.log.Error("failed to render calendar feed", "userID", feed.UserID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	return nil
+}
+
+// streamExport writes the records produced by stream in the given format.
+// CSV starts with a byte order mark, so spreadsheet apps detect UTF-8.
+func streamExport[T any](w io.Writer, format ExportFormat, header []string, values func(*T) []string, stream func(func(*T) error) error) error {
+	if format == ExportFormatJSON {
+		buffered := bufio.NewWriter(w)
+		buffered.WriteString("[")
+
+		first := true
+		err := stream(func(record *T) error {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if !first {
+				buffered.WriteString(",")
+			}
+			first = false
+			_, err = buffered.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		buffered.WriteString("]\n")
+		return buffered.Flush()
+	}
+
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := stream(func(record *T) error {
+		if err := writer.Write(values(record)); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushInterval == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func taskExportValues(dto *ExportTaskResponse) []string {
+	return []string{
+		dto.ID,
+		dto.Title,
+		dto.Composer,
+		dto.TimeSignature,
+		strconv.Itoa(dto.TargetBPM),
+		strconv.Itoa(dto.Duration),
+		strconv.Itoa(dto.CleanRepsGoal),
+		strings.Join(dto.Tags, ";"),
+		strconv.FormatBool(dto.IsCompleted),
+		formatExportTime(dto.CompletedAt),
+		formatExportTime(&dto.CreatedAt),
+		formatExportTime(&dto.UpdatedAt),
+	}
+}
+
+func sessionExportValues(dto *ExportSessionResponse) []string {
+	return []string{
+		dto.ID,
+		dto.TaskID,
+		dto.TaskTitle,
+		dto.Status,
+		formatExportTime(&dto.StartTime),
+		formatExportTime(&dto.EndTime),
+		strconv.Itoa(dto.DurationSeconds),
+		strconv.Itoa(dto.BPM),
+		strconv.Itoa(dto.Confidence),
+		dto.Note,
+	}
+}
+
+func linkExportValues(dto *ExportLinkResponse) []string {
+	return []string{
+		dto.ID,
+		dto.TaskID,
+		dto.Title,
+		dto.Type,
+		formatExportTime(&dto.CreatedAt),
+	}
+}
+
+func mediaExportValues(dto *ExportMediaResponse) []string {
+	return []string{
+		dto.ID,
+		dto.TaskID,
+		dto.Type,
+		dto.Filename,
+		strconv.FormatInt(dto.Size, 10),
+		strconv.Itoa(dto.Duration),
+		formatExportTime(&dto.CreatedAt),
+	}
+}
+
+func journalExportValues(dto *ExportJournalEntryResponse) []string {
+	return []string{
+		dto.ID,
+		dto.Date,
+		dto.Title,
+		dto.Body,
+		strconv.Itoa(dto.Mood),
+		strconv.Itoa(dto.Energy),
+		strings.Join(dto.TaskIDs, ";"),
+		strings.Join(dto.SessionIDs, ";"),
+		formatExportTime(&dto.CreatedAt),
+		formatExportTime(&dto.UpdatedAt),
+	}
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
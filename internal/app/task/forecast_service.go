@@ -0,0 +1,168 @@
+package task
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/forecast"
+)
+
+const (
+	// forecastMinDays is how many practice days with a tempo the curve is
+	// fitted to at least.
+	forecastMinDays = 4
+
+	// Sessions rated below forecastMinConfidence do not count as reaching
+	// their tempo; unrated ones do.
+	forecastMinConfidence = 3
+
+	// The fitted curve levels off at this multiple of the target, so growth
+	// slows down as the target gets near.
+	forecastCeilingRatio = 1.25
+
+	// forecastInterval is the z-score of the ETA interval, about 80%.
+	forecastInterval = 1.28
+
+	// Estimates further out than forecastHorizonDays are reported as stalled.
+	forecastHorizonDays = 3 * 365
+)
+
+// forecastDay is the best tempo played with confidence on a day.
+type forecastDay struct {
+	Day time.Time
+	BPM int
+}
+
+// getTaskForecast estimates when the task reaches its target tempo by fitting
+// a logistic curve to the best confident tempo of each practice day. It
+// returns nil when the task has no target or the forecast cannot be made.
+func (s *analyticsService) getTaskForecast(ctx context.Context, task *Task) *GetForecastResponse {
+	if task.TargetBPM <= 0 {
+		return nil
+	}
+
+	entries, err := s.trendRepo.GetPracticeEntries(ctx, task.ID)
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "taskID", task.ID, "error", err)
+		return nil
+	}
+
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, "", task.UserID)
+	if err != nil {
+		return nil
+	}
+
+	today := startOfDay(time.Now().In(loc))
+	result := forecastTask(forecastDays(entries, loc), task.TargetBPM, today)
+
+	if task.DueDate != nil {
+		result.DueDate = task.DueDate.Format(statsDateLayout)
+		compareForecastWithDueDate(&result, *task.DueDate, loc)
+	}
+
+	return &result
+}
+
+// forecastTask makes the forecast from the practice days, oldest first.
+func forecastTask(days []forecastDay, targetBPM int, today time.Time) GetForecastResponse {
+	result := GetForecastResponse{
+		Status:       string(ForecastStatusInsufficient),
+		TargetBPM:    targetBPM,
+		PracticeDays: len(days),
+	}
+
+	for _, day := range days {
+		result.CurrentBPM = max(result.CurrentBPM, day.BPM)
+		if day.BPM >= targetBPM && result.Status != string(ForecastStatusReached) {
+			result.Status = string(ForecastStatusReached)
+			result.ETA = day.Day.Format(statsDateLayout)
+		}
+	}
+
+	if result.Status == string(ForecastStatusReached) || len(days) < forecastMinDays {
+		return result
+	}
+
+	first := days[0].Day
+	points := make([]forecast.Point, 0, len(days))
+	for _, day := range days {
+		points = append(points, forecast.Point{X: daysBetween(first, day.Day), Y: float64(day.BPM)})
+	}
+
+	curve, err := forecast.FitLogistic(points, float64(targetBPM)*forecastCeilingRatio, forecastInterval)
+	if err != nil {
+		return result
+	}
+
+	x, earliest, latest, ok := curve.Solve(float64(targetBPM))
+	horizon := daysBetween(first, today) + forecastHorizonDays
+	if !ok || x > horizon {
+		result.Status = string(ForecastStatusStalled)
+		return result
+	}
+
+	result.Status = string(ForecastStatusEstimated)
+	result.ETA = forecastDate(first, x, today)
+	result.ETAEarliest = forecastDate(first, earliest, today)
+	if latest <= horizon {
+		result.ETALatest = forecastDate(first, latest, today)
+	}
+
+	return result
+}
+
+// forecastDays groups the sessions by day in loc.
+func forecastDays(entries []PracticeEntry, loc *time.Location) []forecastDay {
+	days := make([]forecastDay, 0)
+	for _, entry := range entries {
+		if entry.BPM <= 0 || (entry.Confidence > 0 && entry.Confidence < forecastMinConfidence) {
+			continue
+		}
+
+		day := startOfDay(entry.StartTime.In(loc))
+		if n := len(days); n > 0 && days[n-1].Day.Equal(day) {
+			days[n-1].BPM = max(days[n-1].BPM, entry.BPM)
+			continue
+		}
+		days = append(days, forecastDay{Day: day, BPM: entry.BPM})
+	}
+
+	return days
+}
+
+// compareForecastWithDueDate tells whether the ETA falls on or before the due
+// date. A stalled task is never on schedule.
+func compareForecastWithDueDate(result *GetForecastResponse, dueDate time.Time, loc *time.Location) {
+	due := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, loc)
+
+	switch ForecastStatus(result.Status) {
+	case ForecastStatusReached, ForecastStatusEstimated:
+		eta, err := time.ParseInLocation(statsDateLayout, result.ETA, loc)
+		if err != nil {
+			return
+		}
+		margin := int(math.Round(daysBetween(eta, due)))
+		onSchedule := margin >= 0
+		result.DueMarginDays = &margin
+		result.OnSchedule = &onSchedule
+	case ForecastStatusStalled:
+		onSchedule := false
+		result.OnSchedule = &onSchedule
+	}
+}
+
+// forecastDate returns the day x days after first, but not before today.
+func forecastDate(first time.Time, x float64, today time.Time) string {
+	day := first.AddDate(0, 0, int(math.Ceil(x)))
+	if day.Before(today) {
+		day = today
+	}
+
+	return day.Format(statsDateLayout)
+}
+
+// daysBetween counts calendar days, ignoring daylight saving shifts.
+func daysBetween(from, to time.Time) float64 {
+	return math.Round(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,171 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestForecastTask(t *testing.T) {
+	first := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	days := func(step int, bpms ...int) []forecastDay {
+		result := make([]forecastDay, 0, len(bpms))
+		for i, bpm := range bpms {
+			result = append(result, forecastDay{Day: first.AddDate(0, 0, step*i), BPM: bpm})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		days       []forecastDay
+		target     int
+		today      time.Time
+		wantStatus ForecastStatus
+		wantETA    string
+		wantBPM    int
+	}{
+		{
+			name:       "too few days",
+			days:       days(2, 60, 70, 80),
+			target:     100,
+			today:      first.AddDate(0, 0, 5),
+			wantStatus: ForecastStatusInsufficient,
+			wantBPM:    80,
+		},
+		{
+			name:       "reached",
+			days:       days(2, 60, 90, 101, 95),
+			target:     100,
+			today:      first.AddDate(0, 0, 7),
+			wantStatus: ForecastStatusReached,
+			wantETA:    "2026-03-05",
+			wantBPM:    101,
+		},
+		{
+			name:       "steady growth",
+			days:       days(2, 60, 64, 69, 73, 78),
+			target:     100,
+			today:      first.AddDate(0, 0, 9),
+			wantStatus: ForecastStatusEstimated,
+			wantBPM:    78,
+		},
+		{
+			name:       "no growth",
+			days:       days(2, 80, 79, 80, 78, 80),
+			target:     120,
+			today:      first.AddDate(0, 0, 9),
+			wantStatus: ForecastStatusStalled,
+			wantBPM:    80,
+		},
+		{
+			name:       "growth too slow for the horizon",
+			days:       days(30, 60, 60, 60, 61, 61),
+			target:     120,
+			today:      first.AddDate(0, 0, 121),
+			wantStatus: ForecastStatusStalled,
+			wantBPM:    61,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := forecastTask(tt.days, tt.target, tt.today)
+
+			if result.Status != string(tt.wantStatus) {
+				t.Fatalf("status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			if result.CurrentBPM != tt.wantBPM {
+				t.Errorf("current tempo = %d, want %d", result.CurrentBPM, tt.wantBPM)
+			}
+			if tt.wantETA != "" && result.ETA != tt.wantETA {
+				t.Errorf("ETA = %q, want %q", result.ETA, tt.wantETA)
+			}
+
+			if tt.wantStatus != ForecastStatusEstimated {
+				return
+			}
+			today := tt.today.Format(statsDateLayout)
+			if result.ETA < today || result.ETAEarliest < today || result.ETAEarliest > result.ETA {
+				t.Errorf("ETA %q, earliest %q, today %q", result.ETA, result.ETAEarliest, today)
+			}
+			if result.ETALatest != "" && result.ETALatest < result.ETA {
+				t.Errorf("latest ETA %q before ETA %q", result.ETALatest, result.ETA)
+			}
+		})
+	}
+}
+
+func TestForecastDays(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	at := func(day, hour, bpm, confidence int) PracticeEntry {
+		return PracticeEntry{StartTime: time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC), BPM: bpm, Confidence: confidence}
+	}
+	day := func(d, bpm int) forecastDay {
+		return forecastDay{Day: time.Date(2026, 3, d, 0, 0, 0, 0, loc), BPM: bpm}
+	}
+
+	tests := []struct {
+		name    string
+		entries []PracticeEntry
+		want    []forecastDay
+	}{
+		{
+			name:    "best tempo of the day",
+			entries: []PracticeEntry{at(1, 10, 60, 4), at(1, 12, 72, 0), at(1, 15, 66, 5)},
+			want:    []forecastDay{day(1, 72)},
+		},
+		{
+			name:    "unconfident and untimed sessions skipped",
+			entries: []PracticeEntry{at(1, 10, 60, 3), at(1, 12, 90, 2), at(2, 10, 0, 5)},
+			want:    []forecastDay{day(1, 60)},
+		},
+		{
+			name:    "days in the user's time zone",
+			entries: []PracticeEntry{at(1, 20, 60, 0), at(1, 22, 64, 0)},
+			want:    []forecastDay{day(1, 60), day(2, 64)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := forecastDays(tt.entries, loc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("days = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareForecastWithDueDate(t *testing.T) {
+	due := time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)
+	margin := func(days int) *int { return &days }
+	onSchedule := func(ok bool) *bool { return &ok }
+
+	tests := []struct {
+		name           string
+		status         ForecastStatus
+		eta            string
+		wantMargin     *int
+		wantOnSchedule *bool
+	}{
+		{name: "early", status: ForecastStatusEstimated, eta: "2026-04-03", wantMargin: margin(7), wantOnSchedule: onSchedule(true)},
+		{name: "on the day", status: ForecastStatusReached, eta: "2026-04-10", wantMargin: margin(0), wantOnSchedule: onSchedule(true)},
+		{name: "late", status: ForecastStatusEstimated, eta: "2026-04-12", wantMargin: margin(-2), wantOnSchedule: onSchedule(false)},
+		{name: "stalled", status: ForecastStatusStalled, wantOnSchedule: onSchedule(false)},
+		{name: "insufficient", status: ForecastStatusInsufficient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetForecastResponse{Status: string(tt.status), ETA: tt.eta}
+			compareForecastWithDueDate(&result, due, time.UTC)
+
+			if !reflect.DeepEqual(result.DueMarginDays, tt.wantMargin) {
+				t.Errorf("margin = %v, want %v", result.DueMarginDays, tt.wantMargin)
+			}
+			if !reflect.DeepEqual(result.OnSchedule, tt.wantOnSchedule) {
+				t.Errorf("on schedule = %v, want %v", result.OnSchedule, tt.wantOnSchedule)
+			}
+		})
+	}
+}
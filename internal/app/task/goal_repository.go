@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GoalRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*Goal, error)
+	Save(ctx context.Context, model *Goal) (*Goal, error)
+}
+
+type goalRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewGoalRepository(pool *pgxpool.Pool) GoalRepository {
+	return &goalRepository{pool}
+}
+
+// GetByUserID returns the goals of the user, or nil when none are set.
+func (r *goalRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*Goal, error) {
+	query := `
+		SELECT user_id, minutes_per_day, days_per_week, sessions_per_week, grace_day, version
+		FROM practice_goals
+		WHERE user_id = $1
+	`
+
+	var goal Goal
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		userID,
+	).Scan(
+		&goal.UserID,
+		&goal.MinutesPerDay,
+		&goal.DaysPerWeek,
+		&goal.SessionsPerWeek,
+		&goal.GraceDay,
+		&goal.Version,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan goal: %w", err)
+	}
+
+	return &goal, nil
+}
+
+// Save creates the goals of the user or updates them when the version
+// matches the stored one.
+func (r *goalRepository) Save(ctx context.Context, model *Goal) (*Goal, error) {
+	query := `
+		INSERT INTO practice_goals(user_id, minutes_per_day, days_per_week, sessions_per_week, grace_day)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET minutes_per_day = EXCLUDED.minutes_per_day,
+			days_per_week = EXCLUDED.days_per_week,
+			sessions_per_week = EXCLUDED.sessions_per_week,
+			grace_day = EXCLUDED.grace_day,
+			version = practice_goals.version + 1,
+			updated_at = NOW()
+		WHERE practice_goals.version = $6
+		RETURNING version
+	`
+
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		model.UserID,
+		model.MinutesPerDay,
+		model.DaysPerWeek,
+		model.SessionsPerWeek,
+		model.GraceDay,
+		model.Version,
+	).Scan(
+		&model.Version,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.ErrVersionConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save goal: %w", err)
+	}
+
+	return model, nil
+}
@@ -0,0 +1,184 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// GetGoals returns the goals of the user together with today's and this
+// week's progress and the practice streaks, all counted in the user's time
+// zone.
+func (s *service) GetGoals(ctx context.Context, timeZone string, userID uuid.UUID) (*GetGoalsResponse, error) {
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, timeZone, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := s.goalRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get goals from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+	if goal == nil {
+		goal = &Goal{UserID: userID}
+	}
+
+	today := startOfDay(time.Now().In(loc))
+	weekStart := periodStart(today, StatsPeriodWeek)
+
+	entries, err := s.statsRepo.GetPracticeEntries(ctx, userID, today.AddDate(0, 0, 1))
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	seconds := make(map[string]int)
+	var firstDay time.Time
+	todaySessions, weekSessions := 0, 0
+	for i := range entries {
+		entry := &entries[i]
+		day := startOfDay(entry.StartTime.In(loc))
+		if firstDay.IsZero() || day.Before(firstDay) {
+			firstDay = day
+		}
+
+		seconds[day.Format(statsDateLayout)] += entry.GetDurationSeconds()
+		if day.Equal(today) {
+			todaySessions++
+		}
+		if !day.Before(weekStart) {
+			weekSessions++
+		}
+	}
+
+	result := GetGoalsResponse{
+		Goals:    GoalToGetSettingsResponse(goal),
+		TimeZone: loc.String(),
+		Version:  goal.Version,
+	}
+
+	todayKey := today.Format(statsDateLayout)
+	result.Today = GetGoalDayResponse{
+		Date:     todayKey,
+		Minutes:  seconds[todayKey] / 60,
+		Sessions: todaySessions,
+		Progress: goalProgress(seconds[todayKey]/60, goal.MinutesPerDay),
+		Met:      dayGoalMet(seconds[todayKey], goal),
+	}
+
+	result.Week = GetGoalWeekResponse{
+		Start:    weekStart.Format(statsDateLayout),
+		Sessions: weekSessions,
+	}
+	for day := weekStart; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format(statsDateLayout)
+		result.Week.Minutes += seconds[key]
+		if dayGoalMet(seconds[key], goal) {
+			result.Week.DaysPracticed++
+		}
+	}
+	result.Week.Minutes /= 60
+	result.Week.DaysProgress = goalProgress(result.Week.DaysPracticed, goal.DaysPerWeek)
+	result.Week.SessionsProgress = goalProgress(weekSessions, goal.SessionsPerWeek)
+	result.Week.Met = (goal.DaysPerWeek > 0 || goal.SessionsPerWeek > 0) &&
+		result.Week.DaysPracticed >= goal.DaysPerWeek &&
+		weekSessions >= goal.SessionsPerWeek
+
+	if !firstDay.IsZero() {
+		result.Streak = computeStreak(seconds, goal, firstDay, today)
+	}
+
+	return &result, nil
+}
+
+func (s *service) SaveGoals(ctx context.Context, req *SaveGoalsRequest, timeZone string, userID uuid.UUID) (*GetGoalsResponse, error) {
+	model := SaveRequestToGoal(req, userID)
+	if _, err := s.goalRepo.Save(ctx, &model); err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("goals version conflict", "userID", userID, "version", req.Version)
+			return nil, s.goalConflict(ctx, timeZone, userID)
+		}
+		s.log.Error("failed to save goals in repository",
+			"req", req,
+			"userID", userID,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.GetGoals(ctx, timeZone, userID)
+}
+
+func (s *service) goalConflict(ctx context.Context, timeZone string, userID uuid.UUID) error {
+	current, err := s.GetGoals(ctx, timeZone, userID)
+	if err != nil {
+		return err
+	}
+
+	return &errors.ConflictError{Current: current}
+}
+
+// computeStreak walks the days from the first practice up to today. A day
+// counts when the daily goal was met, or when there was any practice if no
+// daily goal is set. With a grace day one missed day between two counted days
+// keeps the streak alive without adding to it. Today can only extend the
+// current streak: not having practiced yet does not break it.
+func computeStreak(seconds map[string]int, goal *Goal, firstDay, today time.Time) GetStreakResponse {
+	allowedGap := 0
+	if goal.GraceDay {
+		allowedGap = 1
+	}
+
+	var result GetStreakResponse
+	current, gap := 0, 0
+	for day := firstDay; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format(statsDateLayout)
+		if dayGoalMet(seconds[key], goal) {
+			current++
+			gap = 0
+			result.LastPracticeDate = key
+			if current > result.Longest {
+				result.Longest = current
+			}
+			continue
+		}
+
+		if day.Equal(today) {
+			break
+		}
+
+		gap++
+		if gap > allowedGap {
+			current = 0
+		}
+	}
+
+	result.Current = current
+	result.PracticedToday = result.LastPracticeDate == today.Format(statsDateLayout)
+	result.AtRisk = current > 0 && !result.PracticedToday
+
+	return result
+}
+
+func dayGoalMet(seconds int, goal *Goal) bool {
+	if goal.MinutesPerDay > 0 {
+		return seconds >= goal.MinutesPerDay*60
+	}
+
+	return seconds > 0
+}
+
+// goalProgress returns the share of the target reached, capped at 1. It is 0
+// when the target is not set.
+func goalProgress(value, target int) float64 {
+	if target <= 0 {
+		return 0
+	}
+
+	return math.Round(math.Min(float64(value)/float64(target), 1)*100) / 100
+}
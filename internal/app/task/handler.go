@@ -2,25 +2,39 @@ package task
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/chordpro"
 	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/httpx"
 	validation "github.com/RuLap/trackmus-api/internal/pkg/validator"
 	"github.com/darahayes/go-boom"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// MaxUploadSize limits the files uploaded to the import endpoints.
+const MaxUploadSize = 20 << 20
+
 type Handler struct {
-	log     *slog.Logger
-	service Service
+	log       *slog.Logger
+	service   Service
+	analytics AnalyticsService
+	imports   ImportService
+	exports   ExportService
 }
 
-func NewHandler(log *slog.Logger, service Service) *Handler {
-	return &Handler{log: log, service: service}
+func NewHandler(log *slog.Logger, service Service, analytics AnalyticsService, imports ImportService, exports ExportService) *Handler {
+	return &Handler{log: log, service: service, analytics: analytics, imports: imports, exports: exports}
 }
 
 func (h *Handler) GetActiveTasks(w http.ResponseWriter, r *http.Request) {
@@ -68,6 +82,7 @@ func (h *Handler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	httpx.SetETag(w, response.Version)
 	h.sendJSON(w, response, http.StatusOK)
 }
 
@@ -99,6 +114,12 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
 	id, err := h.getUrlParamUuid(r, "id")
 	if err != nil {
 		boom.BadRequest(w, err)
@@ -116,28 +137,56 @@ func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.service.UpdateTask(r.Context(), &req, *id)
+	req.Version, err = httpx.GetVersion(r, req.Version)
 	if err != nil {
-		boom.Internal(w, err)
+		httpx.SendVersionError(w, err)
 		return
 	}
 
+	response, err := h.service.UpdateTask(r.Context(), &req, *id, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
 	h.sendJSON(w, response, http.StatusOK)
 }
 
 func (h *Handler) CompleteTask(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
 	id, err := h.getUrlParamUuid(r, "id")
 	if err != nil {
 		boom.BadRequest(w, err)
 		return
 	}
 
-	response, err := h.service.CompleteTask(r.Context(), *id)
+	version, err := httpx.GetVersion(r, 0)
 	if err != nil {
-		boom.Internal(w, err)
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	response, err := h.service.CompleteTask(r.Context(), *id, version, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
 		return
 	}
 
+	httpx.SetETag(w, response.Version)
 	h.sendJSON(w, response, http.StatusOK)
 }
 
@@ -154,6 +203,117 @@ func (h *Handler) GetSessionByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	req.Version, err = httpx.GetVersion(r, req.Version)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	response, err := h.service.UpdateSession(r.Context(), &req, *id, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) AddSessionReps(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req AddRepsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.AddSessionReps(r.Context(), &req, *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) RemoveSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	version, err := httpx.GetVersion(r, 0)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	response, err := h.service.RemoveSession(r.Context(), *id, version, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
 	h.sendJSON(w, response, http.StatusOK)
 }
 
@@ -184,6 +344,161 @@ func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, response, http.StatusOK)
 }
 
+// CreateSessionsBatch accepts sessions recorded offline. Items are validated
+// one by one, so the response reports a status for each of them in the order
+// they were sent.
+func (h *Handler) CreateSessionsBatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req BatchSessionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	results := make([]BatchSessionResult, len(req.Sessions))
+	valid := make([]BatchSessionItemRequest, 0, len(req.Sessions))
+	positions := make([]int, 0, len(req.Sessions))
+	for i, item := range req.Sessions {
+		if errors := validation.ValidateStruct(item); errors != nil {
+			results[i] = BatchSessionResult{
+				ClientID: item.ClientID,
+				Status:   string(BatchItemInvalid),
+				Message:  "ошибки валидации",
+				Errors:   errors,
+			}
+			continue
+		}
+		valid = append(valid, item)
+		positions = append(positions, i)
+	}
+
+	if len(valid) > 0 {
+		saved := h.service.CreateSessionsBatch(r.Context(), valid, *userID)
+		for i, result := range saved {
+			results[positions[i]] = result
+		}
+	}
+
+	response := BatchSessionsResponse{Results: results}
+	for _, result := range results {
+		switch BatchItemStatus(result.Status) {
+		case BatchItemCreated:
+			response.Created++
+		case BatchItemDuplicate:
+		default:
+			response.Failed++
+		}
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) StartSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	taskID, err := h.getUrlParamUuid(r, "task_id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.StartSession(r.Context(), *taskID, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) PauseSession(w http.ResponseWriter, r *http.Request) {
+	h.changeSessionState(w, r, h.service.PauseSession)
+}
+
+func (h *Handler) ResumeSession(w http.ResponseWriter, r *http.Request) {
+	h.changeSessionState(w, r, h.service.ResumeSession)
+}
+
+func (h *Handler) HeartbeatSession(w http.ResponseWriter, r *http.Request) {
+	h.changeSessionState(w, r, h.service.HeartbeatSession)
+}
+
+func (h *Handler) FinishSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req FinishSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.FinishSession(r.Context(), &req, *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) changeSessionState(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error),
+) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := change(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
 func (h *Handler) GetMediaUploadURL(w http.ResponseWriter, r *http.Request) {
 	taskID, err := h.getUrlParamUuid(r, "task_id")
 	if err != nil {
@@ -203,6 +518,12 @@ func (h *Handler) GetMediaUploadURL(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ConfirmMediaUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
 	id, err := h.getUrlParamUuid(r, "id")
 	if err != nil {
 		boom.BadRequest(w, err)
@@ -220,9 +541,9 @@ func (h *Handler) ConfirmMediaUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.service.ConfirmMediaUpload(r.Context(), &req, *id)
+	response, err := h.service.ConfirmMediaUpload(r.Context(), &req, *id, *userID)
 	if err != nil {
-		boom.Internal(w, err)
+		h.sendServiceError(w, err)
 		return
 	}
 
@@ -272,38 +593,1331 @@ func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, response, http.StatusOK)
 }
 
-func (h *Handler) RemoveLink(w http.ResponseWriter, r *http.Request) {
-	id, err := h.getUrlParamUuid(r, "id")
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
 		boom.BadRequest(w, err)
 		return
 	}
 
-	err = h.service.RemoveLink(r.Context(), *id)
+	id, err := h.getUrlParamUuid(r, "id")
 	if err != nil {
-		boom.Internal(w, err)
+		boom.BadRequest(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-}
+	var req SaveLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
 
-func (h *Handler) getUrlParamUuid(r *http.Request, param string) (*uuid.UUID, error) {
-	str := chi.URLParam(r, param)
-	if str == "" {
-		err := fmt.Errorf("параметр %s необходим", param)
-		h.log.Error("Incorrect ID in URL", param, str, "error", err.Error())
-		return nil, err
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
 	}
 
-	uid, err := uuid.Parse(str)
+	req.Version, err = httpx.GetVersion(r, req.Version)
 	if err != nil {
-		err := fmt.Errorf("неверный формат параметра %s", param)
-		h.log.Error("Incorrect ID in URL", param, str, "error", err.Error())
-		return nil, err
+		httpx.SendVersionError(w, err)
+		return
 	}
 
-	return &uid, nil
+	response, err := h.service.UpdateLink(r.Context(), &req, *id, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) RemoveLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	version, err := httpx.GetVersion(r, 0)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	err = h.service.RemoveLink(r.Context(), *id, version, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) getUrlParamUuid(r *http.Request, param string) (*uuid.UUID, error) {
+	str := chi.URLParam(r, param)
+	if str == "" {
+		err := fmt.Errorf("параметр %s необходим", param)
+		h.log.Error("Incorrect ID in URL", param, str, "error", err.Error())
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(str)
+	if err != nil {
+		err := fmt.Errorf("неверный формат параметра %s", param)
+		h.log.Error("Incorrect ID in URL", param, str, "error", err.Error())
+		return nil, err
+	}
+
+	return &uid, nil
+}
+
+func (h *Handler) GetEnsembles(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.GetEnsembles(r.Context(), *userID)
+	if err != nil {
+		boom.Internal(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetEnsembleByID(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.GetEnsembleByID(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) CreateEnsemble(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveEnsembleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.CreateEnsemble(r.Context(), &req, *userID)
+	if err != nil {
+		boom.Internal(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) InviteEnsembleMember(w http.ResponseWriter, r *http.Request) {
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req InviteEnsembleMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.InviteEnsembleMember(r.Context(), &req, *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) AcceptEnsembleInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	email, ok := r.Context().Value("user_email").(string)
+	if !ok {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var req AcceptEnsembleInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.AcceptEnsembleInvitation(r.Context(), &req, *userID, email)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) ImportMusicXML(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	data, filename, err := h.getUploadedFile(w, r, ".xml", ".musicxml", ".mxl")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getImportScoreRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.imports.ImportMusicXML(r.Context(), req, data, filename, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) ImportMIDI(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	data, filename, err := h.getUploadedFile(w, r, ".mid", ".midi", ".smf")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getImportScoreRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.imports.ImportMIDI(r.Context(), req, data, filename, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) GetCharts(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	taskID, err := h.getUrlParamUuid(r, "task_id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.GetCharts(r.Context(), *taskID, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetChartByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getRenderChartRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.GetChartByID(r.Context(), req, *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	if req.Format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, response.HTML)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) CreateChart(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	taskID, err := h.getUrlParamUuid(r, "task_id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveChartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.CreateChart(r.Context(), &req, *taskID, *userID)
+	if err != nil {
+		if list, ok := err.(chordpro.Errors); ok {
+			h.sendChartErrors(w, list)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) UpdateChart(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveChartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	req.Version, err = httpx.GetVersion(r, req.Version)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	response, err := h.service.UpdateChart(r.Context(), &req, *id, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		if list, ok := err.(chordpro.Errors); ok {
+			h.sendChartErrors(w, list)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) RemoveChart(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	err = h.service.RemoveChart(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// sendServiceError maps the user-facing service errors to HTTP statuses.
+// GetChanges returns the changes since the token passed in the since query
+// parameter. Without it the full state is returned.
+func (h *Handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.GetChanges(r.Context(), r.URL.Query().Get("since"), *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) PushChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response := h.service.PushChanges(r.Context(), &req, *userID)
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	req := GetStatsRequest{
+		Period:   query.Get("period"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		TimeZone: query.Get("tz"),
+	}
+
+	if year := query.Get("year"); year != "" {
+		req.Year, err = strconv.Atoi(year)
+		if err != nil {
+			boom.BadRequest(w, "неверный формат параметра year")
+			return
+		}
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.analytics.GetStats(r.Context(), &req, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetTempoRecommendation(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	taskID, err := h.getUrlParamUuid(r, "task_id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req := GetTempoRecommendationRequest{
+		Aggressiveness: r.URL.Query().Get("aggressiveness"),
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.analytics.GetTempoRecommendation(r.Context(), &req, *taskID, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.analytics.GetRecommendations(r.Context(), *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetGoals(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.GetGoals(r.Context(), r.URL.Query().Get("tz"), *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	if response.Version > 0 {
+		httpx.SetETag(w, response.Version)
+	}
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) SaveGoals(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveGoalsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	// Goals are created on the first save, so a version is only required once
+	// they exist; a missing one then ends in a conflict with the current goals.
+	if req.Version > 0 || r.Header.Get("If-Match") != "" {
+		req.Version, err = httpx.GetVersion(r, req.Version)
+		if err != nil {
+			httpx.SendVersionError(w, err)
+			return
+		}
+	}
+
+	response, err := h.service.SaveGoals(r.Context(), &req, r.URL.Query().Get("tz"), *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getReportRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.analytics.GetReport(r.Context(), req, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) SendReport(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	email, ok := r.Context().Value("user_email").(string)
+	if !ok {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	req, err := h.getReportRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	if err := h.analytics.SendReport(r.Context(), req, *userID, email); err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) GetPracticePDF(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getReportRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	document, err := h.analytics.RenderPracticePDF(r.Context(), req, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="practice-log.pdf"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+func (h *Handler) getReportRequest(r *http.Request) (*GetReportRequest, error) {
+	query := r.URL.Query()
+	req := GetReportRequest{
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		TimeZone: query.Get("tz"),
+	}
+
+	if year := query.Get("year"); year != "" {
+		var err error
+		req.Year, err = strconv.Atoi(year)
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат параметра year")
+		}
+	}
+
+	return &req, nil
+}
+
+func (h *Handler) ShareTask(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.service.ShareTask(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) UnshareTask(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if err := h.service.UnshareTask(r.Context(), *id, *userID); err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetSharedProgress(w http.ResponseWriter, r *http.Request) {
+	image, err := h.service.RenderSharedProgress(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendSVG(w, r, image)
+}
+
+func (h *Handler) GetSharedBPMChart(w http.ResponseWriter, r *http.Request) {
+	image, err := h.service.RenderSharedBPMChart(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendSVG(w, r, image)
+}
+
+// sendSVG serves a public image. It may be cached for a few minutes and is
+// revalidated by its ETag, so embedding pages pick up new progress quickly.
+func (h *Handler) sendSVG(w http.ResponseWriter, r *http.Request, image string) {
+	hash := sha256.Sum256([]byte(image))
+	etag := strconv.Quote(hex.EncodeToString(hash[:8]))
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, image)
+}
+
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req := ExportRequest{
+		Entity: chi.URLParam(r, "entity"),
+		Format: r.URL.Query().Get("format"),
+	}
+	if req.Format == "" {
+		req.Format = string(ExportFormatCSV)
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if ExportFormat(req.Format) == ExportFormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, req.Entity, req.Format))
+
+	h.stream(w, func(out io.Writer) error {
+		return h.exports.Export(r.Context(), &req, *userID, out)
+	})
+}
+
+func (h *Handler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.exports.GetCalendarFeed(r.Context(), *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.exports.CreateCalendarFeed(r.Context(), *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) RemoveCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if err := h.exports.RemoveCalendarFeed(r.Context(), *userID); err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+
+	h.stream(w, func(out io.Writer) error {
+		return h.exports.RenderCalendarFeed(r.Context(), chi.URLParam(r, "token"), out)
+	})
+}
+
+func (h *Handler) PreviewImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	data, _, err := h.getUploadedFile(w, r, ".csv", ".txt")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getImportMappingRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.imports.PreviewImport(r.Context(), req, data, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	data, filename, err := h.getUploadedFile(w, r, ".csv", ".txt")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req, err := h.getImportMappingRequest(r)
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.imports.CreateImport(r.Context(), req, data, filename, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/imports/"+response.ID)
+	h.sendJSON(w, response, http.StatusAccepted)
+}
+
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	response, err := h.imports.GetImport(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetJournalEntries(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	req := GetJournalEntriesRequest{
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Query:  strings.TrimSpace(query.Get("q")),
+		TaskID: query.Get("task_id"),
+		Format: query.Get("format"),
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.GetJournalEntries(r.Context(), &req, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetJournalEntryByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	req := RenderJournalEntryRequest{
+		Format: r.URL.Query().Get("format"),
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.GetJournalEntryByID(r.Context(), &req, *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	if req.Format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, response.HTML)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) CreateJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveJournalEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	response, err := h.service.CreateJournalEntry(r.Context(), &req, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusCreated)
+}
+
+func (h *Handler) UpdateJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	var req SaveJournalEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "ошибки валидации", errors)
+		return
+	}
+
+	req.Version, err = httpx.GetVersion(r, req.Version)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
+	}
+
+	response, err := h.service.UpdateJournalEntry(r.Context(), &req, *id, *userID)
+	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
+		h.sendServiceError(w, err)
+		return
+	}
+
+	httpx.SetETag(w, response.Version)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) RemoveJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	id, err := h.getUrlParamUuid(r, "id")
+	if err != nil {
+		boom.BadRequest(w, err)
+		return
+	}
+
+	err = h.service.RemoveJournalEntry(r.Context(), *id, *userID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// stream runs a writer of a streamed response. An error is reported to the
+// client only while nothing has been sent; afterwards the response is cut
+// short and the error is logged.
+func (h *Handler) stream(w http.ResponseWriter, write func(io.Writer) error) {
+	out := &countingWriter{w: w}
+	if err := write(out); err != nil {
+		if out.n == 0 {
+			w.Header().Del("Content-Disposition")
+			w.Header().Del("Cache-Control")
+			h.sendServiceError(w, err)
+			return
+		}
+		h.log.Error("streamed response interrupted", "written", out.n, "error", err)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (h *Handler) sendServiceError(w http.ResponseWriter, err error) {
+	switch err.Error() {
+	case errors.ErrAccessDenied:
+		boom.Forbidden(w, err)
+	case errors.ErrInvalidData, errors.ErrInvalidScore, errors.ErrTempoNotFound, errors.ErrInvalidSyncToken,
+		errors.ErrInvalidImportFile, errors.ErrImportColumnNotFound, errors.ErrImportTooLarge:
+		boom.BadRequest(w, err)
+	case errors.ErrSessionState, errors.ErrAlreadyMember:
+		boom.Conflict(w, err)
+	case errors.ErrInvitationExpired:
+		boom.ResourceGone(w, err)
+	case errors.ErrShareNotFound, errors.ErrImportNotFound, errors.ErrJournalEntryNotFound:
+		boom.NotFound(w, err)
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+// sendChartErrors reports ChordPro problems with their line numbers.
+func (h *Handler) sendChartErrors(w http.ResponseWriter, list chordpro.Errors) {
+	h.sendJSON(w, map[string]interface{}{
+		"message": errors.ErrInvalidChart,
+		"errors":  list,
+	}, http.StatusBadRequest)
+}
+
+// getUploadedFile reads the "file" field of a multipart form and checks its
+// extension against the allowed ones.
+func (h *Handler) getUploadedFile(w http.ResponseWriter, r *http.Request, extensions ...string) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+		h.log.Error("failed to parse multipart form", "error", err)
+		return nil, "", fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.log.Error("file is missing in multipart form", "error", err)
+		return nil, "", fmt.Errorf("параметр file необходим")
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	supported := false
+	for _, e := range extensions {
+		supported = supported || ext == e
+	}
+	if !supported {
+		return nil, "", fmt.Errorf(errors.ErrUnsupportedFile)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.log.Error("failed to read uploaded file", "filename", header.Filename, "error", err)
+		return nil, "", fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	return data, filepath.Base(header.Filename), nil
+}
+
+func (h *Handler) getImportScoreRequest(r *http.Request) (*ImportScoreRequest, error) {
+	req := ImportScoreRequest{
+		Title: strings.TrimSpace(r.FormValue("title")),
+	}
+
+	if bpm := r.FormValue("target_bpm"); bpm != "" {
+		value, err := strconv.Atoi(bpm)
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат параметра target_bpm")
+		}
+		req.TargetBPM = value
+	}
+
+	return &req, nil
+}
+
+// getImportMappingRequest reads the column mapping sent as JSON in the
+// mapping field of the form.
+func (h *Handler) getImportMappingRequest(r *http.Request) (*ImportMappingRequest, error) {
+	value := r.FormValue("mapping")
+	if value == "" {
+		return nil, fmt.Errorf("параметр mapping необходим")
+	}
+
+	var req ImportMappingRequest
+	if err := json.Unmarshal([]byte(value), &req); err != nil {
+		h.log.Error("failed to decode import mapping", "error", err)
+		return nil, fmt.Errorf("неверный формат параметра mapping")
+	}
+
+	return &req, nil
+}
+
+func (h *Handler) getRenderChartRequest(r *http.Request) (*RenderChartRequest, error) {
+	query := r.URL.Query()
+	req := RenderChartRequest{
+		Format: query.Get("format"),
+	}
+
+	if transpose := query.Get("transpose"); transpose != "" {
+		value, err := strconv.Atoi(transpose)
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат параметра transpose")
+		}
+		req.Transpose = value
+	}
+
+	if capo := query.Get("capo"); capo != "" {
+		value, err := strconv.Atoi(capo)
+		if err != nil {
+			return nil, fmt.Errorf("неверный формат параметра capo")
+		}
+		req.Capo = &value
+	}
+
+	return &req, nil
 }
 
 func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
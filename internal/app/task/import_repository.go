@@ -0,0 +1,198 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ImportRepository keeps the CSV imports and hands them out to the import
+// worker one at a time.
+type ImportRepository interface {
+	Create(ctx context.Context, model *Import) (*Import, error)
+	GetByID(ctx context.Context, id, userID uuid.UUID) (*Import, error)
+	ClaimNext(ctx context.Context, staleBefore time.Time) (*Import, error)
+	UpdateProgress(ctx context.Context, model *Import) error
+	Finish(ctx context.Context, model *Import) error
+}
+
+type importRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImportRepository(pool *pgxpool.Pool) ImportRepository {
+	return &importRepository{pool}
+}
+
+func (r *importRepository) Create(ctx context.Context, model *Import) (*Import, error) {
+	query := `
+		INSERT INTO imports(user_id, filename, mapping, data, total_rows)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		model.UserID,
+		model.Filename,
+		model.Mapping,
+		model.Data,
+		model.TotalRows,
+	).Scan(
+		&model.ID,
+		&model.Status,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import: %w", err)
+	}
+
+	return model, nil
+}
+
+// GetByID returns the import of the user without its file, or nil when there
+// is none.
+func (r *importRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*Import, error) {
+	query := `
+		SELECT id, user_id, status, filename, mapping, NULL::BYTEA, total_rows, processed_rows, imported_sessions,
+			skipped_sessions, failed_rows, created_tasks, errors, message, created_at, updated_at, finished_at
+		FROM imports
+		WHERE id = $1 AND user_id = $2
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, id, userID))
+}
+
+// ClaimNext marks the oldest pending import as processing and returns it with
+// its file, or nil when there is nothing to do. Imports stuck in processing
+// since before staleBefore, e.g. after a restart, are picked up again.
+func (r *importRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*Import, error) {
+	query := `
+		UPDATE imports
+		SET status = 'processing',
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM imports
+			WHERE status = 'pending' OR (status = 'processing' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, filename, mapping, data, total_rows, processed_rows, imported_sessions,
+			skipped_sessions, failed_rows, created_tasks, errors, message, created_at, updated_at, finished_at
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, staleBefore))
+}
+
+func (r *importRepository) UpdateProgress(ctx context.Context, model *Import) error {
+	query := `
+		UPDATE imports
+		SET total_rows = $2,
+			processed_rows = $3,
+			imported_sessions = $4,
+			skipped_sessions = $5,
+			failed_rows = $6,
+			created_tasks = $7,
+			errors = $8,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(
+		ctx,
+		query,
+		model.ID,
+		model.TotalRows,
+		model.ProcessedRows,
+		model.ImportedSessions,
+		model.SkippedSessions,
+		model.FailedRows,
+		model.CreatedTasks,
+		model.Errors,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update import progress: %w", err)
+	}
+
+	return nil
+}
+
+// Finish stores the final state of the import and drops its file.
+func (r *importRepository) Finish(ctx context.Context, model *Import) error {
+	query := `
+		UPDATE imports
+		SET status = $2,
+			total_rows = $3,
+			processed_rows = $4,
+			imported_sessions = $5,
+			skipped_sessions = $6,
+			failed_rows = $7,
+			created_tasks = $8,
+			errors = $9,
+			message = $10,
+			data = NULL,
+			updated_at = NOW(),
+			finished_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(
+		ctx,
+		query,
+		model.ID,
+		model.Status,
+		model.TotalRows,
+		model.ProcessedRows,
+		model.ImportedSessions,
+		model.SkippedSessions,
+		model.FailedRows,
+		model.CreatedTasks,
+		model.Errors,
+		model.Message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish import: %w", err)
+	}
+
+	return nil
+}
+
+func (r *importRepository) scan(row pgx.Row) (*Import, error) {
+	var model Import
+	err := row.Scan(
+		&model.ID,
+		&model.UserID,
+		&model.Status,
+		&model.Filename,
+		&model.Mapping,
+		&model.Data,
+		&model.TotalRows,
+		&model.ProcessedRows,
+		&model.ImportedSessions,
+		&model.SkippedSessions,
+		&model.FailedRows,
+		&model.CreatedTasks,
+		&model.Errors,
+		&model.Message,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+		&model.FinishedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan import: %w", err)
+	}
+
+	return &model, nil
+}
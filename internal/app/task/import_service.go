@@ -0,0 +1,734 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/storage/minio"
+	"github.com/google/uuid"
+)
+
+// ImportService creates tasks and sessions from uploaded files: CSV exports
+// of other apps, MusicXML scores and MIDI files.
+type ImportService interface {
+	PreviewImport(ctx context.Context, req *ImportMappingRequest, data []byte, userID uuid.UUID) (*GetImportPreviewResponse, error)
+	CreateImport(ctx context.Context, req *ImportMappingRequest, data []byte, filename string, userID uuid.UUID) (*GetImportResponse, error)
+	GetImport(ctx context.Context, id, userID uuid.UUID) (*GetImportResponse, error)
+	ProcessNextImport(ctx context.Context, staleAfter time.Duration) (bool, error)
+
+	ImportMusicXML(ctx context.Context, req *ImportScoreRequest, data []byte, filename string, userID uuid.UUID) (*GetTaskResponse, error)
+	ImportMIDI(ctx context.Context, req *ImportScoreRequest, data []byte, filename string, userID uuid.UUID) (*GetTaskResponse, error)
+}
+
+// importTasks is the part of the task service that imports build on.
+type importTasks interface {
+	GetTaskByID(ctx context.Context, id uuid.UUID) (*GetTaskResponse, error)
+	refreshTaskState(ctx context.Context, taskID uuid.UUID)
+}
+
+type importService struct {
+	log         *slog.Logger
+	minio       *minio.Service
+	bucketName  string
+	tasks       importTasks
+	taskRepo    TaskRepository
+	sessionRepo SessionRepository
+	scoreRepo   ScoreRepository
+	statsRepo   StatsRepository
+	importRepo  ImportRepository
+}
+
+func NewImportService(
+	log *slog.Logger,
+	minio *minio.Service,
+	tasks Service,
+	taskRepo TaskRepository,
+	sessionRepo SessionRepository,
+	scoreRepo ScoreRepository,
+	statsRepo StatsRepository,
+	importRepo ImportRepository,
+) ImportService {
+	return &importService{
+		log:         log,
+		minio:       minio,
+		bucketName:  "trackmus",
+		tasks:       tasks,
+		taskRepo:    taskRepo,
+		sessionRepo: sessionRepo,
+		scoreRepo:   scoreRepo,
+		statsRepo:   statsRepo,
+		importRepo:  importRepo,
+	}
+}
+
+const (
+	importPreviewRows = 50
+	importChunkSize   = 500
+	maxImportRows     = 100000
+	maxImportErrors   = 100
+	maxImportDuration = 24 * time.Hour
+	maxImportBPM      = 400
+	maxImportNote     = 1000
+	maxTaskTitle      = 50
+)
+
+// importDateLayouts are tried in order when the mapping has no date format.
+var importDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+var importTimeLayouts = []string{"15:04:05", "15:04", "3:04 PM", "3:04PM"}
+
+var importDateFormat = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// PreviewImport parses the file as the import would, without saving anything,
+// and returns the first rows with their validation errors along with the
+// counts for the whole file.
+func (s *importService) PreviewImport(ctx context.Context, req *ImportMappingRequest, data []byte, userID uuid.UUID) (*GetImportPreviewResponse, error) {
+	mapping := ImportMappingRequestToModel(req)
+
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, mapping.TimeZone, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, parser, err := s.openImport(data, &mapping, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := s.getTaskTitles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := GetImportPreviewResponse{
+		Columns:  parser.header,
+		Rows:     make([]GetImportRowResponse, 0, importPreviewRows),
+		NewTasks: make([]string, 0),
+	}
+
+	newTasks := make(map[string]bool)
+	err = readImportRows(reader, parser, func(row *importRow) error {
+		result.TotalRows++
+		if result.TotalRows > maxImportRows {
+			return fmt.Errorf(errors.ErrImportTooLarge)
+		}
+
+		if row.Err != nil {
+			result.InvalidRows++
+		} else {
+			result.ValidRows++
+		}
+
+		key := taskTitleKey(row.Task)
+		_, exists := known[key]
+		if row.Err == nil && !exists && !newTasks[key] {
+			newTasks[key] = true
+			result.NewTasks = append(result.NewTasks, row.Task)
+		}
+
+		if len(result.Rows) < importPreviewRows {
+			dto := ImportRowToGetResponse(row)
+			dto.NewTask = row.Task != "" && !exists
+			result.Rows = append(result.Rows, dto)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, s.importReadError(err)
+	}
+
+	return &result, nil
+}
+
+// CreateImport checks the file against the mapping and queues it for the
+// import worker.
+func (s *importService) CreateImport(ctx context.Context, req *ImportMappingRequest, data []byte, filename string, userID uuid.UUID) (*GetImportResponse, error) {
+	mapping := ImportMappingRequestToModel(req)
+
+	reader, _, err := s.openImport(data, &mapping, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, s.importReadError(err)
+		}
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		total++
+		if total > maxImportRows {
+			return nil, fmt.Errorf(errors.ErrImportTooLarge)
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf(errors.ErrInvalidImportFile)
+	}
+
+	model, err := s.importRepo.Create(ctx, &Import{
+		UserID:    userID,
+		Filename:  truncate(filename, 255),
+		Mapping:   mapping,
+		Data:      data,
+		TotalRows: total,
+	})
+	if err != nil {
+		s.log.Error("failed to create import in repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	s.log.Info("import queued", "importID", model.ID, "userID", userID, "rows", total)
+
+	result := ImportToGetResponse(model)
+
+	return &result, nil
+}
+
+func (s *importService) GetImport(ctx context.Context, id, userID uuid.UUID) (*GetImportResponse, error) {
+	model, err := s.importRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		s.log.Error("failed to get import from repository", "importID", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if model == nil {
+		return nil, fmt.Errorf(errors.ErrImportNotFound)
+	}
+
+	result := ImportToGetResponse(model)
+
+	return &result, nil
+}
+
+// ProcessNextImport runs the oldest queued import to the end. It reports
+// false when the queue is empty. Imports left in processing for longer than
+// staleAfter are run again; sessions saved by the first run are recognized
+// by their client ids and skipped.
+func (s *importService) ProcessNextImport(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	job, err := s.importRepo.ClaimNext(ctx, time.Now().Add(-staleAfter))
+	if err != nil {
+		s.log.Error("failed to claim import from repository", "error", err)
+		return false, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if job == nil {
+		return false, nil
+	}
+
+	s.log.Info("import started", "importID", job.ID, "userID", job.UserID)
+
+	job.Status = ImportStatusCompleted
+	if err := s.runImport(ctx, job); err != nil {
+		job.Status = ImportStatusFailed
+		job.Message = err.Error()
+	}
+
+	if err := s.importRepo.Finish(ctx, job); err != nil {
+		s.log.Error("failed to finish import in repository", "importID", job.ID, "error", err)
+		return true, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	s.log.Info("import finished",
+		"importID", job.ID,
+		"status", job.Status,
+		"imported", job.ImportedSessions,
+		"skipped", job.SkippedSessions,
+		"failed", job.FailedRows,
+		"createdTasks", job.CreatedTasks,
+	)
+
+	return true, nil
+}
+
+// runImport saves the sessions of the valid rows, creating the tasks that do
+// not exist yet. A task is matched by its title regardless of case and
+// spacing; a new one gets the fastest imported tempo as its target.
+func (s *importService) runImport(ctx context.Context, job *Import) error {
+	job.ProcessedRows, job.ImportedSessions, job.SkippedSessions, job.FailedRows, job.CreatedTasks = 0, 0, 0, 0, 0
+	job.Errors = make([]ImportRowError, 0)
+
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, job.Mapping.TimeZone, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	reader, parser, err := s.openImport(job.Data, &job.Mapping, loc)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]importRow, 0, job.TotalRows)
+	bestBPM := make(map[string]int)
+	err = readImportRows(reader, parser, func(row *importRow) error {
+		if row.Err != nil {
+			job.FailedRows++
+			if len(job.Errors) < maxImportErrors {
+				job.Errors = append(job.Errors, ImportRowError{Row: row.Row, Message: row.Err.Error()})
+			}
+			return nil
+		}
+
+		key := taskTitleKey(row.Task)
+		bestBPM[key] = max(bestBPM[key], row.BPM)
+		rows = append(rows, *row)
+		return nil
+	})
+	if err != nil {
+		return s.importReadError(err)
+	}
+
+	job.TotalRows = len(rows) + job.FailedRows
+	job.ProcessedRows = job.FailedRows
+
+	taskIDs, err := s.getTaskTitles(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	touched := make(map[uuid.UUID]bool)
+	for start := 0; start < len(rows); start += importChunkSize {
+		chunk := rows[start:min(start+importChunkSize, len(rows))]
+
+		sessions := make([]Session, 0, len(chunk))
+		for i := range chunk {
+			row := &chunk[i]
+			key := taskTitleKey(row.Task)
+
+			taskID, exists := taskIDs[key]
+			if !exists {
+				task, err := s.taskRepo.Create(ctx, &Task{Title: row.Task, TargetBPM: bestBPM[key]}, job.UserID)
+				if err != nil {
+					s.log.Error("failed to create task in repository", "importID", job.ID, "error", err)
+					return fmt.Errorf(errors.ErrFailedToSaveData)
+				}
+				taskID = task.ID
+				taskIDs[key] = taskID
+				job.CreatedTasks++
+			}
+			touched[taskID] = true
+
+			sessions = append(sessions, Session{
+				TaskID:     taskID,
+				BPM:        row.BPM,
+				Note:       row.Note,
+				Confidence: row.Confidence,
+				StartTime:  row.StartTime,
+				EndTime:    row.EndTime,
+				ClientID:   importClientID(row),
+			})
+		}
+
+		created, err := s.sessionRepo.CreateMany(ctx, sessions)
+		if err != nil {
+			s.log.Error("failed to create sessions in repository", "importID", job.ID, "error", err)
+			return fmt.Errorf(errors.ErrFailedToSaveData)
+		}
+
+		job.ImportedSessions += int(created)
+		job.SkippedSessions += len(sessions) - int(created)
+		job.ProcessedRows += len(chunk)
+
+		if err := s.importRepo.UpdateProgress(ctx, job); err != nil {
+			s.log.Warn("failed to update import progress", "importID", job.ID, "error", err)
+		}
+	}
+
+	for taskID := range touched {
+		s.tasks.refreshTaskState(ctx, taskID)
+	}
+
+	return nil
+}
+
+// openImport reads the header of the file and matches it against the
+// mapping.
+func (s *importService) openImport(data []byte, mapping *ImportMapping, loc *time.Location) (*csv.Reader, *importParser, error) {
+	reader := newImportReader(data, mapping.Delimiter)
+
+	header, err := reader.Read()
+	if err != nil {
+		s.log.Info("failed to read import header", "error", err)
+		return nil, nil, fmt.Errorf(errors.ErrInvalidImportFile)
+	}
+
+	parser, err := newImportParser(header, mapping, loc)
+	if err != nil {
+		s.log.Info("import mapping does not match the file", "header", header, "error", err)
+		return nil, nil, fmt.Errorf(errors.ErrImportColumnNotFound)
+	}
+
+	return reader, parser, nil
+}
+
+func (s *importService) importReadError(err error) error {
+	if err.Error() == errors.ErrImportTooLarge {
+		return err
+	}
+
+	s.log.Info("failed to read import file", "error", err)
+	return fmt.Errorf(errors.ErrInvalidImportFile)
+}
+
+// getTaskTitles maps the title keys of the user's tasks to their ids.
+func (s *importService) getTaskTitles(ctx context.Context, userID uuid.UUID) (map[string]uuid.UUID, error) {
+	tasks, err := s.taskRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	titles := make(map[string]uuid.UUID, len(tasks))
+	for _, task := range tasks {
+		key := taskTitleKey(task.Title)
+		if _, exists := titles[key]; !exists {
+			titles[key] = task.ID
+		}
+	}
+
+	return titles, nil
+}
+
+// importRow is a session read from a CSV row, or the reason it was rejected.
+type importRow struct {
+	Row        int
+	Task       string
+	StartTime  time.Time
+	EndTime    time.Time
+	BPM        int
+	Confidence int
+	Note       string
+	Err        error
+}
+
+// importParser turns CSV records into sessions as described by a mapping.
+type importParser struct {
+	header  []string
+	mapping *ImportMapping
+	loc     *time.Location
+	layouts []string
+
+	task, date, clock, end, duration, bpm, confidence, note int
+}
+
+func newImportParser(header []string, mapping *ImportMapping, loc *time.Location) (*importParser, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, exists := positions[key]; !exists {
+			positions[key] = i
+		}
+	}
+
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+
+		position, exists := positions[strings.ToLower(name)]
+		if !exists {
+			return -1, fmt.Errorf("column %q not found", name)
+		}
+
+		return position, nil
+	}
+
+	p := &importParser{header: header, mapping: mapping, loc: loc, layouts: importDateLayouts}
+	if mapping.DateFormat != "" {
+		p.layouts = []string{importDateFormat.Replace(mapping.DateFormat)}
+	}
+
+	targets := []struct {
+		name  string
+		index *int
+	}{
+		{mapping.Task, &p.task},
+		{mapping.Date, &p.date},
+		{mapping.Time, &p.clock},
+		{mapping.End, &p.end},
+		{mapping.Duration, &p.duration},
+		{mapping.BPM, &p.bpm},
+		{mapping.Confidence, &p.confidence},
+		{mapping.Note, &p.note},
+	}
+	for _, target := range targets {
+		position, err := column(target.name)
+		if err != nil {
+			return nil, err
+		}
+		*target.index = position
+	}
+
+	if p.task < 0 || p.date < 0 || (p.end < 0 && p.duration < 0) {
+		return nil, fmt.Errorf("task, date and end or duration columns are required")
+	}
+
+	return p, nil
+}
+
+func (p *importParser) parse(record []string, line int) *importRow {
+	row := &importRow{Row: line}
+	value := func(index int) string {
+		if index < 0 || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	row.Task = strings.Join(strings.Fields(value(p.task)), " ")
+	row.Note = truncate(value(p.note), maxImportNote)
+
+	switch {
+	case row.Task == "":
+		row.Err = fmt.Errorf("не указано название задачи")
+	case len([]rune(row.Task)) > maxTaskTitle:
+		row.Err = fmt.Errorf("название задачи длиннее %d символов", maxTaskTitle)
+	default:
+		row.Err = p.parseFields(row, value)
+	}
+
+	return row
+}
+
+func (p *importParser) parseFields(row *importRow, value func(int) string) error {
+	date := value(p.date)
+	if date == "" {
+		return fmt.Errorf("не указана дата")
+	}
+
+	start, err := p.parseDate(date)
+	if err != nil {
+		return fmt.Errorf("неверный формат даты %q", date)
+	}
+
+	if p.clock >= 0 {
+		if clock := value(p.clock); clock != "" {
+			if start, err = p.atTime(start, clock); err != nil {
+				return fmt.Errorf("неверный формат времени %q", clock)
+			}
+		}
+	}
+	row.StartTime = start
+
+	if end := value(p.end); p.end >= 0 && end != "" {
+		endTime, err := p.parseDate(end)
+		if err != nil {
+			if endTime, err = p.atTime(start, end); err != nil {
+				return fmt.Errorf("неверный формат времени %q", end)
+			}
+			if !endTime.After(start) {
+				// A session past midnight ends on the next day.
+				endTime = endTime.AddDate(0, 0, 1)
+			}
+		}
+		row.EndTime = endTime
+	} else {
+		raw := value(p.duration)
+		duration, err := parseImportDuration(raw, p.mapping.DurationUnit)
+		if err != nil {
+			return fmt.Errorf("неверный формат длительности %q", raw)
+		}
+		row.EndTime = start.Add(duration)
+	}
+
+	if duration := row.EndTime.Sub(row.StartTime); duration <= 0 || duration > maxImportDuration {
+		return fmt.Errorf("длительность должна быть больше нуля и не больше 24 часов")
+	}
+
+	if raw := value(p.bpm); raw != "" {
+		bpm, err := parseImportNumber(raw)
+		if err != nil || bpm < 1 || bpm > maxImportBPM {
+			return fmt.Errorf("неверный темп %q", raw)
+		}
+		row.BPM = int(math.Round(bpm))
+	}
+
+	if raw := value(p.confidence); raw != "" {
+		confidence, err := strconv.Atoi(raw)
+		if err != nil || confidence < 1 || confidence > 5 {
+			return fmt.Errorf("уверенность должна быть от 1 до 5")
+		}
+		row.Confidence = confidence
+	}
+
+	return nil
+}
+
+func (p *importParser) parseDate(value string) (time.Time, error) {
+	for _, layout := range p.layouts {
+		if t, err := time.ParseInLocation(layout, value, p.loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format")
+}
+
+// atTime moves the day to the time of day given in value.
+func (p *importParser) atTime(day time.Time, value string) (time.Time, error) {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(value)); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, p.loc), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown time format")
+}
+
+// readImportRows parses the records left in the reader one by one.
+func readImportRows(reader *csv.Reader, parser *importParser, fn func(*importRow) error) error {
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err := fn(parser.parse(record, line)); err != nil {
+			return err
+		}
+	}
+}
+
+// newImportReader reads the CSV leniently, as spreadsheet apps write it. The
+// delimiter is guessed from the header line when not given.
+func newImportReader(data []byte, delimiter string) *csv.Reader {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	if delimiter != "" {
+		reader.Comma = []rune(delimiter)[0]
+		return reader
+	}
+
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	best := 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if count := bytes.Count(firstLine, []byte(string(candidate))); count > best {
+			best = count
+			reader.Comma = candidate
+		}
+	}
+
+	return reader
+}
+
+// parseImportDuration reads a duration either as h:mm[:ss] or as a number of
+// the given unit, minutes by default.
+func parseImportDuration(value, unit string) (time.Duration, error) {
+	if strings.Contains(value, ":") {
+		parts := strings.Split(value, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("too many parts")
+		}
+
+		seconds := 0
+		for _, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid part %q", part)
+			}
+			seconds = seconds*60 + n
+		}
+		if len(parts) == 2 {
+			seconds *= 60
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	number, err := parseImportNumber(value)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "seconds":
+		return time.Duration(number * float64(time.Second)), nil
+	case "hours":
+		return time.Duration(number * float64(time.Hour)), nil
+	default:
+		return time.Duration(number * float64(time.Minute)), nil
+	}
+}
+
+// parseImportNumber accepts both a dot and a comma as the decimal separator.
+func parseImportNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("not a finite number")
+	}
+
+	return number, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// taskTitleKey normalizes a task title for matching imported rows to tasks.
+func taskTitleKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// importClientID identifies an imported session within its task, so the same
+// row imported twice is saved once.
+func importClientID(row *importRow) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d", row.StartTime.Unix(), row.EndTime.Unix())))
+	return "import:" + hex.EncodeToString(sum[:16])
+}
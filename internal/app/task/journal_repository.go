@@ -0,0 +1,296 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JournalRepository interface {
+	Get(ctx context.Context, userID uuid.UUID, filter *JournalFilter) ([]JournalEntry, error)
+	GetByID(ctx context.Context, id, userID uuid.UUID) (*JournalEntry, error)
+	Create(ctx context.Context, model *JournalEntry) (*JournalEntry, error)
+	Update(ctx context.Context, model *JournalEntry) (*JournalEntry, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	CountOwnTasks(ctx context.Context, ids []uuid.UUID, userID uuid.UUID) (int, error)
+	CountOwnSessions(ctx context.Context, ids []uuid.UUID, userID uuid.UUID) (int, error)
+}
+
+type journalRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewJournalRepository(pool *pgxpool.Pool) JournalRepository {
+	return &journalRepository{pool}
+}
+
+const journalEntryColumns = `
+	e.id, e.user_id, e.entry_date, e.title, e.body, COALESCE(e.mood, 0), COALESCE(e.energy, 0),
+	ARRAY(SELECT t.task_id FROM journal_entry_tasks t WHERE t.entry_id = e.id ORDER BY t.task_id),
+	ARRAY(SELECT s.session_id FROM journal_entry_sessions s WHERE s.entry_id = e.id ORDER BY s.session_id),
+	e.version, e.created_at, e.updated_at
+`
+
+// Get returns the entries of the user matching the filter, the latest first.
+// The query is matched as a web search against the title and the body.
+func (r *journalRepository) Get(ctx context.Context, userID uuid.UUID, filter *JournalFilter) ([]JournalEntry, error) {
+	query := `
+		SELECT ` + journalEntryColumns + `
+		FROM journal_entries e
+		WHERE e.user_id = $1
+			AND ($2::DATE IS NULL OR e.entry_date >= $2)
+			AND ($3::DATE IS NULL OR e.entry_date <= $3)
+			AND ($4::TEXT = '' OR to_tsvector('russian', e.title || ' ' || e.body) @@ websearch_to_tsquery('russian', $4))
+			AND ($5::UUID IS NULL OR EXISTS (
+				SELECT 1 FROM journal_entry_tasks t WHERE t.entry_id = e.id AND t.task_id = $5
+			))
+		ORDER BY e.entry_date DESC, e.created_at DESC
+		LIMIT $6
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, filter.From, filter.To, filter.Query, filter.TaskID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]JournalEntry, 0)
+	for rows.Next() {
+		entry, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetByID returns the entry of the user, or nil when there is none.
+func (r *journalRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*JournalEntry, error) {
+	query := `
+		SELECT ` + journalEntryColumns + `
+		FROM journal_entries e
+		WHERE e.id = $1 AND e.user_id = $2
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, id, userID))
+}
+
+// Create stores the entry together with its links in a single transaction.
+func (r *journalRepository) Create(ctx context.Context, model *JournalEntry) (*JournalEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO journal_entries(user_id, entry_date, title, body, mood, energy)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0))
+		RETURNING id, version, created_at, updated_at
+	`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		model.UserID,
+		model.Date,
+		model.Title,
+		model.Body,
+		model.Mood,
+		model.Energy,
+	).Scan(
+		&model.ID,
+		&model.Version,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	if err := r.insertLinks(ctx, tx, model); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return model, nil
+}
+
+// Update saves the entry and replaces its links. It returns
+// errors.ErrVersionConflict when the entry was changed since model.Version.
+func (r *journalRepository) Update(ctx context.Context, model *JournalEntry) (*JournalEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE journal_entries
+		SET entry_date = $2,
+			title = $3,
+			body = $4,
+			mood = NULLIF($5, 0),
+			energy = NULLIF($6, 0),
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $7
+		RETURNING version, updated_at
+	`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		model.ID,
+		model.Date,
+		model.Title,
+		model.Body,
+		model.Mood,
+		model.Energy,
+		model.Version,
+	).Scan(
+		&model.Version,
+		&model.UpdatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.ErrVersionConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update journal entry: %w", err)
+	}
+
+	query = `
+		DELETE FROM journal_entry_tasks WHERE entry_id = $1
+	`
+	if _, err := tx.Exec(ctx, query, model.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete journal entry tasks: %w", err)
+	}
+
+	query = `
+		DELETE FROM journal_entry_sessions WHERE entry_id = $1
+	`
+	if _, err := tx.Exec(ctx, query, model.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete journal entry sessions: %w", err)
+	}
+
+	if err := r.insertLinks(ctx, tx, model); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *journalRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM journal_entries
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// CountOwnTasks returns how many of the distinct ids are tasks of the user.
+func (r *journalRepository) CountOwnTasks(ctx context.Context, ids []uuid.UUID, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM tasks
+		WHERE id = ANY($1) AND user_id = $2
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, ids, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountOwnSessions returns how many of the distinct ids are sessions of tasks
+// of the user.
+func (r *journalRepository) CountOwnSessions(ctx context.Context, ids []uuid.UUID, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM sessions s
+		JOIN tasks t ON t.id = s.task_id
+		WHERE s.id = ANY($1) AND t.user_id = $2
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, ids, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *journalRepository) insertLinks(ctx context.Context, tx pgx.Tx, model *JournalEntry) error {
+	query := `
+		INSERT INTO journal_entry_tasks(entry_id, task_id)
+		SELECT $1, unnest($2::UUID[])
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, query, model.ID, model.TaskIDs); err != nil {
+		return fmt.Errorf("failed to create journal entry tasks: %w", err)
+	}
+
+	query = `
+		INSERT INTO journal_entry_sessions(entry_id, session_id)
+		SELECT $1, unnest($2::UUID[])
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, query, model.ID, model.SessionIDs); err != nil {
+		return fmt.Errorf("failed to create journal entry sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (r *journalRepository) scan(row pgx.Row) (*JournalEntry, error) {
+	var model JournalEntry
+	err := row.Scan(
+		&model.ID,
+		&model.UserID,
+		&model.Date,
+		&model.Title,
+		&model.Body,
+		&model.Mood,
+		&model.Energy,
+		&model.TaskIDs,
+		&model.SessionIDs,
+		&model.Version,
+		&model.CreatedAt,
+		&model.UpdatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+	}
+
+	return &model, nil
+}
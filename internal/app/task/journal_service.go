@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/markdown"
+	"github.com/google/uuid"
+)
+
+// journalListLimit caps a listing; narrower date ranges page through older
+// entries.
+const journalListLimit = 500
+
+func (s *service) GetJournalEntries(ctx context.Context, req *GetJournalEntriesRequest, userID uuid.UUID) ([]GetJournalEntryResponse, error) {
+	filter := JournalFilter{Query: req.Query, Limit: journalListLimit}
+	if req.From != "" {
+		from, _ := time.Parse(statsDateLayout, req.From)
+		filter.From = &from
+	}
+	if req.To != "" {
+		to, _ := time.Parse(statsDateLayout, req.To)
+		filter.To = &to
+	}
+	if req.TaskID != "" {
+		taskID, _ := uuid.Parse(req.TaskID)
+		filter.TaskID = &taskID
+	}
+
+	entries, err := s.journalRepo.Get(ctx, userID, &filter)
+	if err != nil {
+		s.log.Error("failed to get journal entries from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	result := make([]GetJournalEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, renderJournalEntry(&entry, req.Format))
+	}
+
+	return result, nil
+}
+
+func (s *service) GetJournalEntryByID(ctx context.Context, req *RenderJournalEntryRequest, id, userID uuid.UUID) (*GetJournalEntryResponse, error) {
+	entry, err := s.getOwnJournalEntry(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := renderJournalEntry(entry, req.Format)
+
+	return &result, nil
+}
+
+func (s *service) CreateJournalEntry(ctx context.Context, req *SaveJournalEntryRequest, userID uuid.UUID) (*GetJournalEntryResponse, error) {
+	model, err := s.journalEntryFromRequest(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.journalRepo.Create(ctx, model)
+	if err != nil {
+		s.log.Error("failed to create journal entry in repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	result := JournalEntryToGetResponse(entry)
+
+	return &result, nil
+}
+
+func (s *service) UpdateJournalEntry(ctx context.Context, req *SaveJournalEntryRequest, id, userID uuid.UUID) (*GetJournalEntryResponse, error) {
+	if _, err := s.getOwnJournalEntry(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	model, err := s.journalEntryFromRequest(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+	model.ID = id
+
+	if _, err := s.journalRepo.Update(ctx, model); err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("journal entry version conflict", "id", id, "version", req.Version)
+			return nil, s.journalEntryConflict(ctx, id, userID)
+		}
+		s.log.Error("failed to update journal entry in repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return s.GetJournalEntryByID(ctx, &RenderJournalEntryRequest{}, id, userID)
+}
+
+func (s *service) RemoveJournalEntry(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwnJournalEntry(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.journalRepo.Delete(ctx, id); err != nil {
+		s.log.Error("failed to delete journal entry from repository", "id", id, "error", err)
+		return fmt.Errorf(errors.ErrFailedToDeleteData)
+	}
+
+	return nil
+}
+
+func (s *service) getOwnJournalEntry(ctx context.Context, id, userID uuid.UUID) (*JournalEntry, error) {
+	entry, err := s.journalRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		s.log.Error("failed to get journal entry from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if entry == nil {
+		return nil, fmt.Errorf(errors.ErrJournalEntryNotFound)
+	}
+
+	return entry, nil
+}
+
+// journalEntryFromRequest maps the request and checks that the linked tasks
+// and sessions belong to the user.
+func (s *service) journalEntryFromRequest(ctx context.Context, req *SaveJournalEntryRequest, userID uuid.UUID) (*JournalEntry, error) {
+	date, err := time.Parse(statsDateLayout, req.Date)
+	if err != nil {
+		return nil, fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	model := SaveRequestToJournalEntry(req, date, userID)
+
+	if len(model.TaskIDs) > 0 {
+		count, err := s.journalRepo.CountOwnTasks(ctx, model.TaskIDs, userID)
+		if err != nil {
+			s.log.Error("failed to check journal entry tasks", "userID", userID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+		if count != len(model.TaskIDs) {
+			return nil, fmt.Errorf(errors.ErrInvalidData)
+		}
+	}
+
+	if len(model.SessionIDs) > 0 {
+		count, err := s.journalRepo.CountOwnSessions(ctx, model.SessionIDs, userID)
+		if err != nil {
+			s.log.Error("failed to check journal entry sessions", "userID", userID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+		if count != len(model.SessionIDs) {
+			return nil, fmt.Errorf(errors.ErrInvalidData)
+		}
+	}
+
+	return &model, nil
+}
+
+func (s *service) journalEntryConflict(ctx context.Context, id, userID uuid.UUID) error {
+	current, err := s.GetJournalEntryByID(ctx, &RenderJournalEntryRequest{}, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return &errors.ConflictError{Current: current}
+}
+
+// renderJournalEntry adds the sanitized HTML of the body when asked for.
+func renderJournalEntry(entry *JournalEntry, format string) GetJournalEntryResponse {
+	result := JournalEntryToGetResponse(entry)
+	if format == "html" {
+		result.HTML = markdown.RenderHTML(entry.Body)
+	}
+
+	return result
+}
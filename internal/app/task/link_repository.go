@@ -3,15 +3,20 @@ package task
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LinkRepository interface {
 	GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Link, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Link, error)
 	Create(ctx context.Context, model *Link) (*Link, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	Update(ctx context.Context, model *Link) (*Link, error)
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+	GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Link, error)
 }
 
 type linkRepository struct {
@@ -24,7 +29,7 @@ func NewLinkRepository(pool *pgxpool.Pool) LinkRepository {
 
 func (r *linkRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Link, error) {
 	query := `
-		SELECT id, task_id, url, title, type, created_at
+		SELECT id, task_id, title, type, version, created_at
 		FROM links
 		WHERE task_id = $1
 	`
@@ -43,6 +48,7 @@ func (r *linkRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]L
 			&link.TaskID,
 			&link.Title,
 			&link.Type,
+			&link.Version,
 			&link.CreatedAt,
 		)
 		if err != nil {
@@ -55,11 +61,38 @@ func (r *linkRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]L
 	return links, nil
 }
 
+func (r *linkRepository) GetByID(ctx context.Context, id uuid.UUID) (*Link, error) {
+	query := `
+		SELECT id, task_id, title, type, version, created_at
+		FROM links
+		WHERE id = $1
+	`
+
+	var link Link
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&link.ID,
+		&link.TaskID,
+		&link.Title,
+		&link.Type,
+		&link.Version,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan link: %w", err)
+	}
+
+	return &link, nil
+}
+
 func (r *linkRepository) Create(ctx context.Context, model *Link) (*Link, error) {
 	query := `
-		INSERT INTO links(task_id, url, title, type)
-		VALUES($1, $2, $3, $4)
-		RETURNING id
+		INSERT INTO links(task_id, title, type)
+		VALUES($1, $2, $3)
+		RETURNING id, version
 	`
 
 	var id uuid.UUID
@@ -71,6 +104,7 @@ func (r *linkRepository) Create(ctx context.Context, model *Link) (*Link, error)
 		model.Type,
 	).Scan(
 		&id,
+		&model.Version,
 	)
 
 	if err != nil {
@@ -81,16 +115,96 @@ func (r *linkRepository) Create(ctx context.Context, model *Link) (*Link, error)
 	return model, nil
 }
 
-func (r *linkRepository) Delete(ctx context.Context, id uuid.UUID) error {
+func (r *linkRepository) Update(ctx context.Context, model *Link) (*Link, error) {
 	query := `
-		DELETE FROM links
-		WHERE id = $1
+		UPDATE links
+		SET title = $2,
+			type = $3,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $4
+	`
+
+	tag, err := r.pool.Exec(
+		ctx,
+		query,
+		model.ID,
+		model.Title,
+		model.Type,
+		model.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update link: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, errors.ErrVersionConflict
+	}
+
+	model.Version++
+
+	return model, nil
+}
+
+func (r *linkRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM links
+			WHERE id = $1 AND version = $2
+			RETURNING id, task_id
+		)
+		INSERT INTO tombstones(entity_type, entity_id, task_id)
+		SELECT 'link', id, task_id
+		FROM deleted
 	`
 
-	_, err := r.pool.Exec(ctx, query, id)
+	tag, err := r.pool.Exec(ctx, query, id, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete link: %w", err)
 	}
 
+	if tag.RowsAffected() == 0 {
+		return errors.ErrVersionConflict
+	}
+
 	return nil
 }
+
+// GetChangedSince returns the links of the user's tasks created or updated
+// after since.
+func (r *linkRepository) GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Link, error) {
+	query := `
+		SELECT l.id, l.task_id, l.title, l.type, l.version, l.created_at, l.updated_at
+		FROM links l
+		JOIN tasks t ON t.id = l.task_id
+		WHERE t.user_id = $1 AND l.updated_at > $2
+		ORDER BY l.updated_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]Link, 0)
+	for rows.Next() {
+		var link Link
+		err := rows.Scan(
+			&link.ID,
+			&link.TaskID,
+			&link.Title,
+			&link.Type,
+			&link.Version,
+			&link.CreatedAt,
+			&link.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan link: %w", err)
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
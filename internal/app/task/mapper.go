@@ -1,6 +1,14 @@
 package task
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/chordpro"
+	"github.com/RuLap/trackmus-api/internal/pkg/ical"
+	"github.com/google/uuid"
+)
 
 // Task --------------------------------------------------------------------------------------
 
@@ -10,51 +18,244 @@ func TaskToGetShortResponse(model *Task, progress float64) GetTaskShortResponse
 		Title:     model.Title,
 		TargetBPM: model.TargetBPM,
 		Progress:  progress,
+		Tags:      tagsOrEmpty(model.Tags),
+		Version:   model.Version,
+	}
+}
+
+func TaskTrendToGetResponse(model *TaskTrend) *GetTaskTrendResponse {
+	return &GetTaskTrendResponse{
+		Status:             string(model.Status),
+		Reason:             model.Reason,
+		BestBPM:            model.BestBPM,
+		PreviousBestBPM:    model.PreviousBestBPM,
+		Confidence:         model.Confidence,
+		PreviousConfidence: model.PreviousConfidence,
+		DetectedAt:         model.DetectedAt,
 	}
 }
 
-func TaskToGetResponse(model *Task, sessions []GetSessionResponse, media []GetMediaResponse, links []GetLinkResponse) GetTaskResponse {
+func TaskToGetResponse(
+	model *Task,
+	sections []GetSectionResponse,
+	tempoMarks []GetTempoMarkResponse,
+	sessions []GetSessionResponse,
+	media []GetMediaResponse,
+	links []GetLinkResponse,
+) GetTaskResponse {
 	return GetTaskResponse{
-		ID:        model.ID.String(),
-		Title:     model.Title,
-		TargetBPM: model.TargetBPM,
-		Sessions:  sessions,
-		Media:     media,
-		Links:     links,
+		ID:            model.ID.String(),
+		Title:         model.Title,
+		TargetBPM:     model.TargetBPM,
+		Composer:      model.Composer,
+		TimeSignature: model.TimeSignature,
+		Duration:      model.Duration,
+		CleanRepsGoal: model.CleanRepsGoal,
+		Tags:          tagsOrEmpty(model.Tags),
+		Sections:      sections,
+		TempoMarks:    tempoMarks,
+		Sessions:      sessions,
+		Media:         media,
+		Links:         links,
+		DueDate:       formatDate(model.DueDate),
+		Version:       model.Version,
 	}
 }
 
 func SaveRequestToTask(req *SaveTaskRequest, id uuid.UUID) Task {
-	return Task{
+	task := Task{
 		ID:        id,
 		Title:     req.Title,
 		TargetBPM: req.TargetBPM,
+		Tags:      NormalizeTags(req.Tags),
+		Version:   req.Version,
+	}
+	if req.CleanRepsGoal != nil {
+		task.CleanRepsGoal = *req.CleanRepsGoal
+	}
+	if req.DueDate != nil {
+		if dueDate, err := time.Parse(statsDateLayout, *req.DueDate); err == nil {
+			task.DueDate = &dueDate
+		}
+	}
+
+	return task
+}
+
+func formatDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+
+	value := date.Format(statsDateLayout)
+
+	return &value
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return make([]string, 0)
+	}
+
+	return tags
+}
+
+// Score -------------------------------------------------------------------------------------
+
+func SectionToGetResponse(model *Section) GetSectionResponse {
+	return GetSectionResponse{
+		ID:       model.ID.String(),
+		Name:     model.Name,
+		StartBar: model.StartBar,
+		EndBar:   model.EndBar,
+	}
+}
+
+func TempoMarkToGetResponse(model *TempoMark) GetTempoMarkResponse {
+	return GetTempoMarkResponse{
+		Bar:  model.Bar,
+		BPM:  model.BPM,
+		Text: model.Text,
 	}
 }
 
 // Session -----------------------------------------------------------------------------------
 
 func SessionToGetResponse(model *Session) GetSessionResponse {
+	pauses := make([]GetSessionPauseResponse, 0, len(model.Pauses))
+	for _, p := range model.Pauses {
+		pauses = append(pauses, GetSessionPauseResponse{
+			StartedAt: p.StartedAt,
+			EndedAt:   p.EndedAt,
+		})
+	}
+
+	segments := make([]GetSegmentResponse, 0, len(model.Segments))
+	for _, segment := range model.Segments {
+		segments = append(segments, SegmentToGetResponse(&segment))
+	}
+
+	reps := make([]GetRepResponse, 0, len(model.Reps))
+	for _, rep := range model.Reps {
+		reps = append(reps, GetRepResponse{
+			Clean:    rep.Clean,
+			PlayedAt: rep.PlayedAt,
+		})
+	}
+
+	var endTime *time.Time
+	if !model.IsRunning() {
+		endTime = &model.EndTime
+	}
+
 	return GetSessionResponse{
 		ID:         model.ID.String(),
 		BPM:        model.BPM,
 		Note:       model.Note,
 		Confidence: model.Confidence,
+		Pain:       model.Pain,
+		Tension:    model.Tension,
+		Status:     string(model.Status),
 		StartTime:  model.StartTime,
-		EndTime:    model.EndTime,
+		EndTime:    endTime,
 		Duration:   model.GetDurationSeconds(),
+		Pauses:     pauses,
+		Segments:   segments,
+		Reps:       reps,
+		RepStats:   RepStatsToGetResponse(model.GetRepStats()),
+		Version:    model.Version,
 	}
 }
 
 func SaveRequestToSession(req *SaveSessionRequest, taskID uuid.UUID) Session {
-	return Session{
+	session := Session{
 		TaskID:     taskID,
 		BPM:        req.BPM,
 		Note:       req.Note,
 		Confidence: req.Confidence,
+		Pain:       req.Pain,
+		Tension:    req.Tension,
 		StartTime:  req.StartTime,
 		EndTime:    req.EndTime,
+		Status:     SessionStatusFinished,
+		Segments:   SaveRequestsToSegments(req.Segments),
+		Reps:       SaveRequestsToReps(req.Reps),
+		Version:    req.Version,
 	}
+	session.ApplySegments()
+
+	return session
+}
+
+func FinishRequestToSession(req *FinishSessionRequest, id uuid.UUID) Session {
+	session := Session{
+		ID:         id,
+		BPM:        req.BPM,
+		Note:       req.Note,
+		Confidence: req.Confidence,
+		Pain:       req.Pain,
+		Tension:    req.Tension,
+		Segments:   SaveRequestsToSegments(req.Segments),
+	}
+	session.ApplySegments()
+
+	return session
+}
+
+func RepStatsToGetResponse(stats RepStats) GetRepStatsResponse {
+	return GetRepStatsResponse{
+		Total:              stats.Total,
+		Clean:              stats.Clean,
+		Flawed:             stats.Flawed,
+		LongestCleanStreak: stats.LongestCleanStreak,
+		CurrentCleanStreak: stats.CurrentCleanStreak,
+		SuccessRatio:       stats.SuccessRatio,
+	}
+}
+
+func SaveRequestsToReps(reqs []SaveRepRequest) []SessionRep {
+	reps := make([]SessionRep, 0, len(reqs))
+	for i, req := range reqs {
+		reps = append(reps, SessionRep{
+			Position: i,
+			Clean:    req.Clean,
+			PlayedAt: req.PlayedAt,
+		})
+	}
+
+	return reps
+}
+
+func SegmentToGetResponse(model *SessionSegment) GetSegmentResponse {
+	var sectionID *string
+	if model.SectionID != nil {
+		id := model.SectionID.String()
+		sectionID = &id
+	}
+
+	return GetSegmentResponse{
+		BPM:        model.BPM,
+		Duration:   model.Duration,
+		Reps:       model.Reps,
+		Confidence: model.Confidence,
+		SectionID:  sectionID,
+	}
+}
+
+func SaveRequestsToSegments(reqs []SaveSegmentRequest) []SessionSegment {
+	segments := make([]SessionSegment, 0, len(reqs))
+	for i, req := range reqs {
+		segments = append(segments, SessionSegment{
+			SectionID:  req.SectionID,
+			Position:   i,
+			BPM:        req.BPM,
+			Duration:   req.Duration,
+			Reps:       req.Reps,
+			Confidence: req.Confidence,
+		})
+	}
+
+	return segments
 }
 
 // Media -------------------------------------------------------------------------------------
@@ -74,6 +275,7 @@ func MediaToGetResponse(model *Media, url string) GetMediaResponse {
 func ConfirmUploadRequestToMedia(req *ConfirmMediaUploadRequest, id uuid.UUID) Media {
 	return Media{
 		ID:       id,
+		TaskID:   req.TaskID,
 		Type:     req.Type,
 		Filename: req.Filename,
 		Size:     req.Size,
@@ -88,14 +290,462 @@ func LinkToGetResponse(model *Link) GetLinkResponse {
 		ID:        model.ID.String(),
 		Title:     model.Title,
 		Type:      string(model.Type),
+		Version:   model.Version,
 		CreatedAt: model.CreatedAt,
 	}
 }
 
 func SaveRequestToLink(req *SaveLinkRequest, taskID uuid.UUID) Link {
 	return Link{
-		TaskID: taskID,
-		Title:  req.Title,
-		Type:   req.Type,
+		TaskID:  taskID,
+		Title:   req.Title,
+		Type:    req.Type,
+		Version: req.Version,
+	}
+}
+
+// Share -------------------------------------------------------------------------------------
+
+func TaskShareToGetResponse(model *TaskShare) GetTaskShareResponse {
+	return GetTaskShareResponse{
+		TaskID:      model.TaskID.String(),
+		Token:       model.Token,
+		ProgressURL: fmt.Sprintf("%s/%s/progress.svg", shareBaseURL, model.Token),
+		BPMChartURL: fmt.Sprintf("%s/%s/bpm.svg", shareBaseURL, model.Token),
+		CreatedAt:   model.CreatedAt,
+	}
+}
+
+// Export ------------------------------------------------------------------------------------
+
+func TaskToExportResponse(model *Task) ExportTaskResponse {
+	return ExportTaskResponse{
+		ID:            model.ID.String(),
+		Title:         model.Title,
+		Composer:      model.Composer,
+		TimeSignature: model.TimeSignature,
+		TargetBPM:     model.TargetBPM,
+		Duration:      model.Duration,
+		CleanRepsGoal: model.CleanRepsGoal,
+		Tags:          tagsOrEmpty(model.Tags),
+		IsCompleted:   model.IsCompleted,
+		CompletedAt:   model.CompletedAt,
+		CreatedAt:     model.CreatedAt,
+		UpdatedAt:     model.UpdatedAt,
+	}
+}
+
+func SessionToExportResponse(model *ExportSession) ExportSessionResponse {
+	return ExportSessionResponse{
+		ID:              model.ID.String(),
+		TaskID:          model.TaskID.String(),
+		TaskTitle:       model.TaskTitle,
+		Status:          string(model.Status),
+		StartTime:       model.StartTime,
+		EndTime:         model.EndTime,
+		DurationSeconds: model.GetDurationSeconds(),
+		BPM:             model.BPM,
+		Confidence:      model.Confidence,
+		Note:            model.Note,
+	}
+}
+
+func LinkToExportResponse(model *Link) ExportLinkResponse {
+	return ExportLinkResponse{
+		ID:        model.ID.String(),
+		TaskID:    model.TaskID.String(),
+		Title:     model.Title,
+		Type:      string(model.Type),
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func MediaToExportResponse(model *Media) ExportMediaResponse {
+	return ExportMediaResponse{
+		ID:        model.ID.String(),
+		TaskID:    model.TaskID.String(),
+		Type:      string(model.Type),
+		Filename:  model.Filename,
+		Size:      model.Size,
+		Duration:  model.Duration,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func JournalEntryToExportResponse(model *JournalEntry) ExportJournalEntryResponse {
+	return ExportJournalEntryResponse{
+		ID:         model.ID.String(),
+		Date:       model.Date.Format(statsDateLayout),
+		Title:      model.Title,
+		Body:       model.Body,
+		Mood:       model.Mood,
+		Energy:     model.Energy,
+		TaskIDs:    uuidsToStrings(model.TaskIDs),
+		SessionIDs: uuidsToStrings(model.SessionIDs),
+		CreatedAt:  model.CreatedAt,
+		UpdatedAt:  model.UpdatedAt,
+	}
+}
+
+// SessionToCalendarEvent describes the session as a calendar event titled by
+// its task, with the tempo, confidence and note in the description.
+func SessionToCalendarEvent(model *ExportSession, stamp time.Time) ical.Event {
+	details := make([]string, 0, 4)
+	if model.BPM > 0 {
+		details = append(details, fmt.Sprintf("Темп: %d BPM", model.BPM))
+	}
+	if model.Confidence > 0 {
+		details = append(details, fmt.Sprintf("Уверенность: %d/5", model.Confidence))
+	}
+	details = append(details, fmt.Sprintf("Длительность: %d мин", model.GetDurationSeconds()/60))
+	if model.Note != "" {
+		details = append(details, model.Note)
+	}
+
+	return ical.Event{
+		UID:         model.ID.String() + "@trackmus.ru",
+		Start:       model.StartTime,
+		End:         model.EndTime,
+		Summary:     "Практика: " + model.TaskTitle,
+		Description: strings.Join(details, "\n"),
+		Stamp:       stamp,
+	}
+}
+
+func CalendarFeedToGetResponse(model *CalendarFeed) GetCalendarFeedResponse {
+	return GetCalendarFeedResponse{
+		URL:       fmt.Sprintf("%s/%s.ics", calendarBaseURL, model.Token),
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+// Import ------------------------------------------------------------------------------------
+
+func ImportMappingRequestToModel(req *ImportMappingRequest) ImportMapping {
+	return ImportMapping{
+		Task:         strings.TrimSpace(req.Task),
+		Date:         strings.TrimSpace(req.Date),
+		Time:         strings.TrimSpace(req.Time),
+		End:          strings.TrimSpace(req.End),
+		Duration:     strings.TrimSpace(req.Duration),
+		BPM:          strings.TrimSpace(req.BPM),
+		Confidence:   strings.TrimSpace(req.Confidence),
+		Note:         strings.TrimSpace(req.Note),
+		DateFormat:   strings.TrimSpace(req.DateFormat),
+		DurationUnit: req.DurationUnit,
+		Delimiter:    req.Delimiter,
+		TimeZone:     req.TimeZone,
+	}
+}
+
+func ImportRowToGetResponse(row *importRow) GetImportRowResponse {
+	response := GetImportRowResponse{
+		Row:        row.Row,
+		Task:       row.Task,
+		BPM:        row.BPM,
+		Confidence: row.Confidence,
+		Note:       row.Note,
+	}
+	if !row.StartTime.IsZero() {
+		response.StartTime = &row.StartTime
+	}
+	if !row.EndTime.IsZero() {
+		response.EndTime = &row.EndTime
+		response.DurationSeconds = int(row.EndTime.Sub(row.StartTime).Seconds())
+	}
+	if row.Err != nil {
+		response.Error = row.Err.Error()
+	}
+
+	return response
+}
+
+func ImportToGetResponse(model *Import) GetImportResponse {
+	rowErrors := make([]GetImportErrorResponse, 0, len(model.Errors))
+	for _, rowError := range model.Errors {
+		rowErrors = append(rowErrors, GetImportErrorResponse{
+			Row:     rowError.Row,
+			Message: rowError.Message,
+		})
+	}
+
+	return GetImportResponse{
+		ID:               model.ID.String(),
+		Status:           string(model.Status),
+		Filename:         model.Filename,
+		TotalRows:        model.TotalRows,
+		ProcessedRows:    model.ProcessedRows,
+		ImportedSessions: model.ImportedSessions,
+		SkippedSessions:  model.SkippedSessions,
+		FailedRows:       model.FailedRows,
+		CreatedTasks:     model.CreatedTasks,
+		Errors:           rowErrors,
+		Message:          model.Message,
+		CreatedAt:        model.CreatedAt,
+		FinishedAt:       model.FinishedAt,
+	}
+}
+
+// Goals -------------------------------------------------------------------------------------
+
+func GoalToGetSettingsResponse(model *Goal) GetGoalSettingsResponse {
+	return GetGoalSettingsResponse{
+		MinutesPerDay:   model.MinutesPerDay,
+		DaysPerWeek:     model.DaysPerWeek,
+		SessionsPerWeek: model.SessionsPerWeek,
+		GraceDay:        model.GraceDay,
+	}
+}
+
+func SaveRequestToGoal(req *SaveGoalsRequest, userID uuid.UUID) Goal {
+	return Goal{
+		UserID:          userID,
+		MinutesPerDay:   req.MinutesPerDay,
+		DaysPerWeek:     req.DaysPerWeek,
+		SessionsPerWeek: req.SessionsPerWeek,
+		GraceDay:        req.GraceDay,
+		Version:         req.Version,
+	}
+}
+
+// Sync --------------------------------------------------------------------------------------
+
+func TaskToGetSyncResponse(model *Task, progress float64) GetSyncTaskResponse {
+	return GetSyncTaskResponse{
+		GetTaskShortResponse: TaskToGetShortResponse(model, progress),
+		Composer:             model.Composer,
+		TimeSignature:        model.TimeSignature,
+		Duration:             model.Duration,
+		CleanRepsGoal:        model.CleanRepsGoal,
+		DueDate:              formatDate(model.DueDate),
+		IsCompleted:          model.IsCompleted,
+		CreatedAt:            model.CreatedAt,
+		UpdatedAt:            model.UpdatedAt,
+	}
+}
+
+func SessionToGetSyncResponse(model *Session) GetSyncSessionResponse {
+	return GetSyncSessionResponse{
+		GetSessionResponse: SessionToGetResponse(model),
+		TaskID:             model.TaskID.String(),
+		UpdatedAt:          model.UpdatedAt,
+	}
+}
+
+func MediaToGetSyncResponse(model *Media, url string) GetSyncMediaResponse {
+	return GetSyncMediaResponse{
+		GetMediaResponse: MediaToGetResponse(model, url),
+		TaskID:           model.TaskID.String(),
+		UpdatedAt:        model.UpdatedAt,
+	}
+}
+
+func LinkToGetSyncResponse(model *Link) GetSyncLinkResponse {
+	return GetSyncLinkResponse{
+		GetLinkResponse: LinkToGetResponse(model),
+		TaskID:          model.TaskID.String(),
+		UpdatedAt:       model.UpdatedAt,
+	}
+}
+
+func TombstoneToGetResponse(model *Tombstone) GetTombstoneResponse {
+	return GetTombstoneResponse{
+		Type:      string(model.EntityType),
+		ID:        model.EntityID.String(),
+		TaskID:    model.TaskID.String(),
+		DeletedAt: model.DeletedAt,
+	}
+}
+
+// Chart -------------------------------------------------------------------------------------
+
+func ChartToGetShortResponse(model *Chart) GetChartShortResponse {
+	return GetChartShortResponse{
+		ID:        model.ID.String(),
+		Title:     model.Title,
+		Key:       model.Key,
+		Version:   model.Version,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+// ChartToGetResponse maps a chart whose song has already been transposed to
+// the chord shapes the musician plays; key is the sounding key.
+func ChartToGetResponse(model *Chart, song *chordpro.Song, key string, transpose, capo int) GetChartResponse {
+	sections := make([]GetChartSectionResponse, 0, len(song.Sections))
+	for _, section := range song.Sections {
+		lines := make([]GetChartLineResponse, 0, len(section.Lines))
+		for _, line := range section.Lines {
+			dto := GetChartLineResponse{
+				Type: line.Type,
+				Text: line.Text,
+			}
+			for _, segment := range line.Segments {
+				dto.Segments = append(dto.Segments, GetChartSegmentResponse{
+					Chord:  segment.Chord,
+					Lyrics: segment.Lyrics,
+				})
+			}
+			lines = append(lines, dto)
+		}
+
+		sections = append(sections, GetChartSectionResponse{
+			Type:  section.Type,
+			Label: section.Label,
+			Lines: lines,
+		})
 	}
+
+	return GetChartResponse{
+		ID:        model.ID.String(),
+		TaskID:    model.TaskID.String(),
+		Title:     model.Title,
+		Subtitle:  song.Subtitle,
+		Artist:    song.Artist,
+		Key:       key,
+		ChordKey:  song.Key,
+		Transpose: transpose,
+		Capo:      capo,
+		Tempo:     song.Tempo,
+		Time:      song.Time,
+		Sections:  sections,
+		Content:   model.Content,
+		Version:   model.Version,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+func SaveRequestToChart(req *SaveChartRequest, taskID uuid.UUID) Chart {
+	return Chart{
+		TaskID:  taskID,
+		Title:   req.Title,
+		Content: req.Content,
+		Version: req.Version,
+	}
+}
+
+// Ensemble ----------------------------------------------------------------------------------
+
+func EnsembleToGetShortResponse(model *Ensemble, member *EnsembleMember, readiness float64) GetEnsembleShortResponse {
+	return GetEnsembleShortResponse{
+		ID:        model.ID.String(),
+		Title:     model.Title,
+		PartName:  member.PartName,
+		TaskID:    member.TaskID.String(),
+		Readiness: readiness,
+	}
+}
+
+func EnsembleToGetResponse(model *Ensemble, members []GetEnsembleMemberResponse, readiness, average float64) GetEnsembleResponse {
+	return GetEnsembleResponse{
+		ID:              model.ID.String(),
+		Title:           model.Title,
+		OwnerID:         model.OwnerID.String(),
+		Readiness:       readiness,
+		AverageProgress: average,
+		Members:         members,
+		CreatedAt:       model.CreatedAt,
+	}
+}
+
+func EnsembleMemberToGetResponse(model *EnsembleMember, task *Task, progress float64, sessions []GetSessionResponse) GetEnsembleMemberResponse {
+	return GetEnsembleMemberResponse{
+		UserID:    model.UserID.String(),
+		Username:  model.Username,
+		PartName:  model.PartName,
+		TaskID:    model.TaskID.String(),
+		TargetBPM: task.TargetBPM,
+		Progress:  progress,
+		Sessions:  sessions,
+	}
+}
+
+func SaveRequestToEnsemble(req *SaveEnsembleRequest, ownerID uuid.UUID) Ensemble {
+	return Ensemble{
+		OwnerID: ownerID,
+		Title:   req.Title,
+	}
+}
+
+func EnsembleInvitationToGetResponse(model *EnsembleInvitation) GetEnsembleInvitationResponse {
+	return GetEnsembleInvitationResponse{
+		ID:        model.ID.String(),
+		Email:     model.Email,
+		PartName:  model.PartName,
+		TargetBPM: model.TargetBPM,
+		Status:    string(model.Status),
+		CreatedAt: model.CreatedAt,
+		ExpiresAt: model.ExpiresAt(),
+	}
+}
+
+func InviteRequestToEnsembleInvitation(req *InviteEnsembleMemberRequest, ensembleID, invitedBy uuid.UUID, token string) EnsembleInvitation {
+	return EnsembleInvitation{
+		EnsembleID: ensembleID,
+		InvitedBy:  invitedBy,
+		Email:      req.Email,
+		PartName:   req.PartName,
+		TargetBPM:  req.TargetBPM,
+		Token:      token,
+		Status:     InvitationStatusPending,
+	}
+}
+
+// Journal -----------------------------------------------------------------------------------
+
+func JournalEntryToGetResponse(model *JournalEntry) GetJournalEntryResponse {
+	return GetJournalEntryResponse{
+		ID:         model.ID.String(),
+		Date:       model.Date.Format(statsDateLayout),
+		Title:      model.Title,
+		Body:       model.Body,
+		Mood:       model.Mood,
+		Energy:     model.Energy,
+		TaskIDs:    uuidsToStrings(model.TaskIDs),
+		SessionIDs: uuidsToStrings(model.SessionIDs),
+		Version:    model.Version,
+		CreatedAt:  model.CreatedAt,
+		UpdatedAt:  model.UpdatedAt,
+	}
+}
+
+// SaveRequestToJournalEntry maps a validated request; the links are
+// deduplicated.
+func SaveRequestToJournalEntry(req *SaveJournalEntryRequest, date time.Time, userID uuid.UUID) JournalEntry {
+	return JournalEntry{
+		UserID:     userID,
+		Date:       date,
+		Title:      strings.TrimSpace(req.Title),
+		Body:       req.Body,
+		Mood:       req.Mood,
+		Energy:     req.Energy,
+		TaskIDs:    parseUUIDs(req.TaskIDs),
+		SessionIDs: parseUUIDs(req.SessionIDs),
+		Version:    req.Version,
+	}
+}
+
+func uuidsToStrings(ids []uuid.UUID) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, id.String())
+	}
+
+	return result
+}
+
+func parseUUIDs(values []string) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(values))
+	result := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+
+	return result
 }
@@ -3,6 +3,7 @@ package task
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,6 +15,7 @@ type MediaRepository interface {
 	Create(ctx context.Context, model *Media) (*Media, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	DeleteByIDs(ctx context.Context, ids []uuid.UUID) error
+	GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Media, error)
 }
 
 type mediaRepository struct {
@@ -26,8 +28,8 @@ func NewMediaRepository(pool *pgxpool.Pool) MediaRepository {
 
 func (r *mediaRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Media, error) {
 	query := `
-		SELECT id, type, filename, url, size, duration, created_at
-		FROM media
+		SELECT id, task_id, type, filename, size, duration, created_at
+		FROM medias
 		WHERE task_id = $1
 	`
 
@@ -42,6 +44,7 @@ func (r *mediaRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]
 		var media Media
 		err := rows.Scan(
 			&media.ID,
+			&media.TaskID,
 			&media.Type,
 			&media.Filename,
 			&media.Size,
@@ -60,8 +63,8 @@ func (r *mediaRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]
 
 func (r *mediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*Media, error) {
 	query := `
-		SELECT id, type, filename, url, size, duration, created_at
-		FROM media
+		SELECT id, task_id, type, filename, size, duration, created_at
+		FROM medias
 		WHERE id = $1
 	`
 
@@ -72,6 +75,7 @@ func (r *mediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*Media, er
 		id,
 	).Scan(
 		&media.ID,
+		&media.TaskID,
 		&media.Type,
 		&media.Filename,
 		&media.Size,
@@ -87,21 +91,24 @@ func (r *mediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*Media, er
 
 func (r *mediaRepository) Create(ctx context.Context, model *Media) (*Media, error) {
 	query := `
-		INSERT INTO media(type, filename, url, size, duration)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		INSERT INTO medias(id, task_id, type, filename, size, duration)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
 	`
 
 	var id uuid.UUID
 	err := r.pool.QueryRow(
 		ctx,
 		query,
+		model.ID,
+		model.TaskID,
 		model.Type,
 		model.Filename,
 		model.Size,
 		model.Duration,
 	).Scan(
 		&id,
+		&model.CreatedAt,
 	)
 
 	if err != nil {
@@ -114,8 +121,14 @@ func (r *mediaRepository) Create(ctx context.Context, model *Media) (*Media, err
 
 func (r *mediaRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `
-		DELETE FROM media
-		WHERE id = $1
+		WITH deleted AS (
+			DELETE FROM medias
+			WHERE id = $1
+			RETURNING id, task_id
+		)
+		INSERT INTO tombstones(entity_type, entity_id, task_id)
+		SELECT 'media', id, task_id
+		FROM deleted
 	`
 
 	_, err := r.pool.Exec(ctx, query, id)
@@ -128,8 +141,14 @@ func (r *mediaRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 func (r *mediaRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) error {
 	query := `
-		DELETE FROM media
-		WHERE id = ANY($1::uuid[])
+		WITH deleted AS (
+			DELETE FROM medias
+			WHERE id = ANY($1::uuid[])
+			RETURNING id, task_id
+		)
+		INSERT INTO tombstones(entity_type, entity_id, task_id)
+		SELECT 'media', id, task_id
+		FROM deleted
 	`
 
 	_, err := r.pool.Exec(ctx, query, ids)
@@ -139,3 +158,43 @@ func (r *mediaRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) erro
 
 	return nil
 }
+
+// GetChangedSince returns the media of the user's tasks created or updated
+// after since.
+func (r *mediaRepository) GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Media, error) {
+	query := `
+		SELECT m.id, m.task_id, m.type, m.filename, m.size, m.duration, m.created_at, m.updated_at
+		FROM medias m
+		JOIN tasks t ON t.id = m.task_id
+		WHERE t.user_id = $1 AND m.updated_at > $2
+		ORDER BY m.updated_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	medias := make([]Media, 0)
+	for rows.Next() {
+		var media Media
+		err := rows.Scan(
+			&media.ID,
+			&media.TaskID,
+			&media.Type,
+			&media.Filename,
+			&media.Size,
+			&media.Duration,
+			&media.CreatedAt,
+			&media.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+
+		medias = append(medias, media)
+	}
+
+	return medias, nil
+}
@@ -3,6 +3,8 @@ package task
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +16,8 @@ const (
 	MediaTypeVideo MediaType = "video"
 	MediaTypeAudio MediaType = "audio"
 	MediaTypeImage MediaType = "image"
+	MediaTypeScore MediaType = "score"
+	MediaTypeMIDI  MediaType = "midi"
 )
 
 type LinkType string
@@ -24,23 +28,138 @@ const (
 	LinkTypeOther   LinkType = "other"
 )
 
+// SessionStatus is open or paused while the timer runs on the server.
+// Sessions logged after the fact are finished right away.
+type SessionStatus string
+
+const (
+	SessionStatusOpen      SessionStatus = "open"
+	SessionStatusPaused    SessionStatus = "paused"
+	SessionStatusFinished  SessionStatus = "finished"
+	SessionStatusAbandoned SessionStatus = "abandoned"
+)
+
+// EntityType names the synced resources in change sets and tombstones.
+type EntityType string
+
+const (
+	EntityTask    EntityType = "task"
+	EntitySession EntityType = "session"
+	EntityMedia   EntityType = "media"
+	EntityLink    EntityType = "link"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+)
+
 type Task struct {
-	ID          uuid.UUID `db:"id"`
-	UserID      uuid.UUID `db:"user_id"`
-	Title       string    `db:"title"`
-	TargetBPM   int       `db:"target_bpm"`
-	IsCompleted bool      `db:"is_completed"`
-	CreatedAt   time.Time `db:"created_at"`
+	ID              uuid.UUID  `db:"id"`
+	UserID          uuid.UUID  `db:"user_id"`
+	Title           string     `db:"title"`
+	TargetBPM       int        `db:"target_bpm"`
+	Composer        string     `db:"composer"`
+	TimeSignature   string     `db:"time_signature"`
+	Duration        int        `db:"duration"`
+	CleanRepsGoal   int        `db:"clean_reps_goal"`
+	Tags            []string   `db:"tags"`
+	DueDate         *time.Time `db:"due_date"`
+	IsCompleted     bool       `db:"is_completed"`
+	CompletedAt     *time.Time `db:"completed_at"`
+	CompletedByRule bool       `db:"completed_by_rule"`
+	Version         int        `db:"version"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
 }
 
 type Session struct {
-	ID         uuid.UUID `db:"id"`
-	TaskID     uuid.UUID `db:"task_id"`
-	BPM        int       `db:"bpm"`
-	Note       string    `db:"note"`
-	Confidence int       `db:"confidence"`
-	StartTime  time.Time `db:"start_time"`
-	EndTime    time.Time `db:"end_time"`
+	ID              uuid.UUID     `db:"id"`
+	TaskID          uuid.UUID     `db:"task_id"`
+	BPM             int           `db:"bpm"`
+	Note            string        `db:"note"`
+	Confidence      int           `db:"confidence"`
+	Pain            int           `db:"pain"`
+	Tension         int           `db:"tension"`
+	StartTime       time.Time     `db:"start_time"`
+	EndTime         time.Time     `db:"end_time"`
+	Status          SessionStatus `db:"status"`
+	LastHeartbeatAt time.Time     `db:"last_heartbeat_at"`
+	ClientID        string        `db:"client_id"`
+	Version         int           `db:"version"`
+	UpdatedAt       time.Time     `db:"updated_at"`
+	Pauses          []SessionPause
+	Segments        []SessionSegment
+	Reps            []SessionRep
+}
+
+type SessionRep struct {
+	ID        uuid.UUID  `db:"id"`
+	SessionID uuid.UUID  `db:"session_id"`
+	Position  int        `db:"position"`
+	Clean     bool       `db:"clean"`
+	PlayedAt  *time.Time `db:"played_at"`
+}
+
+type RepStats struct {
+	Total              int
+	Clean              int
+	Flawed             int
+	LongestCleanStreak int
+	CurrentCleanStreak int
+	SuccessRatio       float64
+}
+
+type BatchItemStatus string
+
+const (
+	BatchItemCreated   BatchItemStatus = "created"
+	BatchItemUpdated   BatchItemStatus = "updated"
+	BatchItemDeleted   BatchItemStatus = "deleted"
+	BatchItemDuplicate BatchItemStatus = "duplicate"
+	BatchItemConflict  BatchItemStatus = "conflict"
+	BatchItemInvalid   BatchItemStatus = "invalid"
+	BatchItemForbidden BatchItemStatus = "forbidden"
+	BatchItemFailed    BatchItemStatus = "failed"
+)
+
+type SessionPause struct {
+	ID        uuid.UUID  `db:"id"`
+	SessionID uuid.UUID  `db:"session_id"`
+	StartedAt time.Time  `db:"started_at"`
+	EndedAt   *time.Time `db:"ended_at"`
+}
+
+// SessionSegment is a part of a session played at one tempo. Duration is in
+// seconds; either it or Reps may be left empty.
+type SessionSegment struct {
+	ID         uuid.UUID  `db:"id"`
+	SessionID  uuid.UUID  `db:"session_id"`
+	SectionID  *uuid.UUID `db:"section_id"`
+	Position   int        `db:"position"`
+	BPM        int        `db:"bpm"`
+	Duration   int        `db:"duration"`
+	Reps       int        `db:"reps"`
+	Confidence int        `db:"confidence"`
+}
+
+type Section struct {
+	ID       uuid.UUID `db:"id"`
+	TaskID   uuid.UUID `db:"task_id"`
+	Name     string    `db:"name"`
+	StartBar int       `db:"start_bar"`
+	EndBar   int       `db:"end_bar"`
+	Position int       `db:"position"`
+}
+
+type TempoMark struct {
+	ID     uuid.UUID `db:"id"`
+	TaskID uuid.UUID `db:"task_id"`
+	Bar    int       `db:"bar"`
+	BPM    float64   `db:"bpm"`
+	Text   string    `db:"text"`
 }
 
 type Media struct {
@@ -51,6 +170,7 @@ type Media struct {
 	Size      int64     `db:"size"`
 	Duration  int       `db:"duration"`
 	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
 }
 
 type Link struct {
@@ -58,16 +178,434 @@ type Link struct {
 	TaskID    uuid.UUID `db:"task_id"`
 	Title     string    `db:"title"`
 	Type      LinkType  `db:"type"`
+	Version   int       `db:"version"`
 	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// TaskShare makes the progress images of a task public under a token.
+type TaskShare struct {
+	TaskID    uuid.UUID `db:"task_id"`
+	Token     string    `db:"token"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CalendarFeed publishes the sessions of a user as an iCalendar feed under a
+// secret token.
+type CalendarFeed struct {
+	UserID    uuid.UUID `db:"user_id"`
+	Token     string    `db:"token"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type ExportEntity string
+
+const (
+	ExportEntityTasks    ExportEntity = "tasks"
+	ExportEntitySessions ExportEntity = "sessions"
+	ExportEntityLinks    ExportEntity = "links"
+	ExportEntityMedia    ExportEntity = "media"
+	ExportEntityJournal  ExportEntity = "journal"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// ExportSession is a session with the title of its task, as exported.
+type ExportSession struct {
+	PracticeEntry
+	ID        uuid.UUID     `db:"id"`
+	TaskTitle string        `db:"task_title"`
+	Status    SessionStatus `db:"status"`
+	Note      string        `db:"note"`
+}
+
+// Goal holds the practice targets of a user. Zero means the target is not
+// set. With GraceDay a single missed day does not break a streak.
+type Goal struct {
+	UserID          uuid.UUID `db:"user_id"`
+	MinutesPerDay   int       `db:"minutes_per_day"`
+	DaysPerWeek     int       `db:"days_per_week"`
+	SessionsPerWeek int       `db:"sessions_per_week"`
+	GraceDay        bool      `db:"grace_day"`
+	Version         int       `db:"version"`
+}
+
+type StatsPeriod string
+
+const (
+	StatsPeriodDay   StatsPeriod = "day"
+	StatsPeriodWeek  StatsPeriod = "week"
+	StatsPeriodMonth StatsPeriod = "month"
+)
+
+// PracticeEntry is a finished session reduced to what the statistics need.
+type PracticeEntry struct {
+	TaskID        uuid.UUID `db:"task_id"`
+	StartTime     time.Time `db:"start_time"`
+	EndTime       time.Time `db:"end_time"`
+	BPM           int       `db:"bpm"`
+	Confidence    int       `db:"confidence"`
+	Pain          int       `db:"pain"`
+	Tension       int       `db:"tension"`
+	PausedSeconds int       `db:"paused_seconds"`
+}
+
+// GetDurationSeconds returns the time actually played, without pauses.
+func (e *PracticeEntry) GetDurationSeconds() int {
+	seconds := int(e.EndTime.Sub(e.StartTime).Seconds()) - e.PausedSeconds
+	if seconds < 0 {
+		return 0
+	}
+
+	return seconds
+}
+
+// JournalEntry is a dated note of a user that is not tied to a single task,
+// optionally linked to tasks and sessions. Mood and Energy are rated 1-5;
+// zero means not rated.
+type JournalEntry struct {
+	ID         uuid.UUID   `db:"id"`
+	UserID     uuid.UUID   `db:"user_id"`
+	Date       time.Time   `db:"entry_date"`
+	Title      string      `db:"title"`
+	Body       string      `db:"body"`
+	Mood       int         `db:"mood"`
+	Energy     int         `db:"energy"`
+	TaskIDs    []uuid.UUID `db:"task_ids"`
+	SessionIDs []uuid.UUID `db:"session_ids"`
+	Version    int         `db:"version"`
+	CreatedAt  time.Time   `db:"created_at"`
+	UpdatedAt  time.Time   `db:"updated_at"`
+}
+
+// JournalFilter narrows the journal listing. Zero fields do not filter.
+type JournalFilter struct {
+	From   *time.Time
+	To     *time.Time
+	Query  string
+	TaskID *uuid.UUID
+	Limit  int
 }
 
+// TrendStatus flags a task whose tempo has stopped improving or gone down.
+type TrendStatus string
+
+const (
+	TrendStatusPlateau    TrendStatus = "plateau"
+	TrendStatusRegression TrendStatus = "regression"
+)
+
+// ForecastStatus tells whether the target tempo of a task could be forecast.
+type ForecastStatus string
+
+const (
+	ForecastStatusReached      ForecastStatus = "reached"
+	ForecastStatusEstimated    ForecastStatus = "estimated"
+	ForecastStatusStalled      ForecastStatus = "stalled"
+	ForecastStatusInsufficient ForecastStatus = "insufficient_data"
+)
+
+// TempoAction is the change of tempo recommended for the next session.
+type TempoAction string
+
+const (
+	TempoActionStart    TempoAction = "start"
+	TempoActionStepUp   TempoAction = "step_up"
+	TempoActionHold     TempoAction = "hold"
+	TempoActionStepBack TempoAction = "step_back"
+)
+
+// RecommendationKind tells whether a practice suggestion is one of the user's
+// tasks or an exercise from the built-in catalog.
+type RecommendationKind string
+
+const (
+	RecommendationKindTask     RecommendationKind = "task"
+	RecommendationKindExercise RecommendationKind = "exercise"
+)
+
+// TempoAggressiveness sets how eagerly the recommendations raise the tempo.
+type TempoAggressiveness string
+
+const (
+	TempoAggressivenessCautious   TempoAggressiveness = "cautious"
+	TempoAggressivenessModerate   TempoAggressiveness = "moderate"
+	TempoAggressivenessAggressive TempoAggressiveness = "aggressive"
+)
+
+// TaskTrend is the flag the trend analyzer keeps for a task. The best BPM and
+// the average confidence compare the analysis window with the sessions before
+// it. DetectedAt is when the current status was first seen. WindowDays and
+// MinSessions are the settings of the analysis, repeated when the sessions of
+// the task change.
+type TaskTrend struct {
+	TaskID             uuid.UUID   `db:"task_id"`
+	Status             TrendStatus `db:"status"`
+	Reason             string      `db:"reason"`
+	BestBPM            int         `db:"best_bpm"`
+	PreviousBestBPM    int         `db:"previous_best_bpm"`
+	Confidence         float64     `db:"confidence"`
+	PreviousConfidence float64     `db:"previous_confidence"`
+	DetectedAt         time.Time   `db:"detected_at"`
+	CheckedAt          time.Time   `db:"checked_at"`
+	NotifiedAt         *time.Time  `db:"notified_at"`
+	WindowDays         int         `db:"window_days"`
+	MinSessions        int         `db:"min_sessions"`
+}
+
+// TrendCandidate is an active task with recent practice, along with what is
+// needed to notify its owner.
+type TrendCandidate struct {
+	TaskID    uuid.UUID `db:"task_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Email     string    `db:"email"`
+	Title     string    `db:"title"`
+	TargetBPM int       `db:"target_bpm"`
+}
+
+// WorkloadWarningKind is the reason practice volume may be risky for the
+// hands.
+type WorkloadWarningKind string
+
+const (
+	WorkloadWarningLoadSpike WorkloadWarningKind = "load_spike"
+	WorkloadWarningPain      WorkloadWarningKind = "pain"
+)
+
+// WorkloadCandidate is a user with recent practice, along with what is needed
+// to notify them.
+type WorkloadCandidate struct {
+	UserID   uuid.UUID `db:"user_id"`
+	Email    string    `db:"email"`
+	TimeZone string    `db:"time_zone"`
+}
+
+type ImportStatus string
+
+const (
+	ImportStatusPending    ImportStatus = "pending"
+	ImportStatusProcessing ImportStatus = "processing"
+	ImportStatusCompleted  ImportStatus = "completed"
+	ImportStatusFailed     ImportStatus = "failed"
+)
+
+// Import is a CSV file of sessions waiting for or going through the import
+// worker. Data is dropped once the import is finished.
+type Import struct {
+	ID               uuid.UUID        `db:"id"`
+	UserID           uuid.UUID        `db:"user_id"`
+	Status           ImportStatus     `db:"status"`
+	Filename         string           `db:"filename"`
+	Mapping          ImportMapping    `db:"mapping"`
+	Data             []byte           `db:"data"`
+	TotalRows        int              `db:"total_rows"`
+	ProcessedRows    int              `db:"processed_rows"`
+	ImportedSessions int              `db:"imported_sessions"`
+	SkippedSessions  int              `db:"skipped_sessions"`
+	FailedRows       int              `db:"failed_rows"`
+	CreatedTasks     int              `db:"created_tasks"`
+	Errors           []ImportRowError `db:"errors"`
+	Message          string           `db:"message"`
+	CreatedAt        time.Time        `db:"created_at"`
+	UpdatedAt        time.Time        `db:"updated_at"`
+	FinishedAt       *time.Time       `db:"finished_at"`
+}
+
+// ImportMapping names the CSV columns, by their header, that hold the session
+// fields. Either Duration or End must be set. DateFormat uses the YYYY, MM,
+// DD, HH, mm and ss tokens; common formats are recognized when it is empty.
+type ImportMapping struct {
+	Task         string `json:"task"`
+	Date         string `json:"date"`
+	Time         string `json:"time,omitempty"`
+	End          string `json:"end,omitempty"`
+	Duration     string `json:"duration,omitempty"`
+	BPM          string `json:"bpm,omitempty"`
+	Confidence   string `json:"confidence,omitempty"`
+	Note         string `json:"note,omitempty"`
+	DateFormat   string `json:"date_format,omitempty"`
+	DurationUnit string `json:"duration_unit,omitempty"`
+	Delimiter    string `json:"delimiter,omitempty"`
+	TimeZone     string `json:"time_zone,omitempty"`
+}
+
+// ImportRowError tells why a row of an import was rejected. Rows are numbered
+// as in the file, the header being row 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Tombstone records a deleted resource, so offline clients learn about the
+// deletion on their next sync.
+type Tombstone struct {
+	ID         uuid.UUID  `db:"id"`
+	EntityType EntityType `db:"entity_type"`
+	EntityID   uuid.UUID  `db:"entity_id"`
+	TaskID     uuid.UUID  `db:"task_id"`
+	DeletedAt  time.Time  `db:"deleted_at"`
+}
+
+type Chart struct {
+	ID        uuid.UUID `db:"id"`
+	TaskID    uuid.UUID `db:"task_id"`
+	Title     string    `db:"title"`
+	Key       string    `db:"key"`
+	Content   string    `db:"content"`
+	Version   int       `db:"version"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type Ensemble struct {
+	ID        uuid.UUID `db:"id"`
+	OwnerID   uuid.UUID `db:"owner_id"`
+	Title     string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type EnsembleMember struct {
+	ID         uuid.UUID `db:"id"`
+	EnsembleID uuid.UUID `db:"ensemble_id"`
+	UserID     uuid.UUID `db:"user_id"`
+	TaskID     uuid.UUID `db:"task_id"`
+	PartName   string    `db:"part_name"`
+	Username   string    `db:"username"`
+	JoinedAt   time.Time `db:"joined_at"`
+}
+
+type EnsembleInvitation struct {
+	ID         uuid.UUID        `db:"id"`
+	EnsembleID uuid.UUID        `db:"ensemble_id"`
+	InvitedBy  uuid.UUID        `db:"invited_by"`
+	Email      string           `db:"email"`
+	PartName   string           `db:"part_name"`
+	TargetBPM  int              `db:"target_bpm"`
+	Token      string           `db:"token"`
+	Status     InvitationStatus `db:"status"`
+	CreatedAt  time.Time        `db:"created_at"`
+}
+
+// invitationTTL is how long an ensemble invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+func (i *EnsembleInvitation) ExpiresAt() time.Time {
+	return i.CreatedAt.Add(invitationTTL)
+}
+
+// GetDurationSeconds returns the active practice time: pauses are not
+// counted, and a running session is measured up to now.
 func (s *Session) GetDurationSeconds() int {
-	return int(s.EndTime.Sub(s.StartTime).Seconds())
+	end := s.EndTime
+	if s.IsRunning() {
+		end = time.Now()
+	}
+
+	active := end.Sub(s.StartTime)
+	for _, p := range s.Pauses {
+		pauseEnd := end
+		if p.EndedAt != nil && p.EndedAt.Before(end) {
+			pauseEnd = *p.EndedAt
+		}
+
+		if pauseEnd.After(p.StartedAt) {
+			active -= pauseEnd.Sub(p.StartedAt)
+		}
+	}
+
+	return int(math.Max(active.Seconds(), 0))
+}
+
+// ApplySegments derives the session tempo and confidence from its segments:
+// a session counts at its fastest segment, the latest one on a tie.
+func (s *Session) ApplySegments() {
+	if len(s.Segments) == 0 {
+		return
+	}
+
+	best := s.Segments[0]
+	for _, segment := range s.Segments[1:] {
+		if segment.BPM >= best.BPM {
+			best = segment
+		}
+	}
+
+	s.BPM = best.BPM
+	s.Confidence = best.Confidence
+}
+
+func (s *Session) GetRepStats() RepStats {
+	var stats RepStats
+	for _, rep := range s.Reps {
+		stats.Total++
+		if !rep.Clean {
+			stats.Flawed++
+			stats.CurrentCleanStreak = 0
+			continue
+		}
+
+		stats.Clean++
+		stats.CurrentCleanStreak++
+		stats.LongestCleanStreak = max(stats.LongestCleanStreak, stats.CurrentCleanStreak)
+	}
+
+	if stats.Total > 0 {
+		stats.SuccessRatio = float64(stats.Clean) / float64(stats.Total)
+	}
+
+	return stats
+}
+
+// RepConfidence turns the share of clean reps into the 1-5 confidence scale.
+// It reports false when no reps were logged.
+func (s *Session) RepConfidence() (float64, bool) {
+	stats := s.GetRepStats()
+	if stats.Total == 0 {
+		return 0, false
+	}
+
+	return 1 + 4*stats.SuccessRatio, true
+}
+
+func (s *Session) IsRunning() bool {
+	return s.Status == SessionStatusOpen || s.Status == SessionStatusPaused
+}
+
+// NormalizeTags trims and lowercases the tags and drops empty and repeated
+// ones, so "Scales" and "scales " are grouped together in the statistics.
+func NormalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+// IsCompletedBy tells whether the session meets the completion rule of the
+// task: the prescribed number of clean reps in a row at the target tempo.
+func (t *Task) IsCompletedBy(session *Session) bool {
+	if t.CleanRepsGoal <= 0 || session.Status != SessionStatusFinished || session.BPM < t.TargetBPM {
+		return false
+	}
+
+	return session.GetRepStats().LongestCleanStreak >= t.CleanRepsGoal
 }
 
 func (mt MediaType) IsValid() bool {
 	switch mt {
-	case MediaTypeVideo, MediaTypeAudio, MediaTypeImage:
+	case MediaTypeVideo, MediaTypeAudio, MediaTypeImage, MediaTypeScore, MediaTypeMIDI:
 		return true
 	}
 
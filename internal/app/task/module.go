@@ -1,37 +1,214 @@
 package task
 
 import (
+	"context"
 	"log/slog"
+	"time"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/config"
+	"github.com/RuLap/trackmus-api/internal/pkg/pdf"
+	"github.com/RuLap/trackmus-api/internal/pkg/rabbitmq"
+	"github.com/RuLap/trackmus-api/internal/pkg/redis"
 	"github.com/RuLap/trackmus-api/internal/pkg/storage/minio"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	defaultSessionAbandonTimeout = 30 * time.Minute
+	defaultSessionCheckInterval  = 5 * time.Minute
+	defaultImportPollInterval    = 5 * time.Second
+	defaultImportStaleTimeout    = 10 * time.Minute
+	defaultTrendCheckInterval    = 6 * time.Hour
+	defaultTrendWindowDays       = 21
+	defaultTrendMinSessions      = 5
+	defaultWorkloadCheckInterval = 12 * time.Hour
+	defaultWorkloadAlertCooldown = 7 * 24 * time.Hour
+)
+
 type Module struct {
-	taskRepo    TaskRepository
-	sessionRepo SessionRepository
-	mediaRepo   MediaRepository
-	linkRepo    LinkRepository
-	service     Service
-	Handler     Handler
+	taskRepo     TaskRepository
+	sessionRepo  SessionRepository
+	mediaRepo    MediaRepository
+	linkRepo     LinkRepository
+	ensembleRepo EnsembleRepository
+	scoreRepo    ScoreRepository
+	chartRepo    ChartRepository
+	syncRepo     SyncRepository
+	statsRepo    StatsRepository
+	goalRepo     GoalRepository
+	shareRepo    ShareRepository
+	exportRepo   ExportRepository
+	importRepo   ImportRepository
+	journalRepo  JournalRepository
+	trendRepo    TrendRepository
+	workloadRepo WorkloadRepository
+	service      Service
+	analytics    AnalyticsService
+	imports      ImportService
+	exports      ExportService
+	Handler      Handler
 }
 
-func NewModule(log *slog.Logger, pool *pgxpool.Pool, minio *minio.Service) *Module {
+func NewModule(log *slog.Logger, pool *pgxpool.Pool, minio *minio.Service, rabbitmq *rabbitmq.Service, redis *redis.Service, pdfFont *pdf.Font) *Module {
 	taskRepo := NewTaskRepository(pool)
 	sessionRepo := NewSessionRepository(pool)
 	mediaRepo := NewMediaRepository(pool)
 	linkRepo := NewLinkRepository(pool)
+	ensembleRepo := NewEnsembleRepository(pool)
+	scoreRepo := NewScoreRepository(pool)
+	chartRepo := NewChartRepository(pool)
+	syncRepo := NewSyncRepository(pool)
+	statsRepo := NewStatsRepository(pool)
+	goalRepo := NewGoalRepository(pool)
+	shareRepo := NewShareRepository(pool)
+	exportRepo := NewExportRepository(pool)
+	importRepo := NewImportRepository(pool)
+	journalRepo := NewJournalRepository(pool)
+	trendRepo := NewTrendRepository(pool)
+	workloadRepo := NewWorkloadRepository(pool)
 
-	service := NewService(log, minio, taskRepo, sessionRepo, mediaRepo, linkRepo)
+	analytics := NewAnalyticsService(log, rabbitmq, redis, pdfFont, taskRepo, sessionRepo, statsRepo, goalRepo, trendRepo, workloadRepo)
+	service := NewService(log, minio, rabbitmq, analytics, taskRepo, sessionRepo, mediaRepo, linkRepo, ensembleRepo, scoreRepo, chartRepo, syncRepo, statsRepo, goalRepo, shareRepo, journalRepo)
+	imports := NewImportService(log, minio, service, taskRepo, sessionRepo, scoreRepo, statsRepo, importRepo)
+	exports := NewExportService(log, exportRepo)
 
-	handler := NewHandler(log, service)
+	handler := NewHandler(log, service, analytics, imports, exports)
 
 	return &Module{
-		taskRepo:    taskRepo,
-		sessionRepo: sessionRepo,
-		mediaRepo:   mediaRepo,
-		linkRepo:    linkRepo,
-		service:     service,
-		Handler:     *handler,
+		taskRepo:     taskRepo,
+		sessionRepo:  sessionRepo,
+		mediaRepo:    mediaRepo,
+		linkRepo:     linkRepo,
+		ensembleRepo: ensembleRepo,
+		scoreRepo:    scoreRepo,
+		chartRepo:    chartRepo,
+		syncRepo:     syncRepo,
+		statsRepo:    statsRepo,
+		goalRepo:     goalRepo,
+		shareRepo:    shareRepo,
+		exportRepo:   exportRepo,
+		importRepo:   importRepo,
+		journalRepo:  journalRepo,
+		trendRepo:    trendRepo,
+		workloadRepo: workloadRepo,
+		service:      service,
+		analytics:    analytics,
+		imports:      imports,
+		exports:      exports,
+		Handler:      *handler,
+	}
+}
+
+// StartSessionJanitor periodically closes the live sessions whose client has
+// stopped sending heartbeats. It blocks until ctx is done.
+func (m *Module) StartSessionJanitor(ctx context.Context, cfg *config.Sessions) {
+	timeout := cfg.AbandonTimeout
+	if timeout <= 0 {
+		timeout = defaultSessionAbandonTimeout
+	}
+
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultSessionCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.service.CloseAbandonedSessions(ctx, timeout)
+		}
+	}
+}
+
+// StartImportWorker runs the queued CSV imports one after another, checking
+// the queue every poll interval. It blocks until ctx is done.
+func (m *Module) StartImportWorker(ctx context.Context, cfg *config.Imports) {
+	interval := cfg.PollInterval
+	if interval <= 0 {
+		interval = defaultImportPollInterval
+	}
+
+	staleAfter := cfg.StaleTimeout
+	if staleAfter <= 0 {
+		staleAfter = defaultImportStaleTimeout
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				processed, err := m.imports.ProcessNextImport(ctx, staleAfter)
+				if err != nil || !processed {
+					break
+				}
+			}
+		}
+	}
+}
+
+// StartTrendAnalyzer periodically flags the tasks whose tempo has stalled or
+// dropped. It blocks until ctx is done.
+func (m *Module) StartTrendAnalyzer(ctx context.Context, cfg *config.Trends) {
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultTrendCheckInterval
+	}
+
+	days := cfg.WindowDays
+	if days <= 0 {
+		days = defaultTrendWindowDays
+	}
+
+	minSessions := cfg.MinSessions
+	if minSessions <= 0 {
+		minSessions = defaultTrendMinSessions
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.analytics.AnalyzeTrends(ctx, time.Duration(days)*24*time.Hour, minSessions)
+		}
+	}
+}
+
+// StartWorkloadMonitor periodically warns the users about sudden jumps in
+// practice volume and repeated pain. It blocks until ctx is done.
+func (m *Module) StartWorkloadMonitor(ctx context.Context, cfg *config.Workload) {
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = defaultWorkloadCheckInterval
+	}
+
+	cooldown := cfg.AlertCooldown
+	if cooldown <= 0 {
+		cooldown = defaultWorkloadAlertCooldown
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.analytics.CheckWorkloads(ctx, cooldown)
+		}
 	}
 }
@@ -0,0 +1,173 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	// recommendationSessions is how many of the latest sessions with a tempo
+	// the recommendation looks at.
+	recommendationSessions = 5
+
+	// A task without sessions starts at this share of its target tempo.
+	recommendationStartRatio = 0.7
+
+	minTempoStep           = 2
+	defaultPracticeMinutes = 20
+	minPracticeMinutes     = 10
+	maxPracticeMinutes     = 60
+	stepBackExtraMinutes   = 5
+)
+
+// tempoProfile holds the thresholds of one aggressiveness. Confidence is the
+// average rating of the recent sessions on a 1-5 scale.
+type tempoProfile struct {
+	StepUp         float64
+	StepBack       float64
+	UpConfidence   float64
+	BackConfidence float64
+	MinSessions    int
+}
+
+var tempoProfiles = map[TempoAggressiveness]tempoProfile{
+	TempoAggressivenessCautious:   {StepUp: 0.02, StepBack: 0.08, UpConfidence: 4, BackConfidence: 2.5, MinSessions: 3},
+	TempoAggressivenessModerate:   {StepUp: 0.04, StepBack: 0.06, UpConfidence: 3.5, BackConfidence: 2, MinSessions: 2},
+	TempoAggressivenessAggressive: {StepUp: 0.06, StepBack: 0.05, UpConfidence: 3, BackConfidence: 1.5, MinSessions: 1},
+}
+
+// GetTempoRecommendation suggests the tempo and the length of the next
+// session of the task from the tempo and confidence of the latest ones.
+func (s *analyticsService) GetTempoRecommendation(ctx context.Context, req *GetTempoRecommendationRequest, taskID, userID uuid.UUID) (*GetTempoRecommendationResponse, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get task from repository", "taskID", taskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if task.UserID != userID {
+		s.log.Warn("task belongs to another user", "taskID", taskID, "userID", userID)
+		return nil, fmt.Errorf(errors.ErrAccessDenied)
+	}
+
+	aggressiveness := TempoAggressiveness(req.Aggressiveness)
+	if aggressiveness == "" {
+		value, err := s.statsRepo.GetUserTempoAggressiveness(ctx, userID)
+		if err != nil {
+			s.log.Error("failed to get user tempo aggressiveness from repository", "userID", userID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+		aggressiveness = TempoAggressiveness(value)
+	}
+
+	profile, ok := tempoProfiles[aggressiveness]
+	if !ok {
+		aggressiveness = TempoAggressivenessModerate
+		profile = tempoProfiles[aggressiveness]
+	}
+
+	entries, err := s.trendRepo.GetPracticeEntries(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "taskID", taskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	result := recommendTempo(entries, task.TargetBPM, profile)
+	result.Aggressiveness = string(aggressiveness)
+
+	return &result, nil
+}
+
+// recommendTempo steps back when the latest sessions felt shaky, steps up
+// after enough confident sessions at the current tempo and holds otherwise.
+// The current tempo is the one of the latest session.
+func recommendTempo(entries []PracticeEntry, targetBPM int, profile tempoProfile) GetTempoRecommendationResponse {
+	recent := make([]PracticeEntry, 0, recommendationSessions)
+	for i := len(entries) - 1; i >= 0 && len(recent) < recommendationSessions; i-- {
+		if entries[i].BPM > 0 {
+			recent = append(recent, entries[i])
+		}
+	}
+
+	result := GetTempoRecommendationResponse{
+		TargetBPM:       targetBPM,
+		DurationMinutes: recommendedMinutes(recent),
+		Sessions:        len(recent),
+	}
+
+	if len(recent) == 0 {
+		result.Action = string(TempoActionStart)
+		result.BPM = max(1, int(math.Round(float64(targetBPM)*recommendationStartRatio)))
+		result.Explanation = fmt.Sprintf("Занятий ещё не было: начните с %d BPM, около 70%% целевого темпа, и добейтесь чистой игры.", result.BPM)
+		return result
+	}
+
+	current := recent[0].BPM
+	result.CurrentBPM = current
+	result.BPM = current
+
+	// recent is newest first; the sessions at the current tempo are counted
+	// until the tempo changes.
+	atTempo := 0
+	for _, entry := range recent {
+		if entry.BPM < current-minTempoStep || entry.BPM > current+minTempoStep {
+			break
+		}
+		atTempo++
+	}
+
+	confidence := averageEntryConfidence(recent[:atTempo])
+	latest := averageEntryConfidence(recent[:min(3, len(recent))])
+	result.Confidence = math.Round(confidence*10) / 10
+
+	switch {
+	case latest > 0 && latest <= profile.BackConfidence:
+		result.Action = string(TempoActionStepBack)
+		result.BPM = max(1, min(current-minTempoStep, int(math.Round(float64(current)*(1-profile.StepBack)))))
+		result.DurationMinutes = min(maxPracticeMinutes, result.DurationMinutes+stepBackExtraMinutes)
+		result.Explanation = fmt.Sprintf("Уверенность в последних занятиях — %.1f из 5. Вернитесь к %d BPM и поработайте над чистотой, прежде чем снова ускоряться.", latest, result.BPM)
+	case current >= targetBPM:
+		result.Action = string(TempoActionHold)
+		result.Explanation = fmt.Sprintf("Целевой темп %d BPM достигнут. Закрепите его, играя в этом темпе.", targetBPM)
+	case confidence == 0:
+		result.Action = string(TempoActionHold)
+		result.Explanation = fmt.Sprintf("Держите %d BPM и оценивайте уверенность после занятий, чтобы понять, когда ускоряться.", current)
+	case confidence >= profile.UpConfidence && atTempo >= profile.MinSessions:
+		result.Action = string(TempoActionStepUp)
+		result.BPM = min(targetBPM, max(current+minTempoStep, int(math.Round(float64(current)*(1+profile.StepUp)))))
+		result.Explanation = fmt.Sprintf("На %d BPM вы играете уверенно (%.1f из 5). Поднимите темп до %d BPM.", current, confidence, result.BPM)
+	case confidence >= profile.UpConfidence:
+		result.Action = string(TempoActionHold)
+		result.Explanation = fmt.Sprintf("Хорошее начало на %d BPM. Закрепите этот темп ещё в одном-двух занятиях, прежде чем ускоряться.", current)
+	default:
+		result.Action = string(TempoActionHold)
+		result.Explanation = fmt.Sprintf("Держите %d BPM, пока уверенность не поднимется до %.1f из 5 (сейчас %.1f).", current, profile.UpConfidence, confidence)
+	}
+
+	return result
+}
+
+// recommendedMinutes is the median length of the sessions, rounded to five
+// minutes.
+func recommendedMinutes(entries []PracticeEntry) int {
+	minutes := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if seconds := entry.GetDurationSeconds(); seconds > 0 {
+			minutes = append(minutes, seconds/60)
+		}
+	}
+
+	if len(minutes) == 0 {
+		return defaultPracticeMinutes
+	}
+
+	sort.Ints(minutes)
+	median := int(math.Round(float64(minutes[len(minutes)/2])/5)) * 5
+
+	return max(minPracticeMinutes, min(maxPracticeMinutes, median))
+}
@@ -0,0 +1,167 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecommendTempo(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	played := func(bpm, confidence int) func(i int) PracticeEntry {
+		return func(i int) PracticeEntry {
+			begin := start.AddDate(0, 0, i)
+			return PracticeEntry{StartTime: begin, EndTime: begin.Add(30 * time.Minute), BPM: bpm, Confidence: confidence}
+		}
+	}
+	sessions := func(played ...func(i int) PracticeEntry) []PracticeEntry {
+		entries := make([]PracticeEntry, 0, len(played))
+		for i, session := range played {
+			entries = append(entries, session(i))
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name           string
+		entries        []PracticeEntry
+		aggressiveness TempoAggressiveness
+		wantAction     TempoAction
+		wantBPM        int
+		wantMinutes    int
+	}{
+		{
+			name:        "first session",
+			wantAction:  TempoActionStart,
+			wantBPM:     84,
+			wantMinutes: defaultPracticeMinutes,
+		},
+		{
+			name:           "confident at the tempo",
+			entries:        sessions(played(100, 4), played(100, 4)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionStepUp,
+			wantBPM:        104,
+			wantMinutes:    30,
+		},
+		{
+			name:           "step up stops at the target",
+			entries:        sessions(played(118, 5), played(118, 5)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionStepUp,
+			wantBPM:        120,
+			wantMinutes:    30,
+		},
+		{
+			name:           "small steps are at least two BPM",
+			entries:        sessions(played(60, 4), played(60, 4), played(61, 5)),
+			aggressiveness: TempoAggressivenessCautious,
+			wantAction:     TempoActionStepUp,
+			wantBPM:        63,
+			wantMinutes:    30,
+		},
+		{
+			name:           "cautious waits for more sessions",
+			entries:        sessions(played(100, 4), played(100, 4)),
+			aggressiveness: TempoAggressivenessCautious,
+			wantAction:     TempoActionHold,
+			wantBPM:        100,
+			wantMinutes:    30,
+		},
+		{
+			name:           "aggressive steps up after one session",
+			entries:        sessions(played(100, 3)),
+			aggressiveness: TempoAggressivenessAggressive,
+			wantAction:     TempoActionStepUp,
+			wantBPM:        106,
+			wantMinutes:    30,
+		},
+		{
+			name:           "shaky sessions",
+			entries:        sessions(played(100, 3), played(100, 2), played(100, 1)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionStepBack,
+			wantBPM:        94,
+			wantMinutes:    35,
+		},
+		{
+			name:           "target reached",
+			entries:        sessions(played(120, 4), played(121, 5)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionHold,
+			wantBPM:        121,
+			wantMinutes:    30,
+		},
+		{
+			name:           "no ratings",
+			entries:        sessions(played(100, 0), played(100, 0)),
+			aggressiveness: TempoAggressivenessAggressive,
+			wantAction:     TempoActionHold,
+			wantBPM:        100,
+			wantMinutes:    30,
+		},
+		{
+			name:           "new tempo counts its own sessions",
+			entries:        sessions(played(90, 5), played(90, 5), played(100, 4)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionHold,
+			wantBPM:        100,
+			wantMinutes:    30,
+		},
+		{
+			name:           "not confident enough",
+			entries:        sessions(played(100, 3), played(100, 3)),
+			aggressiveness: TempoAggressivenessModerate,
+			wantAction:     TempoActionHold,
+			wantBPM:        100,
+			wantMinutes:    30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := recommendTempo(tt.entries, 120, tempoProfiles[tt.aggressiveness])
+
+			if result.Action != string(tt.wantAction) || result.BPM != tt.wantBPM {
+				t.Errorf("recommendation = %s at %d BPM, want %s at %d BPM", result.Action, result.BPM, tt.wantAction, tt.wantBPM)
+			}
+			if result.DurationMinutes != tt.wantMinutes {
+				t.Errorf("duration = %d min, want %d min", result.DurationMinutes, tt.wantMinutes)
+			}
+			if result.Explanation == "" {
+				t.Errorf("explanation is empty")
+			}
+		})
+	}
+}
+
+func TestRecommendedMinutes(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	lasting := func(minutes ...int) []PracticeEntry {
+		entries := make([]PracticeEntry, 0, len(minutes))
+		for _, m := range minutes {
+			entries = append(entries, PracticeEntry{StartTime: start, EndTime: start.Add(time.Duration(m) * time.Minute)})
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name    string
+		entries []PracticeEntry
+		want    int
+	}{
+		{name: "no sessions", want: defaultPracticeMinutes},
+		{name: "median rounded down", entries: lasting(40, 22, 15), want: 20},
+		{name: "median rounded up", entries: lasting(23), want: 25},
+		{name: "short sessions", entries: lasting(3, 4), want: minPracticeMinutes},
+		{name: "long sessions", entries: lasting(90, 120), want: maxPracticeMinutes},
+		{name: "paused time left out", entries: []PracticeEntry{{StartTime: start, EndTime: start.Add(time.Hour), PausedSeconds: 1800}}, want: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recommendedMinutes(tt.entries); got != tt.want {
+				t.Errorf("recommendedMinutes = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/pdf"
+	"github.com/RuLap/trackmus-api/internal/pkg/svg"
+	"github.com/google/uuid"
+)
+
+const (
+	pdfMargin      = 40.0
+	pdfRowHeight   = 15.0
+	pdfChartHeight = 110.0
+	pdfDateLayout  = "02.01.2006"
+)
+
+var (
+	pdfAccent = pdf.Color{R: 0, G: 0.48, B: 1}
+	pdfTarget = pdf.Color{R: 0.16, G: 0.65, B: 0.27}
+	pdfMuted  = pdf.Color{R: 0.45, G: 0.45, B: 0.45}
+	pdfLight  = pdf.Color{R: 0.93, G: 0.95, B: 0.97}
+	pdfGrid   = pdf.Color{R: 0.85, G: 0.87, B: 0.9}
+)
+
+// pdfColumns are the session table columns: header and left edge.
+var pdfColumns = []struct {
+	title string
+	x     float64
+}{
+	{"Дата", pdfMargin},
+	{"Длительность", pdfMargin + 70},
+	{"BPM", pdfMargin + 150},
+	{"Уверенность", pdfMargin + 190},
+	{"Заметки", pdfMargin + 265},
+}
+
+// RenderPracticePDF builds a printable practice log for the period: totals,
+// and for every task practiced in it a summary, a tempo chart and the table
+// of its sessions.
+func (s *analyticsService) RenderPracticePDF(ctx context.Context, req *GetReportRequest, userID uuid.UUID) ([]byte, error) {
+	report, err := s.GetReport(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	from, _ := time.ParseInLocation(statsDateLayout, report.From, loc)
+	to, _ := time.ParseInLocation(statsDateLayout, report.To, loc)
+	end := to.AddDate(0, 0, 1)
+
+	tasks, err := s.taskRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	doc := pdf.NewDocument(s.pdfFont, fmt.Sprintf("Журнал практики %s — %s", report.From, report.To))
+	layout := &pdfLayout{doc: doc}
+	layout.newPage()
+
+	layout.page.Text(pdfMargin, layout.y+18, 20, pdf.Black, "Журнал практики")
+	layout.y += 36
+	layout.page.Text(pdfMargin, layout.y, 10, pdfMuted, fmt.Sprintf("%s — %s, часовой пояс %s",
+		from.Format(pdfDateLayout), to.Format(pdfDateLayout), report.TimeZone))
+	layout.y += 24
+
+	layout.writeTotals(report)
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		sessions, err := s.sessionRepo.GetByTaskID(ctx, task.ID)
+		if err != nil {
+			s.log.Error("failed to load sessions from repository", "taskID", task.ID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+
+		inRange := make([]Session, 0, len(sessions))
+		for _, session := range sessions {
+			if session.Status == SessionStatusFinished && !session.StartTime.Before(from) && session.StartTime.Before(end) {
+				inRange = append(inRange, session)
+			}
+		}
+		if len(inRange) == 0 {
+			continue
+		}
+		sort.Slice(inRange, func(i, j int) bool {
+			return inRange[i].StartTime.Before(inRange[j].StartTime)
+		})
+
+		layout.writeTask(task, inRange, taskProgress(task, sessions), loc)
+	}
+
+	layout.writeFooters()
+
+	data, err := doc.Bytes()
+	if err != nil {
+		s.log.Error("failed to render practice pdf", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrCommon)
+	}
+
+	return data, nil
+}
+
+// pdfLayout places blocks one below another and starts a new page when the
+// next block does not fit.
+type pdfLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = pdfMargin
+}
+
+func (l *pdfLayout) ensure(height float64) bool {
+	if l.y+height <= pdf.PageHeight-pdfMargin {
+		return false
+	}
+
+	l.newPage()
+	return true
+}
+
+func (l *pdfLayout) writeTotals(report *GetReportResponse) {
+	totals := []struct{ label, value string }{
+		{"Часов практики", fmt.Sprintf("%.1f", report.TotalHours)},
+		{"Занятий", fmt.Sprint(report.Sessions)},
+		{"Дней с практикой", fmt.Sprint(report.DaysPracticed)},
+		{"Самая длинная серия", fmt.Sprint(report.LongestStreak)},
+		{"Задач выполнено", fmt.Sprint(report.TasksCompleted)},
+	}
+
+	width := (pdf.PageWidth - 2*pdfMargin) / float64(len(totals))
+	l.page.Rect(pdfMargin, l.y, pdf.PageWidth-2*pdfMargin, 48, pdfLight)
+	for i, total := range totals {
+		x := pdfMargin + float64(i)*width + 8
+		l.page.Text(x, l.y+22, 16, pdfAccent, total.value)
+		l.page.Text(x, l.y+38, 8, pdfMuted, l.doc.Fit(total.label, 8, width-12))
+	}
+	l.y += 68
+}
+
+func (l *pdfLayout) writeTask(task *Task, sessions []Session, progress float64, loc *time.Location) {
+	seconds, best, confidenceSum, rated := 0, 0, 0, 0
+	for i := range sessions {
+		seconds += sessions[i].GetDurationSeconds()
+		best = max(best, sessionBestBPM(&sessions[i]))
+		if sessions[i].Confidence > 0 {
+			confidenceSum += sessions[i].Confidence
+			rated++
+		}
+	}
+
+	// Keep the heading together with the chart and the first rows.
+	l.ensure(40 + pdfChartHeight + 4*pdfRowHeight)
+
+	contentWidth := pdf.PageWidth - 2*pdfMargin
+	l.y += 8
+	l.page.Text(pdfMargin, l.y+14, 14, pdf.Black, l.doc.Fit(task.Title, 14, contentWidth))
+	l.y += 30
+
+	summary := fmt.Sprintf("Занятий: %d · Время: %s · Лучший темп: %d", len(sessions), formatPracticeDuration(seconds), best)
+	if task.TargetBPM > 0 {
+		summary += fmt.Sprintf(" из %d BPM", task.TargetBPM)
+	}
+	if rated > 0 {
+		summary += fmt.Sprintf(" · Уверенность: %.1f", float64(confidenceSum)/float64(rated))
+	}
+	summary += fmt.Sprintf(" · Прогресс: %.0f%%", progress)
+	l.page.Text(pdfMargin, l.y, 9, pdfMuted, l.doc.Fit(summary, 9, contentWidth))
+	l.y += 12
+
+	if points := dailyBestBPM(sessions, loc); len(points) > 0 {
+		l.writeChart(points, float64(task.TargetBPM))
+	}
+
+	l.writeTableHeader()
+	for i := range sessions {
+		if l.ensure(pdfRowHeight) {
+			l.writeTableHeader()
+		}
+		l.writeSessionRow(&sessions[i], loc, i%2 == 1)
+	}
+	l.y += 12
+}
+
+func (l *pdfLayout) writeChart(points []svg.Point, target float64) {
+	left, right := pdfMargin+30, pdf.PageWidth-pdfMargin
+	top, bottom := l.y+8, l.y+pdfChartHeight-12
+
+	maxValue := target
+	for _, point := range points {
+		maxValue = math.Max(maxValue, point.Value)
+	}
+	yMax := math.Max(10, math.Ceil(maxValue*1.1/10)*10)
+	y := func(value float64) float64 {
+		return bottom - value/yMax*(bottom-top)
+	}
+
+	for _, value := range []float64{0, yMax / 2, yMax} {
+		l.page.Line(left, y(value), right, y(value), 0.5, pdfGrid)
+		l.page.TextRight(left-4, y(value)+3, 7, pdfMuted, fmt.Sprintf("%.0f", value))
+	}
+	if target > 0 {
+		l.page.DashedLine(left, y(target), right, y(target), 0.8, 4, pdfTarget)
+	}
+
+	first, last := points[0].Time, points[len(points)-1].Time
+	span := last.Sub(first).Seconds()
+	coords := make([][2]float64, 0, len(points))
+	for _, point := range points {
+		x := (left + right) / 2
+		if span > 0 {
+			x = left + point.Time.Sub(first).Seconds()/span*(right-left)
+		}
+		coords = append(coords, [2]float64{x, y(point.Value)})
+	}
+	l.page.Polyline(coords, 1.5, pdfAccent)
+	for _, coord := range coords {
+		l.page.Rect(coord[0]-1.5, coord[1]-1.5, 3, 3, pdfAccent)
+	}
+
+	l.page.Text(left, bottom+10, 7, pdfMuted, first.Format(pdfDateLayout))
+	if span > 0 {
+		l.page.TextRight(right, bottom+10, 7, pdfMuted, last.Format(pdfDateLayout))
+	}
+
+	l.y += pdfChartHeight
+}
+
+func (l *pdfLayout) writeTableHeader() {
+	l.page.Rect(pdfMargin, l.y, pdf.PageWidth-2*pdfMargin, pdfRowHeight, pdfLight)
+	for _, column := range pdfColumns {
+		l.page.Text(column.x+2, l.y+10.5, 8, pdfMuted, column.title)
+	}
+	l.y += pdfRowHeight
+}
+
+func (l *pdfLayout) writeSessionRow(session *Session, loc *time.Location, shaded bool) {
+	if shaded {
+		l.page.Rect(pdfMargin, l.y, pdf.PageWidth-2*pdfMargin, pdfRowHeight, pdf.Color{R: 0.98, G: 0.98, B: 0.99})
+	}
+
+	values := []string{
+		session.StartTime.In(loc).Format(pdfDateLayout),
+		formatPracticeDuration(session.GetDurationSeconds()),
+		"—",
+		"—",
+		session.Note,
+	}
+	if bpm := sessionBestBPM(session); bpm > 0 {
+		values[2] = fmt.Sprint(bpm)
+	}
+	if session.Confidence > 0 {
+		values[3] = fmt.Sprintf("%d / 5", session.Confidence)
+	}
+
+	for i, column := range pdfColumns {
+		width := pdf.PageWidth - pdfMargin - column.x - 4
+		if i+1 < len(pdfColumns) {
+			width = pdfColumns[i+1].x - column.x - 4
+		}
+		l.page.Text(column.x+2, l.y+10.5, 8, pdf.Black, l.doc.Fit(values[i], 8, width))
+	}
+	l.y += pdfRowHeight
+}
+
+func (l *pdfLayout) writeFooters() {
+	pages := l.doc.Pages()
+	for i, page := range pages {
+		page.TextRight(pdf.PageWidth-pdfMargin, pdf.PageHeight-20, 8, pdfMuted, fmt.Sprintf("стр. %d из %d", i+1, len(pages)))
+		page.Text(pdfMargin, pdf.PageHeight-20, 8, pdfMuted, "Trackmus")
+	}
+}
+
+func sessionBestBPM(session *Session) int {
+	best := 0
+	for _, attempt := range sessionAttempts(session) {
+		best = max(best, attempt.BPM)
+	}
+
+	return best
+}
+
+func formatPracticeDuration(seconds int) string {
+	hours, minutes := seconds/3600, seconds%3600/60
+	if hours > 0 {
+		return fmt.Sprintf("%d ч %02d мин", hours, minutes)
+	}
+
+	return fmt.Sprintf("%d мин", minutes)
+}
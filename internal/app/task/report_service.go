@@ -0,0 +1,371 @@
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/events"
+	"github.com/RuLap/trackmus-api/internal/pkg/svg"
+	"github.com/google/uuid"
+)
+
+const (
+	reportMonthLayout = "2006-01"
+
+	// Reports of finished periods rarely change, only when an offline client
+	// uploads old sessions, so they are kept longer than the current ones.
+	reportCacheTTL     = 7 * 24 * time.Hour
+	reportLiveCacheTTL = time.Hour
+)
+
+var reportMonthNames = [...]string{
+	"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь",
+}
+
+// GetReport summarizes the practice of the user over a period: a calendar
+// year by default or the from-to range when given. Results are cached per
+// period and time zone under the user's report version, which every change of
+// their tasks and sessions bumps.
+func (s *analyticsService) GetReport(ctx context.Context, req *GetReportRequest, userID uuid.UUID) (*GetReportResponse, error) {
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, req.TimeZone, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := reportRange(req, loc)
+	if err != nil {
+		s.log.Info("invalid report range", "req", req, "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	cacheKey := ""
+	if s.redis != nil {
+		version, err := s.redis.GetInt(ctx, reportVersionKey(userID))
+		if err != nil {
+			s.log.Warn("failed to get report version", "userID", userID, "error", err)
+		} else {
+			cacheKey = fmt.Sprintf("report:%s:%d:%s:%s:%s", userID, version, from.Format(statsDateLayout), to.Format(statsDateLayout), loc.String())
+		}
+	}
+
+	if cacheKey != "" {
+		var cached GetReportResponse
+		if err := s.redis.GetJSON(ctx, cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	result, err := s.buildReport(ctx, from, to, loc, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheKey != "" {
+		ttl := reportCacheTTL
+		if !to.Before(startOfDay(time.Now().In(loc))) {
+			ttl = reportLiveCacheTTL
+		}
+		if err := s.redis.SetJSON(ctx, cacheKey, result, ttl); err != nil {
+			s.log.Warn("failed to cache report", "key", cacheKey, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// invalidateReports makes the cached reports of the user unreachable; they
+// expire on their own.
+func (s *analyticsService) invalidateReports(ctx context.Context, userID uuid.UUID) {
+	if s.redis == nil {
+		return
+	}
+
+	if _, err := s.redis.Incr(ctx, reportVersionKey(userID)); err != nil {
+		s.log.Warn("failed to bump report version", "userID", userID, "error", err)
+	}
+}
+
+func reportVersionKey(userID uuid.UUID) string {
+	return fmt.Sprintf("report:version:%s", userID)
+}
+
+// SendReport emails the report to the user through the mail service.
+func (s *analyticsService) SendReport(ctx context.Context, req *GetReportRequest, userID uuid.UUID, email string) error {
+	report, err := s.GetReport(ctx, req, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.rabbitmq == nil {
+		s.log.Warn("event service not available - report email not sent", "userID", userID)
+		return fmt.Errorf(errors.ErrFailedToSendEmail)
+	}
+
+	data := map[string]interface{}{
+		"user_email":      email,
+		"from":            report.From,
+		"to":              report.To,
+		"total_hours":     strconv.FormatFloat(report.TotalHours, 'f', -1, 64),
+		"sessions":        strconv.Itoa(report.Sessions),
+		"days_practiced":  strconv.Itoa(report.DaysPracticed),
+		"longest_streak":  strconv.Itoa(report.LongestStreak),
+		"tasks_completed": strconv.Itoa(report.TasksCompleted),
+	}
+	if report.MostPracticed != nil {
+		data["most_practiced"] = report.MostPracticed.Title
+		data["most_practiced_hours"] = strconv.FormatFloat(secondsToHours(report.MostPracticed.PracticeSeconds), 'f', -1, 64)
+		if chart, err := s.renderReportChart(ctx, report); err == nil {
+			data["bpm_chart"] = base64.StdEncoding.EncodeToString(chart)
+		}
+	}
+	if report.BiggestBPMJump != nil {
+		data["bpm_jump_title"] = report.BiggestBPMJump.Title
+		data["bpm_jump"] = fmt.Sprintf("%d → %d BPM", report.BiggestBPMJump.FromBPM, report.BiggestBPMJump.ToBPM)
+	}
+	if report.BusiestMonth != nil {
+		if month, err := time.Parse(reportMonthLayout, report.BusiestMonth.Month); err == nil {
+			data["busiest_month"] = fmt.Sprintf("%s %d", reportMonthNames[month.Month()-1], month.Year())
+		}
+	}
+
+	event := events.EmailEvent{
+		To:       email,
+		Template: "practice_report",
+		Subject:  "Ваши итоги практики",
+		Data:     data,
+	}
+
+	if err := s.rabbitmq.PublishEmail(event); err != nil {
+		s.log.Error("failed to publish email event", "error", err)
+		return fmt.Errorf(errors.ErrFailedToSendEmail)
+	}
+
+	s.log.Info("practice report sent", "userID", userID, "from", report.From, "to", report.To)
+
+	return nil
+}
+
+// renderReportChart draws the tempo chart of the most practiced task for the
+// report email. It is a PNG, as most mail clients show neither SVG nor data
+// URIs; the mail service sends it as an inline attachment. The email is still
+// sent without it when it fails.
+func (s *analyticsService) renderReportChart(ctx context.Context, report *GetReportResponse) ([]byte, error) {
+	taskID, err := uuid.Parse(report.MostPracticed.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		s.log.Warn("failed to get task for report chart", "taskID", taskID, "error", err)
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	sessions, err := s.sessionRepo.GetByTaskID(ctx, task.ID)
+	if err != nil {
+		s.log.Warn("failed to load sessions for report chart", "taskID", taskID, "error", err)
+		return nil, err
+	}
+
+	image, err := svg.RenderLineChartPNG(bpmChart(task, sessions, loc))
+	if err != nil {
+		s.log.Warn("failed to render report chart", "taskID", taskID, "error", err)
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func (s *analyticsService) buildReport(ctx context.Context, from, to time.Time, loc *time.Location, userID uuid.UUID) (*GetReportResponse, error) {
+	end := to.AddDate(0, 0, 1)
+
+	entries, err := s.statsRepo.GetPracticeEntriesSince(ctx, userID, from, end)
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	bestBPM, err := s.statsRepo.GetBestBPMs(ctx, userID, from)
+	if err != nil {
+		s.log.Error("failed to get best bpms from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	tasks, err := s.taskRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	goal, err := s.goalRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get goals from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+	if goal == nil {
+		goal = &Goal{UserID: userID}
+	}
+
+	tasksByID := make(map[uuid.UUID]*Task, len(tasks))
+	for i := range tasks {
+		tasksByID[tasks[i].ID] = &tasks[i]
+	}
+
+	totals := newStatsAccumulator()
+	byTask := make(map[uuid.UUID]*statsAccumulator)
+	byMonth := make(map[string]*statsAccumulator)
+	seconds := make(map[string]int)
+
+	for i := range entries {
+		entry := &entries[i]
+		day := startOfDay(entry.StartTime.In(loc))
+		best := bestBPM[entry.TaskID]
+
+		totals.add(entry, best)
+		statsBucket(byTask, entry.TaskID).add(entry, best)
+		statsBucket(byMonth, day.Format(reportMonthLayout)).add(entry, best)
+		seconds[day.Format(statsDateLayout)] += entry.GetDurationSeconds()
+
+		if entry.BPM > bestBPM[entry.TaskID] {
+			bestBPM[entry.TaskID] = entry.BPM
+		}
+	}
+
+	result := GetReportResponse{
+		TimeZone:        loc.String(),
+		From:            from.Format(statsDateLayout),
+		To:              to.Format(statsDateLayout),
+		GeneratedAt:     time.Now().UTC(),
+		PracticeSeconds: totals.seconds,
+		TotalHours:      secondsToHours(totals.seconds),
+		Sessions:        totals.sessions,
+		LongestStreak:   computeStreak(seconds, goal, from, to).Longest,
+		CompletedTasks:  make([]GetReportCompletedResponse, 0),
+	}
+
+	for _, practiced := range seconds {
+		if practiced > 0 {
+			result.DaysPracticed++
+		}
+	}
+
+	for taskID, acc := range byTask {
+		title := ""
+		if task, ok := tasksByID[taskID]; ok {
+			title = task.Title
+		}
+
+		most := result.MostPracticed
+		if most == nil || acc.seconds > most.PracticeSeconds ||
+			(acc.seconds == most.PracticeSeconds && reportTaskBefore(title, taskID, most.Title, most.TaskID)) {
+			result.MostPracticed = &GetReportTaskResponse{
+				TaskID:          taskID.String(),
+				Title:           title,
+				PracticeSeconds: acc.seconds,
+				Sessions:        acc.sessions,
+			}
+		}
+
+		start, best := acc.bpmRange(taskID)
+		jump := result.BiggestBPMJump
+		if gain := best - start; gain > 0 && (jump == nil || gain > jump.Gain ||
+			(gain == jump.Gain && reportTaskBefore(title, taskID, jump.Title, jump.TaskID))) {
+			result.BiggestBPMJump = &GetReportBPMJumpResponse{
+				TaskID:  taskID.String(),
+				Title:   title,
+				FromBPM: start,
+				ToBPM:   best,
+				Gain:    gain,
+			}
+		}
+	}
+
+	for month, acc := range byMonth {
+		busiest := result.BusiestMonth
+		if busiest == nil || acc.seconds > busiest.PracticeSeconds ||
+			(acc.seconds == busiest.PracticeSeconds && month < busiest.Month) {
+			result.BusiestMonth = &GetReportMonthResponse{
+				Month:           month,
+				PracticeSeconds: acc.seconds,
+				Sessions:        acc.sessions,
+			}
+		}
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if task.CompletedAt == nil || task.CompletedAt.Before(from) || !task.CompletedAt.Before(end) {
+			continue
+		}
+
+		result.CompletedTasks = append(result.CompletedTasks, GetReportCompletedResponse{
+			TaskID:      task.ID.String(),
+			Title:       task.Title,
+			CompletedAt: *task.CompletedAt,
+		})
+	}
+	result.TasksCompleted = len(result.CompletedTasks)
+
+	return &result, nil
+}
+
+// reportTaskBefore orders tasks that tie in a report highlight by title, then
+// by ID, so the pick does not depend on map iteration order.
+func reportTaskBefore(title string, id uuid.UUID, otherTitle, otherID string) bool {
+	if title != otherTitle {
+		return title < otherTitle
+	}
+	return id.String() < otherID
+}
+
+// reportRange resolves the period of a report. Without from and to it is the
+// requested or the current calendar year; a missing from starts the year of
+// to, and a missing to ends today.
+func reportRange(req *GetReportRequest, loc *time.Location) (time.Time, time.Time, error) {
+	today := startOfDay(time.Now().In(loc))
+
+	if req.From == "" && req.To == "" {
+		year := req.Year
+		if year == 0 {
+			year = today.Year()
+		}
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+
+	var err error
+	to := today
+	if req.To != "" {
+		if to, err = time.ParseInLocation(statsDateLayout, req.To, loc); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	from := time.Date(to.Year(), 1, 1, 0, 0, 0, 0, loc)
+	if req.From != "" {
+		if from, err = time.ParseInLocation(statsDateLayout, req.From, loc); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	if from.After(to) || to.Sub(from) > maxStatsRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range %s - %s is out of bounds", from, to)
+	}
+
+	return from, to, nil
+}
+
+func secondsToHours(seconds int) float64 {
+	return math.Round(float64(seconds)/3600*10) / 10
+}
@@ -0,0 +1,197 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ScoreRepository interface {
+	GetSectionsByTaskID(ctx context.Context, taskID uuid.UUID) ([]Section, error)
+	GetTempoMarksByTaskID(ctx context.Context, taskID uuid.UUID) ([]TempoMark, error)
+	CreateTask(ctx context.Context, task *Task, sections []Section, marks []TempoMark, media *Media) (*Task, error)
+}
+
+type scoreRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewScoreRepository(pool *pgxpool.Pool) ScoreRepository {
+	return &scoreRepository{pool}
+}
+
+func (r *scoreRepository) GetSectionsByTaskID(ctx context.Context, taskID uuid.UUID) ([]Section, error) {
+	query := `
+		SELECT id, task_id, name, start_bar, end_bar, position
+		FROM task_sections
+		WHERE task_id = $1
+		ORDER BY position
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	sections := make([]Section, 0)
+	for rows.Next() {
+		var section Section
+		err := rows.Scan(
+			&section.ID,
+			&section.TaskID,
+			&section.Name,
+			&section.StartBar,
+			&section.EndBar,
+			&section.Position,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan section: %w", err)
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
+
+func (r *scoreRepository) GetTempoMarksByTaskID(ctx context.Context, taskID uuid.UUID) ([]TempoMark, error) {
+	query := `
+		SELECT id, task_id, bar, bpm, text
+		FROM task_tempo_marks
+		WHERE task_id = $1
+		ORDER BY bar
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	marks := make([]TempoMark, 0)
+	for rows.Next() {
+		var mark TempoMark
+		err := rows.Scan(
+			&mark.ID,
+			&mark.TaskID,
+			&mark.Bar,
+			&mark.BPM,
+			&mark.Text,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tempo mark: %w", err)
+		}
+
+		marks = append(marks, mark)
+	}
+
+	return marks, nil
+}
+
+// CreateTask stores a task imported from a score file together with its
+// sections, tempo marks and the media of the original file in a single
+// transaction. The task and media IDs are set by the caller.
+func (r *scoreRepository) CreateTask(ctx context.Context, task *Task, sections []Section, marks []TempoMark, media *Media) (*Task, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO tasks(id, user_id, title, target_bpm, composer, time_signature, duration)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version
+	`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		task.ID,
+		task.UserID,
+		task.Title,
+		task.TargetBPM,
+		task.Composer,
+		task.TimeSignature,
+		task.Duration,
+	).Scan(
+		&task.ID,
+		&task.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	query = `
+		INSERT INTO task_sections(task_id, name, start_bar, end_bar, position)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	for i := range sections {
+		sections[i].TaskID = task.ID
+		_, err := tx.Exec(
+			ctx,
+			query,
+			task.ID,
+			sections[i].Name,
+			sections[i].StartBar,
+			sections[i].EndBar,
+			sections[i].Position,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create section: %w", err)
+		}
+	}
+
+	query = `
+		INSERT INTO task_tempo_marks(task_id, bar, bpm, text)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	for i := range marks {
+		marks[i].TaskID = task.ID
+		_, err := tx.Exec(
+			ctx,
+			query,
+			task.ID,
+			marks[i].Bar,
+			marks[i].BPM,
+			marks[i].Text,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tempo mark: %w", err)
+		}
+	}
+
+	query = `
+		INSERT INTO medias(id, task_id, type, filename, size, duration)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	media.TaskID = task.ID
+	err = tx.QueryRow(
+		ctx,
+		query,
+		media.ID,
+		media.TaskID,
+		media.Type,
+		media.Filename,
+		media.Size,
+		media.Duration,
+	).Scan(
+		&media.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return task, nil
+}
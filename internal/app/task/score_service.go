@@ -0,0 +1,171 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/midi"
+	"github.com/RuLap/trackmus-api/internal/pkg/musicxml"
+	"github.com/google/uuid"
+)
+
+func (s *importService) ImportMusicXML(ctx context.Context, req *ImportScoreRequest, data []byte, filename string, userID uuid.UUID) (*GetTaskResponse, error) {
+	score, err := musicxml.Parse(data)
+	if err != nil {
+		s.log.Warn("failed to parse MusicXML", "filename", filename, "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidScore)
+	}
+
+	task := Task{
+		UserID:        userID,
+		Title:         firstNonEmpty(req.Title, score.Title, strings.TrimSuffix(filename, filepath.Ext(filename))),
+		TargetBPM:     req.TargetBPM,
+		Composer:      truncate(score.Composer, 100),
+		TimeSignature: score.TimeSignature,
+	}
+	if task.TargetBPM == 0 && len(score.Tempos) > 0 {
+		task.TargetBPM = int(math.Round(score.Tempos[0].BPM))
+	}
+
+	sections := make([]Section, 0, len(score.Sections))
+	for i, section := range score.Sections {
+		sections = append(sections, Section{
+			Name:     truncate(section.Name, 50),
+			StartBar: section.StartBar,
+			EndBar:   section.EndBar,
+			Position: i,
+		})
+	}
+
+	marks := make([]TempoMark, 0, len(score.Tempos))
+	for _, tempo := range score.Tempos {
+		marks = append(marks, TempoMark{
+			Bar:  tempo.Bar,
+			BPM:  tempo.BPM,
+			Text: truncate(tempo.Text, 100),
+		})
+	}
+
+	return s.importScore(ctx, &task, sections, marks, data, filename, MediaTypeScore)
+}
+
+func (s *importService) ImportMIDI(ctx context.Context, req *ImportScoreRequest, data []byte, filename string, userID uuid.UUID) (*GetTaskResponse, error) {
+	const (
+		tempoTolerance = 0.03
+		minSectionBars = 2
+	)
+
+	file, err := midi.Parse(data)
+	if err != nil {
+		s.log.Warn("failed to parse MIDI file", "filename", filename, "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidScore)
+	}
+
+	task := Task{
+		UserID:        userID,
+		Title:         firstNonEmpty(req.Title, file.Title, strings.TrimSuffix(filename, filepath.Ext(filename))),
+		TargetBPM:     req.TargetBPM,
+		TimeSignature: file.TimeSignatures[0].String(),
+		Duration:      int(math.Round(file.DurationSeconds)),
+	}
+	if task.TargetBPM == 0 {
+		task.TargetBPM = int(math.Round(file.PredominantBPM()))
+	}
+
+	var sections []Section
+	if suggested := file.TempoSections(tempoTolerance, minSectionBars); len(suggested) > 1 {
+		for i, section := range suggested {
+			sections = append(sections, Section{
+				Name:     fmt.Sprintf("Часть %d (%d BPM)", i+1, int(math.Round(section.BPM))),
+				StartBar: section.StartBar,
+				EndBar:   section.EndBar,
+				Position: i,
+			})
+		}
+	}
+
+	marks := make([]TempoMark, 0, len(file.Tempos))
+	for _, tempo := range file.Tempos {
+		marks = append(marks, TempoMark{
+			Bar: tempo.Bar,
+			BPM: math.Round(tempo.BPM*100) / 100,
+		})
+	}
+
+	return s.importScore(ctx, &task, sections, marks, data, filename, MediaTypeMIDI)
+}
+
+// importScore creates a task from a parsed score or MIDI file and keeps the
+// original file as media of the new task. The file is uploaded first, so a
+// failed import leaves neither a task nor a stray object behind.
+func (s *importService) importScore(
+	ctx context.Context,
+	task *Task,
+	sections []Section,
+	marks []TempoMark,
+	data []byte,
+	filename string,
+	mediaType MediaType,
+) (*GetTaskResponse, error) {
+	if task.TargetBPM <= 0 {
+		return nil, fmt.Errorf(errors.ErrTempoNotFound)
+	}
+	task.ID = uuid.New()
+	task.Title = truncate(task.Title, 50)
+
+	media := Media{
+		ID:       uuid.New(),
+		TaskID:   task.ID,
+		Type:     mediaType,
+		Filename: filename,
+		Size:     int64(len(data)),
+	}
+	s3Key := fmt.Sprintf("%s/%s", task.ID, media.ID)
+
+	err := s.minio.UploadFile(ctx, s.bucketName, s3Key, bytes.NewReader(data), media.Size)
+	if err != nil {
+		s.log.Error("failed to upload score to minio", "objName", s3Key, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	created, err := s.scoreRepo.CreateTask(ctx, task, sections, marks, &media)
+	if err != nil {
+		s.log.Error("failed to create task from score in repository",
+			"filename", filename,
+			"userID", task.UserID,
+			"error", err,
+		)
+		if err := s.minio.DeleteFile(ctx, s.bucketName, s3Key); err != nil {
+			s.log.Warn("failed to delete score from minio", "objName", s3Key, "error", err)
+		}
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	s.log.Info("task imported from score", "taskID", created.ID, "filename", filename)
+
+	return s.tasks.GetTaskByID(ctx, created.ID)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	return string(runes[:max])
+}
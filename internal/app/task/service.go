@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"time"
 
 	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/rabbitmq"
 	"github.com/RuLap/trackmus-api/internal/pkg/storage/minio"
 	"github.com/google/uuid"
 )
@@ -16,46 +18,127 @@ type Service interface {
 	GetCompletedTasks(ctx context.Context, userID uuid.UUID) ([]GetTaskShortResponse, error)
 	GetTaskByID(ctx context.Context, id uuid.UUID) (*GetTaskResponse, error)
 	CreateTask(ctx context.Context, req *SaveTaskRequest, userID uuid.UUID) (*GetTaskShortResponse, error)
-	UpdateTask(ctx context.Context, req *SaveTaskRequest, id uuid.UUID) (*GetTaskResponse, error)
-	CompleteTask(ctx context.Context, id uuid.UUID) (*GetTaskShortResponse, error)
+	UpdateTask(ctx context.Context, req *SaveTaskRequest, id, userID uuid.UUID) (*GetTaskResponse, error)
+	CompleteTask(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) (*GetTaskShortResponse, error)
 
 	GetSessionByID(ctx context.Context, id uuid.UUID) (*GetSessionResponse, error)
 	CreateSession(ctx context.Context, req *SaveSessionRequest, taskID uuid.UUID) (*GetSessionResponse, error)
+	UpdateSession(ctx context.Context, req *SaveSessionRequest, id, userID uuid.UUID) (*GetSessionResponse, error)
+	RemoveSession(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) (*GetTaskShortResponse, error)
+	AddSessionReps(ctx context.Context, req *AddRepsRequest, id, userID uuid.UUID) (*GetSessionResponse, error)
+	StartSession(ctx context.Context, taskID, userID uuid.UUID) (*GetSessionResponse, error)
+	PauseSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error)
+	ResumeSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error)
+	HeartbeatSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error)
+	FinishSession(ctx context.Context, req *FinishSessionRequest, id, userID uuid.UUID) (*GetSessionResponse, error)
+	CloseAbandonedSessions(ctx context.Context, timeout time.Duration) (int64, error)
+	CreateSessionsBatch(ctx context.Context, items []BatchSessionItemRequest, userID uuid.UUID) []BatchSessionResult
+
+	GetChanges(ctx context.Context, token string, userID uuid.UUID) (*GetSyncResponse, error)
+	PushChanges(ctx context.Context, req *SyncPushRequest, userID uuid.UUID) *SyncPushResponse
+
+	GetGoals(ctx context.Context, timeZone string, userID uuid.UUID) (*GetGoalsResponse, error)
+	SaveGoals(ctx context.Context, req *SaveGoalsRequest, timeZone string, userID uuid.UUID) (*GetGoalsResponse, error)
+
+	ShareTask(ctx context.Context, taskID, userID uuid.UUID) (*GetTaskShareResponse, error)
+	UnshareTask(ctx context.Context, taskID, userID uuid.UUID) error
+	RenderSharedProgress(ctx context.Context, token string) (string, error)
+	RenderSharedBPMChart(ctx context.Context, token string) (string, error)
 
 	GetMediaUploadURL(ctx context.Context, taskID, mediaID uuid.UUID) (*GetUploadURLResponse, error)
-	ConfirmMediaUpload(ctx context.Context, req *ConfirmMediaUploadRequest, id uuid.UUID) (*GetMediaResponse, error)
+	ConfirmMediaUpload(ctx context.Context, req *ConfirmMediaUploadRequest, id, userID uuid.UUID) (*GetMediaResponse, error)
 	RemoveMedia(ctx context.Context, id uuid.UUID) error
 
 	SaveLink(ctx context.Context, req *SaveLinkRequest, taskID uuid.UUID) (*GetLinkResponse, error)
-	RemoveLink(ctx context.Context, id uuid.UUID) error
+	UpdateLink(ctx context.Context, req *SaveLinkRequest, id, userID uuid.UUID) (*GetLinkResponse, error)
+	RemoveLink(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) error
+
+	GetEnsembles(ctx context.Context, userID uuid.UUID) ([]GetEnsembleShortResponse, error)
+	GetEnsembleByID(ctx context.Context, id, userID uuid.UUID) (*GetEnsembleResponse, error)
+	CreateEnsemble(ctx context.Context, req *SaveEnsembleRequest, userID uuid.UUID) (*GetEnsembleResponse, error)
+	InviteEnsembleMember(ctx context.Context, req *InviteEnsembleMemberRequest, ensembleID, userID uuid.UUID) (*GetEnsembleInvitationResponse, error)
+	AcceptEnsembleInvitation(ctx context.Context, req *AcceptEnsembleInvitationRequest, userID uuid.UUID, email string) (*GetEnsembleResponse, error)
+
+	GetCharts(ctx context.Context, taskID, userID uuid.UUID) ([]GetChartShortResponse, error)
+	GetChartByID(ctx context.Context, req *RenderChartRequest, id, userID uuid.UUID) (*GetChartResponse, error)
+	CreateChart(ctx context.Context, req *SaveChartRequest, taskID, userID uuid.UUID) (*GetChartResponse, error)
+	UpdateChart(ctx context.Context, req *SaveChartRequest, id, userID uuid.UUID) (*GetChartResponse, error)
+	RemoveChart(ctx context.Context, id, userID uuid.UUID) error
+
+	GetJournalEntries(ctx context.Context, req *GetJournalEntriesRequest, userID uuid.UUID) ([]GetJournalEntryResponse, error)
+	GetJournalEntryByID(ctx context.Context, req *RenderJournalEntryRequest, id, userID uuid.UUID) (*GetJournalEntryResponse, error)
+	CreateJournalEntry(ctx context.Context, req *SaveJournalEntryRequest, userID uuid.UUID) (*GetJournalEntryResponse, error)
+	UpdateJournalEntry(ctx context.Context, req *SaveJournalEntryRequest, id, userID uuid.UUID) (*GetJournalEntryResponse, error)
+	RemoveJournalEntry(ctx context.Context, id, userID uuid.UUID) error
+
+	refreshTaskState(ctx context.Context, taskID uuid.UUID)
 }
 
 type service struct {
-	log         *slog.Logger
-	minio       *minio.Service
-	bucketName  string
-	taskRepo    TaskRepository
-	sessionRepo SessionRepository
-	mediaRepo   MediaRepository
-	linkRepo    LinkRepository
+	log          *slog.Logger
+	minio        *minio.Service
+	rabbitmq     *rabbitmq.Service
+	bucketName   string
+	taskRepo     TaskRepository
+	sessionRepo  SessionRepository
+	mediaRepo    MediaRepository
+	linkRepo     LinkRepository
+	ensembleRepo EnsembleRepository
+	scoreRepo    ScoreRepository
+	chartRepo    ChartRepository
+	syncRepo     SyncRepository
+	statsRepo    StatsRepository
+	goalRepo     GoalRepository
+	shareRepo    ShareRepository
+	journalRepo  JournalRepository
+	analytics    taskAnalytics
+}
+
+// taskAnalytics is the part of the analytics that the task endpoints show or
+// keep up to date.
+type taskAnalytics interface {
+	getTaskForecast(ctx context.Context, task *Task) *GetForecastResponse
+	getTrendsByTask(ctx context.Context, userID uuid.UUID) map[uuid.UUID]*TaskTrend
+	refreshTrend(ctx context.Context, task *Task)
+	invalidateReports(ctx context.Context, userID uuid.UUID)
 }
 
 func NewService(
 	log *slog.Logger,
 	minio *minio.Service,
+	rabbitmq *rabbitmq.Service,
+	analytics AnalyticsService,
 	taskRepo TaskRepository,
 	sessionRepo SessionRepository,
 	mediaRepo MediaRepository,
 	linkRepo LinkRepository,
+	ensembleRepo EnsembleRepository,
+	scoreRepo ScoreRepository,
+	chartRepo ChartRepository,
+	syncRepo SyncRepository,
+	statsRepo StatsRepository,
+	goalRepo GoalRepository,
+	shareRepo ShareRepository,
+	journalRepo JournalRepository,
 ) Service {
 	return &service{
-		log:         log,
-		taskRepo:    taskRepo,
-		minio:       minio,
-		bucketName:  "trackmus",
-		sessionRepo: sessionRepo,
-		mediaRepo:   mediaRepo,
-		linkRepo:    linkRepo,
+		log:          log,
+		taskRepo:     taskRepo,
+		minio:        minio,
+		rabbitmq:     rabbitmq,
+		bucketName:   "trackmus",
+		sessionRepo:  sessionRepo,
+		mediaRepo:    mediaRepo,
+		linkRepo:     linkRepo,
+		ensembleRepo: ensembleRepo,
+		scoreRepo:    scoreRepo,
+		chartRepo:    chartRepo,
+		syncRepo:     syncRepo,
+		statsRepo:    statsRepo,
+		goalRepo:     goalRepo,
+		shareRepo:    shareRepo,
+		journalRepo:  journalRepo,
+		analytics:    analytics,
 	}
 }
 
@@ -70,6 +153,8 @@ func (s *service) GetActiveTasks(ctx context.Context, userID uuid.UUID) ([]GetTa
 		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
 	}
 
+	trends := s.analytics.getTrendsByTask(ctx, userID)
+
 	result := make([]GetTaskShortResponse, 0)
 	for _, task := range tasks {
 		progress, err := s.getTaskProgress(ctx, &task)
@@ -77,6 +162,9 @@ func (s *service) GetActiveTasks(ctx context.Context, userID uuid.UUID) ([]GetTa
 
 		}
 		dto := TaskToGetShortResponse(&task, progress)
+		if trend, ok := trends[task.ID]; ok {
+			dto.Trend = TaskTrendToGetResponse(trend)
+		}
 
 		result = append(result, dto)
 	}
@@ -116,6 +204,11 @@ func (s *service) GetTaskByID(ctx context.Context, id uuid.UUID) (*GetTaskRespon
 		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
 	}
 
+	sections, tempoMarks, err := s.getScoreByTaskID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	sessions, err := s.getSessionsByTaskID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -131,7 +224,8 @@ func (s *service) GetTaskByID(ctx context.Context, id uuid.UUID) (*GetTaskRespon
 		return nil, err
 	}
 
-	result := TaskToGetResponse(task, sessions, media, links)
+	result := TaskToGetResponse(task, sections, tempoMarks, sessions, media, links)
+	result.Forecast = s.analytics.getTaskForecast(ctx, task)
 
 	return &result, nil
 }
@@ -154,11 +248,31 @@ func (s *service) CreateTask(ctx context.Context, req *SaveTaskRequest, userID u
 	return &result, nil
 }
 
-func (s *service) UpdateTask(ctx context.Context, req *SaveTaskRequest, id uuid.UUID) (*GetTaskResponse, error) {
+func (s *service) UpdateTask(ctx context.Context, req *SaveTaskRequest, id, userID uuid.UUID) (*GetTaskResponse, error) {
+	task, err := s.getOwnTask(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	model := SaveRequestToTask(req, id)
+	model.IsCompleted = task.IsCompleted
+	model.CompletedByRule = task.CompletedByRule
+	if req.CleanRepsGoal == nil {
+		model.CleanRepsGoal = task.CleanRepsGoal
+	}
+	if req.Tags == nil {
+		model.Tags = task.Tags
+	}
+	if req.DueDate == nil {
+		model.DueDate = task.DueDate
+	}
 
-	task, err := s.taskRepo.Update(ctx, &model)
+	_, err = s.taskRepo.Update(ctx, &model)
 	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("task version conflict", "id", id, "version", req.Version)
+			return nil, s.taskConflict(ctx, id)
+		}
 		s.log.Error("failed to save task in repository",
 			"req", req,
 			"id", id,
@@ -166,40 +280,31 @@ func (s *service) UpdateTask(ctx context.Context, req *SaveTaskRequest, id uuid.
 		)
 		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
 	}
+	s.analytics.invalidateReports(ctx, task.UserID)
 
-	sessions, err := s.getSessionsByTaskID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
-	media, err := s.getMediaByTaskID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
-	links, err := s.getLinksByTaskID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
-	result := TaskToGetResponse(task, sessions, media, links)
-
-	return &result, nil
+	return s.GetTaskByID(ctx, id)
 }
 
-func (s *service) CompleteTask(ctx context.Context, id uuid.UUID) (*GetTaskShortResponse, error) {
-	task, err := s.taskRepo.GetByID(ctx, id)
+func (s *service) CompleteTask(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) (*GetTaskShortResponse, error) {
+	task, err := s.getOwnTask(ctx, id, userID)
 	if err != nil {
-		s.log.Error("failed to get task from repository", "id", id, "error", err)
-		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		return nil, err
 	}
 
 	task.IsCompleted = true
+	task.CompletedByRule = false
+	task.Version = version
 
-	_, err = s.taskRepo.Update(ctx, task)
+	task, err = s.taskRepo.Update(ctx, task)
 	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("task version conflict", "id", id, "version", version)
+			return nil, s.taskConflict(ctx, id)
+		}
+		s.log.Error("failed to save task in repository", "id", id, "error", err)
 		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
 	}
+	s.analytics.invalidateReports(ctx, task.UserID)
 
 	progress, err := s.getTaskProgress(ctx, task)
 	if err != nil {
@@ -225,6 +330,9 @@ func (s *service) GetSessionByID(ctx context.Context, id uuid.UUID) (*GetSession
 
 func (s *service) CreateSession(ctx context.Context, req *SaveSessionRequest, taskID uuid.UUID) (*GetSessionResponse, error) {
 	model := SaveRequestToSession(req, taskID)
+	if err := s.checkSegmentSections(ctx, taskID, model.Segments); err != nil {
+		return nil, err
+	}
 
 	session, err := s.sessionRepo.Create(ctx, &model, taskID)
 	if err != nil {
@@ -235,12 +343,109 @@ func (s *service) CreateSession(ctx context.Context, req *SaveSessionRequest, ta
 		)
 		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
 	}
+	s.refreshTaskState(ctx, taskID)
+
+	result := SessionToGetResponse(session)
+
+	return &result, nil
+}
+
+func (s *service) UpdateSession(ctx context.Context, req *SaveSessionRequest, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	current, err := s.getOwnSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status != SessionStatusFinished {
+		s.log.Info("session is not finished yet", "id", id, "status", current.Status)
+		return nil, fmt.Errorf(errors.ErrSessionState)
+	}
+
+	model := SaveRequestToSession(req, current.TaskID)
+	model.ID = id
+	model.Pauses = current.Pauses
+	if req.Segments == nil {
+		model.Segments = current.Segments
+		model.ApplySegments()
+	}
+	if req.Reps == nil {
+		model.Reps = current.Reps
+	}
+	if err := s.checkSegmentSections(ctx, current.TaskID, model.Segments); err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.Update(ctx, &model)
+	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("session version conflict", "id", id, "version", req.Version)
+			return nil, s.sessionConflict(ctx, id)
+		}
+		s.log.Error("failed to update session in repository",
+			"req", req,
+			"id", id,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+	s.refreshTaskState(ctx, current.TaskID)
 
 	result := SessionToGetResponse(session)
 
 	return &result, nil
 }
 
+// AddSessionReps logs reps played during a session, e.g. one at a time while
+// the timer is running.
+func (s *service) AddSessionReps(ctx context.Context, req *AddRepsRequest, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	session, err := s.getOwnSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.AddReps(ctx, id, SaveRequestsToReps(req.Reps)); err != nil {
+		s.log.Error("failed to add session reps in repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+	s.refreshTaskState(ctx, session.TaskID)
+
+	return s.GetSessionByID(ctx, id)
+}
+
+// RemoveSession deletes a session and returns its task with the progress and
+// the derived state recalculated without it.
+func (s *service) RemoveSession(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) (*GetTaskShortResponse, error) {
+	session, err := s.getOwnSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.Delete(ctx, id, version); err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("session version conflict", "id", id, "version", version)
+			return nil, s.sessionConflict(ctx, id)
+		}
+		s.log.Error("failed to delete session from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToDeleteData)
+	}
+	s.refreshTaskState(ctx, session.TaskID)
+
+	task, err := s.taskRepo.GetByID(ctx, session.TaskID)
+	if err != nil {
+		s.log.Error("failed to get task from repository", "taskID", session.TaskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	progress, err := s.getTaskProgress(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	result := TaskToGetShortResponse(task, progress)
+
+	return &result, nil
+}
+
 func (s *service) GetMediaUploadURL(ctx context.Context, taskID, mediaID uuid.UUID) (*GetUploadURLResponse, error) {
 	s3Key := fmt.Sprintf("%s/%s", taskID, mediaID)
 
@@ -256,7 +461,11 @@ func (s *service) GetMediaUploadURL(ctx context.Context, taskID, mediaID uuid.UU
 	}, nil
 }
 
-func (s *service) ConfirmMediaUpload(ctx context.Context, req *ConfirmMediaUploadRequest, id uuid.UUID) (*GetMediaResponse, error) {
+func (s *service) ConfirmMediaUpload(ctx context.Context, req *ConfirmMediaUploadRequest, id, userID uuid.UUID) (*GetMediaResponse, error) {
+	if err := s.checkTaskOwner(ctx, req.TaskID, userID); err != nil {
+		return nil, err
+	}
+
 	model := ConfirmUploadRequestToMedia(req, id)
 
 	media, err := s.mediaRepo.Create(ctx, &model)
@@ -328,9 +537,46 @@ func (s *service) SaveLink(ctx context.Context, req *SaveLinkRequest, taskID uui
 	return &dto, nil
 }
 
-func (s *service) RemoveLink(ctx context.Context, id uuid.UUID) error {
-	err := s.linkRepo.Delete(ctx, id)
+func (s *service) UpdateLink(ctx context.Context, req *SaveLinkRequest, id, userID uuid.UUID) (*GetLinkResponse, error) {
+	current, err := s.getOwnLink(ctx, id, userID)
 	if err != nil {
+		return nil, err
+	}
+
+	model := SaveRequestToLink(req, current.TaskID)
+	model.ID = id
+	model.CreatedAt = current.CreatedAt
+
+	link, err := s.linkRepo.Update(ctx, &model)
+	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("link version conflict", "id", id, "version", req.Version)
+			return nil, s.linkConflict(ctx, id)
+		}
+		s.log.Error("failed to update link in repository",
+			"req", req,
+			"id", id,
+			"error", err,
+		)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	dto := LinkToGetResponse(link)
+
+	return &dto, nil
+}
+
+func (s *service) RemoveLink(ctx context.Context, id uuid.UUID, version int, userID uuid.UUID) error {
+	if _, err := s.getOwnLink(ctx, id, userID); err != nil {
+		return err
+	}
+
+	err := s.linkRepo.Delete(ctx, id, version)
+	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("link version conflict", "id", id, "version", version)
+			return s.linkConflict(ctx, id)
+		}
 		s.log.Error("failed to delete link in repository", "id", id, "error", err)
 		return fmt.Errorf(errors.ErrFailedToDeleteData)
 	}
@@ -338,6 +584,46 @@ func (s *service) RemoveLink(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (s *service) getOwnLink(ctx context.Context, id, userID uuid.UUID) (*Link, error) {
+	link, err := s.linkRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get link from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if err := s.checkTaskOwner(ctx, link.TaskID, userID); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (s *service) getScoreByTaskID(ctx context.Context, taskID uuid.UUID) ([]GetSectionResponse, []GetTempoMarkResponse, error) {
+	sections, err := s.scoreRepo.GetSectionsByTaskID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get sections from repository", "taskID", taskID, "error", err)
+		return nil, nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	marks, err := s.scoreRepo.GetTempoMarksByTaskID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get tempo marks from repository", "taskID", taskID, "error", err)
+		return nil, nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	sectionsResult := make([]GetSectionResponse, 0)
+	for _, section := range sections {
+		sectionsResult = append(sectionsResult, SectionToGetResponse(&section))
+	}
+
+	marksResult := make([]GetTempoMarkResponse, 0)
+	for _, mark := range marks {
+		marksResult = append(marksResult, TempoMarkToGetResponse(&mark))
+	}
+
+	return sectionsResult, marksResult, nil
+}
+
 func (s *service) getSessionsByTaskID(ctx context.Context, taskID uuid.UUID) ([]GetSessionResponse, error) {
 	sessions, err := s.sessionRepo.GetByTaskID(ctx, taskID)
 	if err != nil {
@@ -437,6 +723,146 @@ func (s *service) getLinksByTaskID(ctx context.Context, taskID uuid.UUID) ([]Get
 	return result, nil
 }
 
+// refreshTaskState brings the data derived from the sessions of the task up
+// to date after they were created, changed or deleted: the cached reports,
+// the completion and the trend flag. The sessions are
+// already saved at this point, so failures are only logged.
+func (s *service) refreshTaskState(ctx context.Context, taskID uuid.UUID) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get task from repository", "taskID", taskID, "error", err)
+		return
+	}
+
+	s.analytics.invalidateReports(ctx, task.UserID)
+	s.applyCompletionRule(ctx, task)
+	s.analytics.refreshTrend(ctx, task)
+}
+
+// applyCompletionRule completes the task once a session meets its clean reps
+// goal and reopens a task it completed when no session meets the goal any
+// more. Tasks completed by hand are left alone.
+func (s *service) applyCompletionRule(ctx context.Context, task *Task) {
+	if task.CleanRepsGoal <= 0 || (task.IsCompleted && !task.CompletedByRule) {
+		return
+	}
+
+	sessions, err := s.sessionRepo.GetByTaskID(ctx, task.ID)
+	if err != nil {
+		s.log.Error("failed to load sessions from repository", "taskID", task.ID, "error", err)
+		return
+	}
+
+	var completedBy *Session
+	for i := range sessions {
+		if task.IsCompletedBy(&sessions[i]) {
+			completedBy = &sessions[i]
+			break
+		}
+	}
+
+	if (completedBy != nil) == task.IsCompleted {
+		return
+	}
+
+	task.IsCompleted = completedBy != nil
+	task.CompletedByRule = task.IsCompleted
+	if _, err := s.taskRepo.Update(ctx, task); err != nil {
+		s.log.Error("failed to update task completion in repository", "taskID", task.ID, "error", err)
+		return
+	}
+
+	if completedBy != nil {
+		s.log.Info("task completed by clean reps goal", "taskID", task.ID, "sessionID", completedBy.ID)
+	} else {
+		s.log.Info("task reopened, no session meets the clean reps goal", "taskID", task.ID)
+	}
+}
+
+// checkSegmentSections makes sure the segments only refer to sections of the
+// session's task.
+func (s *service) checkSegmentSections(ctx context.Context, taskID uuid.UUID, segments []SessionSegment) error {
+	var sections []Section
+	for _, segment := range segments {
+		if segment.SectionID == nil {
+			continue
+		}
+
+		if sections == nil {
+			var err error
+			sections, err = s.scoreRepo.GetSectionsByTaskID(ctx, taskID)
+			if err != nil {
+				s.log.Error("failed to get sections from repository", "taskID", taskID, "error", err)
+				return fmt.Errorf(errors.ErrFailedToLoadData)
+			}
+		}
+
+		found := false
+		for _, section := range sections {
+			found = found || section.ID == *segment.SectionID
+		}
+		if !found {
+			s.log.Info("segment refers to a section of another task", "taskID", taskID, "sectionID", segment.SectionID)
+			return fmt.Errorf(errors.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// checkTaskOwner makes sure the task belongs to the user before its nested
+// resources are read or changed.
+func (s *service) checkTaskOwner(ctx context.Context, taskID, userID uuid.UUID) error {
+	_, err := s.getOwnTask(ctx, taskID, userID)
+
+	return err
+}
+
+func (s *service) getOwnTask(ctx context.Context, id, userID uuid.UUID) (*Task, error) {
+	task, err := s.taskRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get task from repository", "taskID", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if task.UserID != userID {
+		s.log.Warn("task belongs to another user", "taskID", id, "userID", userID)
+		return nil, fmt.Errorf(errors.ErrAccessDenied)
+	}
+
+	return task, nil
+}
+
+func (s *service) taskConflict(ctx context.Context, id uuid.UUID) error {
+	current, err := s.GetTaskByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return &errors.ConflictError{Current: current}
+}
+
+func (s *service) sessionConflict(ctx context.Context, id uuid.UUID) error {
+	current, err := s.GetSessionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return &errors.ConflictError{Current: current}
+}
+
+func (s *service) linkConflict(ctx context.Context, id uuid.UUID) error {
+	link, err := s.linkRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get link from repository", "id", id, "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	current := LinkToGetResponse(link)
+
+	return &errors.ConflictError{Current: &current}
+}
+
 func (s *service) getTaskProgress(ctx context.Context, task *Task) (float64, error) {
 	sessions, err := s.sessionRepo.GetByTaskID(ctx, task.ID)
 	if err != nil {
@@ -444,10 +870,12 @@ func (s *service) getTaskProgress(ctx context.Context, task *Task) (float64, err
 		return 0, fmt.Errorf(errors.ErrFailedToLoadData)
 	}
 
-	if len(sessions) == 0 {
-		return 0, nil
-	}
+	return taskProgress(task, sessions), nil
+}
 
+// taskProgress scores the best finished attempt of the task against its
+// target tempo and full confidence.
+func taskProgress(task *Task, sessions []Session) float64 {
 	const (
 		bw = 0.4
 		cw = 0.6
@@ -456,17 +884,50 @@ func (s *service) getTaskProgress(ctx context.Context, task *Task) (float64, err
 	bestWeightedSum := -1.0
 
 	for _, s := range sessions {
-		bprogress := tanhProgress(float64(s.BPM), float64(task.TargetBPM))
-		cprogress := tanhProgress(float64(s.Confidence), 5.0)
+		if s.Status != SessionStatusFinished {
+			continue
+		}
+
+		for _, attempt := range sessionAttempts(&s) {
+			bprogress := tanhProgress(float64(attempt.BPM), float64(task.TargetBPM))
+			cprogress := tanhProgress(float64(attempt.Confidence), 5.0)
 
-		weightedSum := bw*bprogress + cw*cprogress
+			weightedSum := bw*bprogress + cw*cprogress
 
-		if weightedSum > bestWeightedSum {
-			bestWeightedSum = weightedSum
+			if weightedSum > bestWeightedSum {
+				bestWeightedSum = weightedSum
+			}
 		}
 	}
 
-	return math.Min(bestWeightedSum, 100), nil
+	if bestWeightedSum < 0 {
+		return 0
+	}
+
+	return math.Min(bestWeightedSum, 100)
+}
+
+// sessionAttempts returns the tempo and confidence pairs played in a session:
+// every segment, or the session itself when it has none. Logged reps are a
+// measured result, so their clean share replaces the self-rated confidence.
+func sessionAttempts(session *Session) []SessionSegment {
+	attempts := session.Segments
+	if len(attempts) == 0 {
+		attempts = []SessionSegment{{BPM: session.BPM, Confidence: session.Confidence}}
+	}
+
+	confidence, ok := session.RepConfidence()
+	if !ok {
+		return attempts
+	}
+
+	result := make([]SessionSegment, 0, len(attempts))
+	for _, attempt := range attempts {
+		attempt.Confidence = int(math.Round(confidence))
+		result = append(result, attempt)
+	}
+
+	return result
 }
 
 func tanhProgress(current, target float64) float64 {
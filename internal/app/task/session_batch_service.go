@@ -0,0 +1,98 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// CreateSessionsBatch stores sessions uploaded by an offline client. Every
+// item is saved on its own, so one bad item does not reject the whole
+// backlog; the result of each item is reported under its client id.
+func (s *service) CreateSessionsBatch(ctx context.Context, items []BatchSessionItemRequest, userID uuid.UUID) []BatchSessionResult {
+	results := make([]BatchSessionResult, 0, len(items))
+	owned := make(map[uuid.UUID]error)
+	changed := make(map[uuid.UUID]bool)
+
+	for _, item := range items {
+		result := BatchSessionResult{ClientID: item.ClientID}
+
+		err, checked := owned[item.TaskID]
+		if !checked {
+			err = s.checkTaskOwner(ctx, item.TaskID, userID)
+			owned[item.TaskID] = err
+		}
+		if err != nil {
+			result.Status = string(BatchItemForbidden)
+			if err.Error() != errors.ErrAccessDenied {
+				result.Status = string(BatchItemFailed)
+			}
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		id, status, err := s.createBatchSession(ctx, &item)
+		result.Status = string(status)
+		if err != nil {
+			result.Message = err.Error()
+		} else {
+			result.ID = id.String()
+		}
+		if status == BatchItemCreated {
+			changed[item.TaskID] = true
+		}
+
+		results = append(results, result)
+	}
+
+	for taskID := range changed {
+		s.refreshTaskState(ctx, taskID)
+	}
+
+	return results
+}
+
+func (s *service) createBatchSession(ctx context.Context, item *BatchSessionItemRequest) (*uuid.UUID, BatchItemStatus, error) {
+	existing, err := s.sessionRepo.GetByClientID(ctx, item.TaskID, item.ClientID)
+	if err != nil {
+		s.log.Error("failed to get session by client id from repository",
+			"taskID", item.TaskID,
+			"clientID", item.ClientID,
+			"error", err,
+		)
+		return nil, BatchItemFailed, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if existing != nil {
+		return existing, BatchItemDuplicate, nil
+	}
+
+	model := SaveRequestToSession(&item.SaveSessionRequest, item.TaskID)
+	model.ClientID = item.ClientID
+	if err := s.checkSegmentSections(ctx, item.TaskID, model.Segments); err != nil {
+		return nil, BatchItemInvalid, err
+	}
+
+	session, err := s.sessionRepo.Create(ctx, &model, item.TaskID)
+	if err != nil && isUniqueViolation(err) {
+		// A concurrent upload of the same item won the race between the
+		// lookup above and the insert.
+		existing, err := s.sessionRepo.GetByClientID(ctx, item.TaskID, item.ClientID)
+		if err == nil && existing != nil {
+			return existing, BatchItemDuplicate, nil
+		}
+	}
+	if err != nil {
+		s.log.Error("failed to create session in repository",
+			"taskID", item.TaskID,
+			"clientID", item.ClientID,
+			"error", err,
+		)
+		return nil, BatchItemFailed, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	return &session.ID, BatchItemCreated, nil
+}
@@ -2,9 +2,14 @@ package task
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"time"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,6 +17,20 @@ type SessionRepository interface {
 	GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Session, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Session, error)
 	Create(ctx context.Context, session *Session, taskID uuid.UUID) (*Session, error)
+	CreateMany(ctx context.Context, sessions []Session) (int64, error)
+	Update(ctx context.Context, session *Session) (*Session, error)
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+	AddReps(ctx context.Context, sessionID uuid.UUID, reps []SessionRep) error
+	GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Session, error)
+
+	GetByClientID(ctx context.Context, taskID uuid.UUID, clientID string) (*uuid.UUID, error)
+	GetRunningByTaskID(ctx context.Context, taskID uuid.UUID) (*Session, error)
+	Start(ctx context.Context, taskID uuid.UUID, at time.Time) (*Session, error)
+	Pause(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	Resume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	Heartbeat(ctx context.Context, id uuid.UUID, at time.Time) (bool, error)
+	Finish(ctx context.Context, session *Session, at time.Time) (bool, error)
+	CloseAbandoned(ctx context.Context, before time.Time) (int64, error)
 }
 
 type sessionRepository struct {
@@ -24,7 +43,8 @@ func NewSessionRepository(pool *pgxpool.Pool) SessionRepository {
 
 func (r *sessionRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) ([]Session, error) {
 	query := `
-		SELECT id, bpm, note, confidence, start_time, end_time
+		SELECT id, task_id, COALESCE(bpm, 0), COALESCE(note, ''), COALESCE(confidence, 0), COALESCE(pain, 0), COALESCE(tension, 0), start_time,
+			COALESCE(end_time, start_time), status, COALESCE(last_heartbeat_at, start_time), version
 		FROM sessions
 		WHERE task_id = $1
 	`
@@ -40,11 +60,17 @@ func (r *sessionRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) (
 		var session Session
 		err := rows.Scan(
 			&session.ID,
+			&session.TaskID,
 			&session.BPM,
 			&session.Note,
 			&session.Confidence,
+			&session.Pain,
+			&session.Tension,
 			&session.StartTime,
 			&session.EndTime,
+			&session.Status,
+			&session.LastHeartbeatAt,
+			&session.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
@@ -52,13 +78,23 @@ func (r *sessionRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) (
 
 		sessions = append(sessions, session)
 	}
+	rows.Close()
+
+	refs := make([]*Session, 0, len(sessions))
+	for i := range sessions {
+		refs = append(refs, &sessions[i])
+	}
+	if err := r.loadDetails(ctx, refs); err != nil {
+		return nil, err
+	}
 
 	return sessions, nil
 }
 
 func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*Session, error) {
 	query := `
-		SELECT id, bpm, note, confidence, start_time, end_time
+		SELECT id, task_id, COALESCE(bpm, 0), COALESCE(note, ''), COALESCE(confidence, 0), COALESCE(pain, 0), COALESCE(tension, 0), start_time,
+			COALESCE(end_time, start_time), status, COALESCE(last_heartbeat_at, start_time), version
 		FROM sessions
 		WHERE id = $1
 	`
@@ -70,28 +106,44 @@ func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*Session
 		id,
 	).Scan(
 		&session.ID,
+		&session.TaskID,
 		&session.BPM,
 		&session.Note,
 		&session.Confidence,
+		&session.Pain,
+		&session.Tension,
 		&session.StartTime,
 		&session.EndTime,
+		&session.Status,
+		&session.LastHeartbeatAt,
+		&session.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan session: %w", err)
 	}
 
+	if err := r.loadDetails(ctx, []*Session{&session}); err != nil {
+		return nil, err
+	}
+
 	return &session, nil
 }
 
 func (r *sessionRepository) Create(ctx context.Context, session *Session, taskID uuid.UUID) (*Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO sessions(task_id, bpm, note, confidence, start_time, end_time)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		INSERT INTO sessions(task_id, bpm, note, confidence, start_time, end_time, client_id, pain, tension)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, 0), NULLIF($9, 0))
+		RETURNING id, status, version
 	`
 
 	var id uuid.UUID
-	err := r.pool.QueryRow(
+	err = tx.QueryRow(
 		ctx,
 		query,
 		taskID,
@@ -100,14 +152,760 @@ func (r *sessionRepository) Create(ctx context.Context, session *Session, taskID
 		session.Confidence,
 		session.StartTime,
 		session.EndTime,
+		session.ClientID,
+		session.Pain,
+		session.Tension,
 	).Scan(
 		&id,
+		&session.Status,
+		&session.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
 	session.ID = id
+	session.TaskID = taskID
+
+	if err := replaceSegments(ctx, tx, session.ID, session.Segments); err != nil {
+		return nil, err
+	}
+
+	if err := replaceReps(ctx, tx, session.ID, session.Reps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return session, nil
+}
+
+func (r *sessionRepository) Update(ctx context.Context, session *Session) (*Session, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE sessions
+		SET bpm = $2,
+			note = $3,
+			confidence = $4,
+			start_time = $5,
+			end_time = $6,
+			pain = NULLIF($8, 0),
+			tension = NULLIF($9, 0),
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $7
+	`
+
+	tag, err := tx.Exec(
+		ctx,
+		query,
+		session.ID,
+		session.BPM,
+		session.Note,
+		session.Confidence,
+		session.StartTime,
+		session.EndTime,
+		session.Version,
+		session.Pain,
+		session.Tension,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, errors.ErrVersionConflict
+	}
+
+	if err := replaceSegments(ctx, tx, session.ID, session.Segments); err != nil {
+		return nil, err
+	}
+
+	if err := replaceReps(ctx, tx, session.ID, session.Reps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	session.Version++
 
 	return session, nil
 }
+
+func (r *sessionRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM sessions
+			WHERE id = $1 AND version = $2
+			RETURNING id, task_id
+		)
+		INSERT INTO tombstones(entity_type, entity_id, task_id)
+		SELECT 'session', id, task_id
+		FROM deleted
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// AddReps appends reps to the ones already logged for the session and bumps
+// its version, so that a client holding the old one gets a conflict.
+func (r *sessionRepository) AddReps(ctx context.Context, sessionID uuid.UUID, reps []SessionRep) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT COALESCE(MAX(position) + 1, 0)
+		FROM session_reps
+		WHERE session_id = $1
+	`
+
+	var next int
+	if err := tx.QueryRow(ctx, query, sessionID).Scan(&next); err != nil {
+		return fmt.Errorf("failed to get next rep position: %w", err)
+	}
+
+	for i := range reps {
+		reps[i].Position = next + i
+	}
+
+	if err := insertReps(ctx, tx, sessionID, reps); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE sessions
+		SET version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to update session version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetChangedSince returns the sessions of the user's tasks created or updated
+// after since.
+func (r *sessionRepository) GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Session, error) {
+	query := `
+		SELECT s.id, s.task_id, COALESCE(s.bpm, 0), COALESCE(s.note, ''), COALESCE(s.confidence, 0), COALESCE(s.pain, 0), COALESCE(s.tension, 0), s.start_time,
+			COALESCE(s.end_time, s.start_time), s.status, COALESCE(s.last_heartbeat_at, s.start_time), s.version, s.updated_at
+		FROM sessions s
+		JOIN tasks t ON t.id = s.task_id
+		WHERE t.user_id = $1 AND s.updated_at > $2
+		ORDER BY s.updated_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.TaskID,
+			&session.BPM,
+			&session.Note,
+			&session.Confidence,
+			&session.Pain,
+			&session.Tension,
+			&session.StartTime,
+			&session.EndTime,
+			&session.Status,
+			&session.LastHeartbeatAt,
+			&session.Version,
+			&session.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		sessions = append(sessions, session)
+	}
+	rows.Close()
+
+	refs := make([]*Session, 0, len(sessions))
+	for i := range sessions {
+		refs = append(refs, &sessions[i])
+	}
+	if err := r.loadDetails(ctx, refs); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// GetByClientID returns the id of the session uploaded with the client-side
+// id, or nil when there is none.
+func (r *sessionRepository) GetByClientID(ctx context.Context, taskID uuid.UUID, clientID string) (*uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM sessions
+		WHERE task_id = $1 AND client_id = $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var id uuid.UUID
+	if err := rows.Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+
+	return &id, nil
+}
+
+// isUniqueViolation reports whether the query failed on a unique index, such
+// as a session uploaded twice with the same client-side id.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return stderrors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// GetRunningByTaskID returns the open or paused session of the task, or nil
+// when the timer is not running.
+func (r *sessionRepository) GetRunningByTaskID(ctx context.Context, taskID uuid.UUID) (*Session, error) {
+	query := `
+		SELECT id
+		FROM sessions
+		WHERE task_id = $1 AND status IN ('open', 'paused')
+		ORDER BY start_time DESC
+		LIMIT 1
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var id uuid.UUID
+	if err := rows.Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+	rows.Close()
+
+	return r.GetByID(ctx, id)
+}
+
+func (r *sessionRepository) Start(ctx context.Context, taskID uuid.UUID, at time.Time) (*Session, error) {
+	query := `
+		INSERT INTO sessions(task_id, start_time, last_heartbeat_at, status)
+		VALUES ($1, $2, $2, 'open')
+		RETURNING id, version
+	`
+
+	session := Session{
+		TaskID:          taskID,
+		StartTime:       at,
+		EndTime:         at,
+		LastHeartbeatAt: at,
+		Status:          SessionStatusOpen,
+	}
+	err := r.pool.QueryRow(ctx, query, taskID, at).Scan(&session.ID, &session.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Pause stops the timer of an open session. It reports false when the session
+// is not open.
+func (r *sessionRepository) Pause(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE sessions
+		SET status = 'paused',
+			last_heartbeat_at = $2,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`
+
+	tag, err := tx.Exec(ctx, query, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to pause session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO session_pauses(session_id, started_at)
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(ctx, query, id, at); err != nil {
+		return false, fmt.Errorf("failed to create session pause: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// Resume restarts the timer of a paused session. It reports false when the
+// session is not paused.
+func (r *sessionRepository) Resume(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE sessions
+		SET status = 'open',
+			last_heartbeat_at = $2,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'paused'
+	`
+
+	tag, err := tx.Exec(ctx, query, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to resume session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := closePauses(ctx, tx, id, at); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// Heartbeat marks a running session as alive. It does not change the version,
+// so the client may send it as often as it needs.
+func (r *sessionRepository) Heartbeat(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET last_heartbeat_at = $2
+		WHERE id = $1 AND status IN ('open', 'paused')
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to update session heartbeat: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Finish stores the results of a running or abandoned session and stops its
+// timer. An abandoned session keeps the end time it was closed with.
+func (r *sessionRepository) Finish(ctx context.Context, session *Session, at time.Time) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE sessions
+		SET status = 'finished',
+			bpm = $2,
+			note = $3,
+			confidence = $4,
+			end_time = COALESCE(end_time, $5),
+			pain = NULLIF($6, 0),
+			tension = NULLIF($7, 0),
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND status IN ('open', 'paused', 'abandoned')
+	`
+
+	tag, err := tx.Exec(
+		ctx,
+		query,
+		session.ID,
+		session.BPM,
+		session.Note,
+		session.Confidence,
+		at,
+		session.Pain,
+		session.Tension,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish session: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := closePauses(ctx, tx, session.ID, at); err != nil {
+		return false, err
+	}
+
+	if err := replaceSegments(ctx, tx, session.ID, session.Segments); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateMany inserts finished sessions in one statement. Sessions whose
+// client id is already taken within their task are skipped; the number of
+// inserted sessions is returned.
+func (r *sessionRepository) CreateMany(ctx context.Context, sessions []Session) (int64, error) {
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	taskIDs := make([]uuid.UUID, 0, len(sessions))
+	bpms := make([]int, 0, len(sessions))
+	notes := make([]string, 0, len(sessions))
+	confidences := make([]int, 0, len(sessions))
+	startTimes := make([]time.Time, 0, len(sessions))
+	endTimes := make([]time.Time, 0, len(sessions))
+	clientIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		taskIDs = append(taskIDs, session.TaskID)
+		bpms = append(bpms, session.BPM)
+		notes = append(notes, session.Note)
+		confidences = append(confidences, session.Confidence)
+		startTimes = append(startTimes, session.StartTime)
+		endTimes = append(endTimes, session.EndTime)
+		clientIDs = append(clientIDs, session.ClientID)
+	}
+
+	query := `
+		INSERT INTO sessions(task_id, bpm, note, confidence, start_time, end_time, client_id)
+		SELECT task_id, NULLIF(bpm, 0), NULLIF(note, ''), NULLIF(confidence, 0), start_time, end_time, NULLIF(client_id, '')
+		FROM unnest($1::UUID[], $2::INT[], $3::TEXT[], $4::INT[], $5::TIMESTAMPTZ[], $6::TIMESTAMPTZ[], $7::VARCHAR[])
+			AS s(task_id, bpm, note, confidence, start_time, end_time, client_id)
+		ON CONFLICT (task_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+	`
+
+	tag, err := r.pool.Exec(ctx, query, taskIDs, bpms, notes, confidences, startTimes, endTimes, clientIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// CloseAbandoned closes the running sessions without a heartbeat since
+// before. They end at their last heartbeat and wait for the user to fill in
+// the results.
+func (r *sessionRepository) CloseAbandoned(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE session_pauses p
+		SET ended_at = GREATEST(p.started_at, s.last_heartbeat_at)
+		FROM sessions s
+		WHERE p.session_id = s.id
+			AND p.ended_at IS NULL
+			AND s.status IN ('open', 'paused')
+			AND s.last_heartbeat_at < $1
+	`
+
+	if _, err := tx.Exec(ctx, query, before); err != nil {
+		return 0, fmt.Errorf("failed to close session pauses: %w", err)
+	}
+
+	query = `
+		UPDATE sessions
+		SET status = 'abandoned',
+			end_time = last_heartbeat_at,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE status IN ('open', 'paused') AND last_heartbeat_at < $1
+	`
+
+	tag, err := tx.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close abandoned sessions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// loadDetails attaches pauses, tempo segments and reps to the sessions.
+func (r *sessionRepository) loadDetails(ctx context.Context, sessions []*Session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(sessions))
+	byID := make(map[uuid.UUID]*Session, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+		byID[s.ID] = s
+	}
+
+	if err := r.loadPauses(ctx, ids, byID); err != nil {
+		return err
+	}
+
+	if err := r.loadSegments(ctx, ids, byID); err != nil {
+		return err
+	}
+
+	return r.loadReps(ctx, ids, byID)
+}
+
+func (r *sessionRepository) loadPauses(ctx context.Context, ids []uuid.UUID, byID map[uuid.UUID]*Session) error {
+	query := `
+		SELECT id, session_id, started_at, ended_at
+		FROM session_pauses
+		WHERE session_id = ANY($1)
+		ORDER BY started_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pause SessionPause
+		err := rows.Scan(
+			&pause.ID,
+			&pause.SessionID,
+			&pause.StartedAt,
+			&pause.EndedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan session pause: %w", err)
+		}
+
+		session := byID[pause.SessionID]
+		session.Pauses = append(session.Pauses, pause)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) loadSegments(ctx context.Context, ids []uuid.UUID, byID map[uuid.UUID]*Session) error {
+	query := `
+		SELECT id, session_id, section_id, position, bpm, COALESCE(duration, 0), COALESCE(reps, 0), confidence
+		FROM session_segments
+		WHERE session_id = ANY($1)
+		ORDER BY position
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var segment SessionSegment
+		err := rows.Scan(
+			&segment.ID,
+			&segment.SessionID,
+			&segment.SectionID,
+			&segment.Position,
+			&segment.BPM,
+			&segment.Duration,
+			&segment.Reps,
+			&segment.Confidence,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan session segment: %w", err)
+		}
+
+		session := byID[segment.SessionID]
+		session.Segments = append(session.Segments, segment)
+	}
+
+	return nil
+}
+
+// replaceSegments stores the segments of a session instead of the existing
+// ones.
+func replaceSegments(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, segments []SessionSegment) error {
+	query := `
+		DELETE FROM session_segments
+		WHERE session_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session segments: %w", err)
+	}
+
+	query = `
+		INSERT INTO session_segments(session_id, section_id, position, bpm, duration, reps, confidence)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7)
+	`
+
+	for i := range segments {
+		segments[i].SessionID = sessionID
+		_, err := tx.Exec(
+			ctx,
+			query,
+			sessionID,
+			segments[i].SectionID,
+			segments[i].Position,
+			segments[i].BPM,
+			segments[i].Duration,
+			segments[i].Reps,
+			segments[i].Confidence,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create session segment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) loadReps(ctx context.Context, ids []uuid.UUID, byID map[uuid.UUID]*Session) error {
+	query := `
+		SELECT id, session_id, position, clean, played_at
+		FROM session_reps
+		WHERE session_id = ANY($1)
+		ORDER BY position
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rep SessionRep
+		err := rows.Scan(
+			&rep.ID,
+			&rep.SessionID,
+			&rep.Position,
+			&rep.Clean,
+			&rep.PlayedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan session rep: %w", err)
+		}
+
+		session := byID[rep.SessionID]
+		session.Reps = append(session.Reps, rep)
+	}
+
+	return nil
+}
+
+func replaceReps(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, reps []SessionRep) error {
+	query := `
+		DELETE FROM session_reps
+		WHERE session_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session reps: %w", err)
+	}
+
+	return insertReps(ctx, tx, sessionID, reps)
+}
+
+func insertReps(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, reps []SessionRep) error {
+	query := `
+		INSERT INTO session_reps(session_id, position, clean, played_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	for i := range reps {
+		reps[i].SessionID = sessionID
+		_, err := tx.Exec(
+			ctx,
+			query,
+			sessionID,
+			reps[i].Position,
+			reps[i].Clean,
+			reps[i].PlayedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create session rep: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func closePauses(ctx context.Context, tx pgx.Tx, sessionID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE session_pauses
+		SET ended_at = GREATEST(started_at, $2)
+		WHERE session_id = $1 AND ended_at IS NULL
+	`
+
+	if _, err := tx.Exec(ctx, query, sessionID, at); err != nil {
+		return fmt.Errorf("failed to close session pause: %w", err)
+	}
+
+	return nil
+}
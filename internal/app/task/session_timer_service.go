@@ -0,0 +1,129 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// StartSession starts the server-side timer for the task. When the timer is
+// already running, the running session is returned, so a retried request does
+// not open a second one.
+func (s *service) StartSession(ctx context.Context, taskID, userID uuid.UUID) (*GetSessionResponse, error) {
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.GetRunningByTaskID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get running session from repository", "taskID", taskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if session == nil {
+		session, err = s.sessionRepo.Start(ctx, taskID, time.Now())
+		if err != nil {
+			s.log.Error("failed to start session in repository", "taskID", taskID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+		}
+	}
+
+	result := SessionToGetResponse(session)
+
+	return &result, nil
+}
+
+func (s *service) PauseSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	return s.changeSessionState(ctx, id, userID, "pause", s.sessionRepo.Pause)
+}
+
+func (s *service) ResumeSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	return s.changeSessionState(ctx, id, userID, "resume", s.sessionRepo.Resume)
+}
+
+func (s *service) HeartbeatSession(ctx context.Context, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	return s.changeSessionState(ctx, id, userID, "heartbeat", s.sessionRepo.Heartbeat)
+}
+
+func (s *service) FinishSession(ctx context.Context, req *FinishSessionRequest, id, userID uuid.UUID) (*GetSessionResponse, error) {
+	session, err := s.getOwnSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	model := FinishRequestToSession(req, id)
+	if err := s.checkSegmentSections(ctx, session.TaskID, model.Segments); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.sessionRepo.Finish(ctx, &model, time.Now())
+	if err != nil {
+		s.log.Error("failed to finish session in repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	if !ok {
+		s.log.Info("session is already finished", "id", id)
+		return nil, fmt.Errorf(errors.ErrSessionState)
+	}
+	s.refreshTaskState(ctx, session.TaskID)
+
+	return s.GetSessionByID(ctx, id)
+}
+
+// CloseAbandonedSessions closes the sessions whose client stopped sending
+// heartbeats, e.g. because the app was killed mid-practice.
+func (s *service) CloseAbandonedSessions(ctx context.Context, timeout time.Duration) (int64, error) {
+	closed, err := s.sessionRepo.CloseAbandoned(ctx, time.Now().Add(-timeout))
+	if err != nil {
+		s.log.Error("failed to close abandoned sessions in repository", "error", err)
+		return 0, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	if closed > 0 {
+		s.log.Info("abandoned sessions closed", "count", closed)
+	}
+
+	return closed, nil
+}
+
+func (s *service) changeSessionState(
+	ctx context.Context,
+	id, userID uuid.UUID,
+	action string,
+	change func(ctx context.Context, id uuid.UUID, at time.Time) (bool, error),
+) (*GetSessionResponse, error) {
+	if _, err := s.getOwnSession(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	ok, err := change(ctx, id, time.Now())
+	if err != nil {
+		s.log.Error("failed to change session state in repository", "id", id, "action", action, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	if !ok {
+		s.log.Info("session state does not allow action", "id", id, "action", action)
+		return nil, fmt.Errorf(errors.ErrSessionState)
+	}
+
+	return s.GetSessionByID(ctx, id)
+}
+
+func (s *service) getOwnSession(ctx context.Context, id, userID uuid.UUID) (*Session, error) {
+	session, err := s.sessionRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get session from repository", "id", id, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if err := s.checkTaskOwner(ctx, session.TaskID, userID); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
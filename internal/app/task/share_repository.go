@@ -0,0 +1,98 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShareRepository interface {
+	GetByTaskID(ctx context.Context, taskID uuid.UUID) (*TaskShare, error)
+	GetByToken(ctx context.Context, token string) (*TaskShare, error)
+	Create(ctx context.Context, model *TaskShare) (*TaskShare, error)
+	Delete(ctx context.Context, taskID uuid.UUID) error
+}
+
+type shareRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewShareRepository(pool *pgxpool.Pool) ShareRepository {
+	return &shareRepository{pool}
+}
+
+// GetByTaskID returns the share of the task, or nil when it is not shared.
+func (r *shareRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) (*TaskShare, error) {
+	query := `
+		SELECT task_id, token, created_at
+		FROM task_shares
+		WHERE task_id = $1
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, taskID))
+}
+
+// GetByToken returns the share with the token, or nil when there is none.
+func (r *shareRepository) GetByToken(ctx context.Context, token string) (*TaskShare, error) {
+	query := `
+		SELECT task_id, token, created_at
+		FROM task_shares
+		WHERE token = $1
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, token))
+}
+
+func (r *shareRepository) Create(ctx context.Context, model *TaskShare) (*TaskShare, error) {
+	query := `
+		INSERT INTO task_shares(task_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (task_id) DO NOTHING
+		RETURNING created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, model.TaskID, model.Token).Scan(&model.CreatedAt)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		// Shared concurrently by another request, keep its token.
+		return r.GetByTaskID(ctx, model.TaskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task share: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *shareRepository) Delete(ctx context.Context, taskID uuid.UUID) error {
+	query := `
+		DELETE FROM task_shares
+		WHERE task_id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, taskID); err != nil {
+		return fmt.Errorf("failed to delete task share: %w", err)
+	}
+
+	return nil
+}
+
+func (r *shareRepository) scan(row pgx.Row) (*TaskShare, error) {
+	var share TaskShare
+	err := row.Scan(
+		&share.TaskID,
+		&share.Token,
+		&share.CreatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan task share: %w", err)
+	}
+
+	return &share, nil
+}
@@ -0,0 +1,165 @@
+package task
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/svg"
+	"github.com/google/uuid"
+)
+
+const shareBaseURL = "https://trackmus.ru/share"
+
+// ShareTask publishes the progress images of the task. Sharing an already
+// shared task returns the existing links.
+func (s *service) ShareTask(ctx context.Context, taskID, userID uuid.UUID) (*GetTaskShareResponse, error) {
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	share, err := s.shareRepo.GetByTaskID(ctx, taskID)
+	if err != nil {
+		s.log.Error("failed to get task share from repository", "taskID", taskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if share == nil {
+		rawToken := make([]byte, 16)
+		if _, err := rand.Read(rawToken); err != nil {
+			s.log.Error("failed to generate share token", "error", err)
+			return nil, fmt.Errorf(errors.ErrCommon)
+		}
+
+		share, err = s.shareRepo.Create(ctx, &TaskShare{TaskID: taskID, Token: hex.EncodeToString(rawToken)})
+		if err != nil {
+			s.log.Error("failed to create task share in repository", "taskID", taskID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToSaveData)
+		}
+	}
+
+	result := TaskShareToGetResponse(share)
+
+	return &result, nil
+}
+
+// UnshareTask revokes the token, so the public links stop working.
+func (s *service) UnshareTask(ctx context.Context, taskID, userID uuid.UUID) error {
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return err
+	}
+
+	if err := s.shareRepo.Delete(ctx, taskID); err != nil {
+		s.log.Error("failed to delete task share from repository", "taskID", taskID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToDeleteData)
+	}
+
+	return nil
+}
+
+func (s *service) RenderSharedProgress(ctx context.Context, token string) (string, error) {
+	task, err := s.getSharedTask(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	progress, err := s.getTaskProgress(ctx, task)
+	if err != nil {
+		return "", err
+	}
+
+	return svg.RenderProgressBar(svg.ProgressBar{
+		Label:   task.Title,
+		Percent: progress,
+	}), nil
+}
+
+func (s *service) RenderSharedBPMChart(ctx context.Context, token string) (string, error) {
+	task, err := s.getSharedTask(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, "", task.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.renderBPMChart(ctx, task, loc)
+}
+
+func (s *service) getSharedTask(ctx context.Context, token string) (*Task, error) {
+	share, err := s.shareRepo.GetByToken(ctx, token)
+	if err != nil {
+		s.log.Error("failed to get task share from repository", "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	if share == nil {
+		return nil, fmt.Errorf(errors.ErrShareNotFound)
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, share.TaskID)
+	if err != nil {
+		s.log.Error("failed to get task from repository", "taskID", share.TaskID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	return task, nil
+}
+
+// renderBPMChart plots the best tempo of every practice day of the task
+// against its target tempo. Days are taken in the given time zone.
+func (s *service) renderBPMChart(ctx context.Context, task *Task, loc *time.Location) (string, error) {
+	sessions, err := s.sessionRepo.GetByTaskID(ctx, task.ID)
+	if err != nil {
+		s.log.Error("failed to load sessions from repository", "taskID", task.ID, "error", err)
+		return "", fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	return svg.RenderLineChart(bpmChart(task, sessions, loc)), nil
+}
+
+func bpmChart(task *Task, sessions []Session, loc *time.Location) svg.LineChart {
+	return svg.LineChart{
+		Title:  task.Title,
+		Points: dailyBestBPM(sessions, loc),
+		Target: float64(task.TargetBPM),
+		Unit:   "BPM",
+	}
+}
+
+// dailyBestBPM returns the best tempo of every day with finished sessions,
+// oldest first.
+func dailyBestBPM(sessions []Session, loc *time.Location) []svg.Point {
+	best := make(map[time.Time]int)
+	for i := range sessions {
+		session := &sessions[i]
+		if session.Status != SessionStatusFinished {
+			continue
+		}
+
+		day := startOfDay(session.StartTime.In(loc))
+		for _, attempt := range sessionAttempts(session) {
+			if attempt.BPM > best[day] {
+				best[day] = attempt.BPM
+			}
+		}
+	}
+
+	points := make([]svg.Point, 0, len(best))
+	for day, bpm := range best {
+		if bpm > 0 {
+			points = append(points, svg.Point{Time: day, Value: float64(bpm)})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return points
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StatsRepository interface {
+	GetUserTimeZone(ctx context.Context, userID uuid.UUID) (string, error)
+	GetUserTempoAggressiveness(ctx context.Context, userID uuid.UUID) (string, error)
+	GetPracticeEntries(ctx context.Context, userID uuid.UUID, before time.Time) ([]PracticeEntry, error)
+	GetPracticeEntriesSince(ctx context.Context, userID uuid.UUID, since, before time.Time) ([]PracticeEntry, error)
+	GetBestBPMs(ctx context.Context, userID uuid.UUID, before time.Time) (map[uuid.UUID]int, error)
+}
+
+type statsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStatsRepository(pool *pgxpool.Pool) StatsRepository {
+	return &statsRepository{pool}
+}
+
+func (r *statsRepository) GetUserTimeZone(ctx context.Context, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT time_zone
+		FROM users
+		WHERE id = $1
+	`
+
+	var timeZone string
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&timeZone); err != nil {
+		return "", fmt.Errorf("failed to get user time zone: %w", err)
+	}
+
+	return timeZone, nil
+}
+
+func (r *statsRepository) GetUserTempoAggressiveness(ctx context.Context, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT tempo_aggressiveness
+		FROM users
+		WHERE id = $1
+	`
+
+	var aggressiveness string
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&aggressiveness); err != nil {
+		return "", fmt.Errorf("failed to get user tempo aggressiveness: %w", err)
+	}
+
+	return aggressiveness, nil
+}
+
+// GetPracticeEntries returns the user's finished sessions started before the
+// given time, oldest first.
+func (r *statsRepository) GetPracticeEntries(ctx context.Context, userID uuid.UUID, before time.Time) ([]PracticeEntry, error) {
+	return r.GetPracticeEntriesSince(ctx, userID, time.Time{}, before)
+}
+
+// GetPracticeEntriesSince is GetPracticeEntries limited to the sessions
+// started at or after since.
+func (r *statsRepository) GetPracticeEntriesSince(ctx context.Context, userID uuid.UUID, since, before time.Time) ([]PracticeEntry, error) {
+	query := `
+		SELECT s.task_id, s.start_time, COALESCE(s.end_time, s.start_time), COALESCE(s.bpm, 0), COALESCE(s.confidence, 0),
+			COALESCE(s.pain, 0), COALESCE(s.tension, 0), COALESCE((
+				SELECT SUM(EXTRACT(EPOCH FROM (COALESCE(p.ended_at, s.end_time) - p.started_at)))
+				FROM session_pauses p
+				WHERE p.session_id = s.id
+			), 0)::INT
+		FROM sessions s
+		JOIN tasks t ON t.id = s.task_id
+		WHERE t.user_id = $1 AND s.status = 'finished' AND s.start_time >= $2 AND s.start_time < $3
+		ORDER BY s.start_time
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since, before)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]PracticeEntry, 0)
+	for rows.Next() {
+		var entry PracticeEntry
+		err := rows.Scan(
+			&entry.TaskID,
+			&entry.StartTime,
+			&entry.EndTime,
+			&entry.BPM,
+			&entry.Confidence,
+			&entry.Pain,
+			&entry.Tension,
+			&entry.PausedSeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan practice entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetBestBPMs returns the best session tempo of every task of the user over
+// the finished sessions started before the given time. Tasks practiced
+// without a tempo are present with 0.
+func (r *statsRepository) GetBestBPMs(ctx context.Context, userID uuid.UUID, before time.Time) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT s.task_id, MAX(COALESCE(s.bpm, 0))
+		FROM sessions s
+		JOIN tasks t ON t.id = s.task_id
+		WHERE t.user_id = $1 AND s.status = 'finished' AND s.start_time < $2
+		GROUP BY s.task_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, before)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var taskID uuid.UUID
+		var bpm int
+		if err := rows.Scan(&taskID, &bpm); err != nil {
+			return nil, fmt.Errorf("failed to scan best bpm: %w", err)
+		}
+
+		best[taskID] = bpm
+	}
+
+	return best, nil
+}
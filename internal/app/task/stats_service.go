@@ -0,0 +1,329 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	statsDateLayout   = "2006-01-02"
+	defaultStatsDays  = 90
+	maxStatsRangeDays = 3 * 366
+)
+
+// GetStats aggregates the finished sessions of the user. Sessions are placed
+// on the day they started in the requested time zone.
+func (s *analyticsService) GetStats(ctx context.Context, req *GetStatsRequest, userID uuid.UUID) (*GetStatsResponse, error) {
+	loc, err := getStatsLocation(ctx, s.log, s.statsRepo, req.TimeZone, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	period := StatsPeriod(req.Period)
+	if period == "" {
+		period = StatsPeriodWeek
+	}
+
+	today := startOfDay(time.Now().In(loc))
+
+	to := today
+	if req.To != "" {
+		if to, err = time.ParseInLocation(statsDateLayout, req.To, loc); err != nil {
+			return nil, fmt.Errorf(errors.ErrInvalidData)
+		}
+	}
+
+	from := to.AddDate(0, 0, -defaultStatsDays+1)
+	if req.From != "" {
+		if from, err = time.ParseInLocation(statsDateLayout, req.From, loc); err != nil {
+			return nil, fmt.Errorf(errors.ErrInvalidData)
+		}
+	}
+
+	if from.After(to) || to.Sub(from) > maxStatsRangeDays*24*time.Hour {
+		s.log.Info("invalid stats range", "from", from, "to", to)
+		return nil, fmt.Errorf(errors.ErrInvalidData)
+	}
+
+	year := req.Year
+	if year == 0 {
+		year = today.Year()
+	}
+
+	end := to.AddDate(0, 0, 1)
+	if yearEnd := time.Date(year+1, 1, 1, 0, 0, 0, 0, loc); yearEnd.After(end) {
+		end = yearEnd
+	}
+
+	// The range, the heatmap year and the workload window are all that is
+	// shown; older sessions only matter for the tempo baseline.
+	since := from
+	if yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, loc); yearStart.Before(since) {
+		since = yearStart
+	}
+	if chronicStart := to.AddDate(0, 0, 1-workloadChronicDays); chronicStart.Before(since) {
+		since = chronicStart
+	}
+
+	entries, err := s.statsRepo.GetPracticeEntriesSince(ctx, userID, since, end)
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	bestBPM, err := s.statsRepo.GetBestBPMs(ctx, userID, since)
+	if err != nil {
+		s.log.Error("failed to get best bpms from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	tasks, err := s.taskRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	tasksByID := make(map[uuid.UUID]*Task, len(tasks))
+	for i := range tasks {
+		tasksByID[tasks[i].ID] = &tasks[i]
+	}
+
+	totals := newStatsAccumulator()
+	periods := make(map[string]*statsAccumulator)
+	byTask := make(map[uuid.UUID]*statsAccumulator)
+	byTag := make(map[string]*statsAccumulator)
+	heatmap := make(map[string]int)
+
+	// Entries come oldest first, so bestBPM always holds the best tempo played
+	// before the current entry; it is the baseline BPM gains are measured from.
+	for i := range entries {
+		entry := &entries[i]
+		day := startOfDay(entry.StartTime.In(loc))
+
+		if day.Year() == year {
+			heatmap[day.Format(statsDateLayout)] += entry.GetDurationSeconds()
+		}
+
+		if !day.Before(from) && !day.After(to) {
+			best := bestBPM[entry.TaskID]
+
+			totals.add(entry, best)
+			statsBucket(periods, periodStart(day, period).Format(statsDateLayout)).add(entry, best)
+			statsBucket(byTask, entry.TaskID).add(entry, best)
+			if task, ok := tasksByID[entry.TaskID]; ok {
+				for _, tag := range task.Tags {
+					statsBucket(byTag, tag).add(entry, best)
+				}
+			}
+		}
+
+		if entry.BPM > bestBPM[entry.TaskID] {
+			bestBPM[entry.TaskID] = entry.BPM
+		}
+	}
+
+	result := GetStatsResponse{
+		TimeZone: loc.String(),
+		Period:   string(period),
+		From:     from.Format(statsDateLayout),
+		To:       to.Format(statsDateLayout),
+		Totals:   totals.totals(),
+		Periods:  make([]GetStatsPeriodResponse, 0),
+		Tasks:    make([]GetTaskStatsResponse, 0, len(byTask)),
+		Tags:     make([]GetTagStatsResponse, 0, len(byTag)),
+		Heatmap:  buildHeatmap(heatmap, year, loc),
+		Workload: analyzeWorkload(entries, to.AddDate(0, 0, 1)),
+	}
+
+	for start := periodStart(from, period); !start.After(to); start = nextPeriod(start, period) {
+		key := start.Format(statsDateLayout)
+		result.Periods = append(result.Periods, GetStatsPeriodResponse{
+			Start:                  key,
+			GetStatsTotalsResponse: statsBucket(periods, key).totals(),
+		})
+	}
+
+	for taskID, acc := range byTask {
+		dto := GetTaskStatsResponse{
+			TaskID:                 taskID.String(),
+			GetStatsTotalsResponse: acc.totals(),
+		}
+		dto.StartBPM, dto.BestBPM = acc.bpmRange(taskID)
+		if task, ok := tasksByID[taskID]; ok {
+			dto.Title = task.Title
+		}
+		result.Tasks = append(result.Tasks, dto)
+	}
+	sort.Slice(result.Tasks, func(i, j int) bool {
+		return result.Tasks[i].PracticeSeconds > result.Tasks[j].PracticeSeconds
+	})
+
+	for tag, acc := range byTag {
+		result.Tags = append(result.Tags, GetTagStatsResponse{
+			Tag:                    tag,
+			GetStatsTotalsResponse: acc.totals(),
+		})
+	}
+	sort.Slice(result.Tags, func(i, j int) bool {
+		return result.Tags[i].PracticeSeconds > result.Tags[j].PracticeSeconds
+	})
+
+	return &result, nil
+}
+
+// getStatsLocation returns the requested time zone or, without one, the time
+// zone saved in the user's profile.
+func getStatsLocation(ctx context.Context, log *slog.Logger, statsRepo StatsRepository, timeZone string, userID uuid.UUID) (*time.Location, error) {
+	if timeZone == "" {
+		var err error
+		timeZone, err = statsRepo.GetUserTimeZone(ctx, userID)
+		if err != nil {
+			log.Error("failed to get user time zone from repository", "userID", userID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+	}
+
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		log.Warn("unknown time zone, falling back to UTC", "timeZone", timeZone, "userID", userID)
+		return time.UTC, nil
+	}
+
+	return loc, nil
+}
+
+// statsAccumulator sums up the sessions of one group. BPM gain is the sum over
+// tasks of the best tempo reached in the group minus the best tempo before it;
+// for a task practiced for the first time the first tempo is the baseline.
+type statsAccumulator struct {
+	seconds       int
+	sessions      int
+	confidenceSum int
+	rated         int
+	baseline      map[uuid.UUID]int
+	best          map[uuid.UUID]int
+}
+
+func newStatsAccumulator() *statsAccumulator {
+	return &statsAccumulator{
+		baseline: make(map[uuid.UUID]int),
+		best:     make(map[uuid.UUID]int),
+	}
+}
+
+func statsBucket[K comparable](buckets map[K]*statsAccumulator, key K) *statsAccumulator {
+	acc, ok := buckets[key]
+	if !ok {
+		acc = newStatsAccumulator()
+		buckets[key] = acc
+	}
+
+	return acc
+}
+
+func (a *statsAccumulator) add(entry *PracticeEntry, bestBefore int) {
+	a.seconds += entry.GetDurationSeconds()
+	a.sessions++
+
+	if entry.Confidence > 0 {
+		a.confidenceSum += entry.Confidence
+		a.rated++
+	}
+
+	if entry.BPM <= 0 {
+		return
+	}
+
+	if _, ok := a.baseline[entry.TaskID]; !ok {
+		if bestBefore > 0 {
+			a.baseline[entry.TaskID] = bestBefore
+		} else {
+			a.baseline[entry.TaskID] = entry.BPM
+		}
+	}
+
+	if entry.BPM > a.best[entry.TaskID] {
+		a.best[entry.TaskID] = entry.BPM
+	}
+}
+
+func (a *statsAccumulator) totals() GetStatsTotalsResponse {
+	result := GetStatsTotalsResponse{
+		PracticeSeconds: a.seconds,
+		Sessions:        a.sessions,
+	}
+
+	if a.rated > 0 {
+		result.AverageConfidence = math.Round(float64(a.confidenceSum)/float64(a.rated)*100) / 100
+	}
+
+	for taskID, best := range a.best {
+		if gain := best - a.baseline[taskID]; gain > 0 {
+			result.BPMGain += gain
+		}
+	}
+
+	return result
+}
+
+func (a *statsAccumulator) bpmRange(taskID uuid.UUID) (int, int) {
+	return a.baseline[taskID], a.best[taskID]
+}
+
+func buildHeatmap(seconds map[string]int, year int, loc *time.Location) GetHeatmapResponse {
+	result := GetHeatmapResponse{
+		Year: year,
+		Days: make([]GetHeatmapDayResponse, 0, 366),
+	}
+
+	for day := time.Date(year, 1, 1, 0, 0, 0, 0, loc); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		date := day.Format(statsDateLayout)
+		minutes := int(math.Round(float64(seconds[date]) / 60))
+		if minutes > result.MaxMinutes {
+			result.MaxMinutes = minutes
+		}
+
+		result.Days = append(result.Days, GetHeatmapDayResponse{
+			Date:    date,
+			Minutes: minutes,
+		})
+	}
+
+	return result
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// periodStart returns the first day of the period the day belongs to. Weeks
+// start on Monday.
+func periodStart(day time.Time, period StatsPeriod) time.Time {
+	switch period {
+	case StatsPeriodMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	case StatsPeriodWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return day
+	}
+}
+
+func nextPeriod(start time.Time, period StatsPeriod) time.Time {
+	switch period {
+	case StatsPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	case StatsPeriodWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
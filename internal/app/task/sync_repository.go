@@ -0,0 +1,69 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SyncRepository interface {
+	Now(ctx context.Context) (time.Time, error)
+	GetTombstonesSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Tombstone, error)
+}
+
+type syncRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSyncRepository(pool *pgxpool.Pool) SyncRepository {
+	return &syncRepository{pool}
+}
+
+// Now returns the database clock. Change tokens are built from it, so they
+// compare correctly with updated_at no matter how the API server clock drifts.
+func (r *syncRepository) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := r.pool.QueryRow(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get database time: %w", err)
+	}
+
+	return now, nil
+}
+
+func (r *syncRepository) GetTombstonesSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Tombstone, error) {
+	query := `
+		SELECT d.id, d.entity_type, d.entity_id, d.task_id, d.deleted_at
+		FROM tombstones d
+		JOIN tasks t ON t.id = d.task_id
+		WHERE t.user_id = $1 AND d.deleted_at > $2
+		ORDER BY d.deleted_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	tombstones := make([]Tombstone, 0)
+	for rows.Next() {
+		var tombstone Tombstone
+		err := rows.Scan(
+			&tombstone.ID,
+			&tombstone.EntityType,
+			&tombstone.EntityID,
+			&tombstone.TaskID,
+			&tombstone.DeletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tombstone: %w", err)
+		}
+
+		tombstones = append(tombstones, tombstone)
+	}
+
+	return tombstones, nil
+}
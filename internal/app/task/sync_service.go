@@ -0,0 +1,358 @@
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	syncTokenVersion = "v1"
+
+	// syncOverlap is subtracted from the change token when reading changes.
+	// A transaction that started before the previous sync but committed after
+	// it still gets picked up; clients apply changes by id, so repeats are
+	// harmless.
+	syncOverlap = time.Minute
+)
+
+// GetChanges returns everything that changed for the user since the token.
+// An empty token returns the full state. The response token is passed as
+// since on the next call.
+func (s *service) GetChanges(ctx context.Context, token string, userID uuid.UUID) (*GetSyncResponse, error) {
+	since, err := parseSyncToken(token)
+	if err != nil {
+		s.log.Info("invalid sync token", "token", token, "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrInvalidSyncToken)
+	}
+
+	now, err := s.syncRepo.Now(ctx)
+	if err != nil {
+		s.log.Error("failed to get database time", "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	from := since
+	if !since.IsZero() {
+		from = since.Add(-syncOverlap)
+	}
+
+	tasks, err := s.taskRepo.GetChangedSince(ctx, userID, from)
+	if err != nil {
+		s.log.Error("failed to get changed tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	sessions, err := s.sessionRepo.GetChangedSince(ctx, userID, from)
+	if err != nil {
+		s.log.Error("failed to get changed sessions from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	media, err := s.mediaRepo.GetChangedSince(ctx, userID, from)
+	if err != nil {
+		s.log.Error("failed to get changed media from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	links, err := s.linkRepo.GetChangedSince(ctx, userID, from)
+	if err != nil {
+		s.log.Error("failed to get changed links from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	// A full sync starts from an empty client, so there is nothing to delete.
+	tombstones := make([]Tombstone, 0)
+	if !since.IsZero() {
+		tombstones, err = s.syncRepo.GetTombstonesSince(ctx, userID, from)
+		if err != nil {
+			s.log.Error("failed to get tombstones from repository", "userID", userID, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+	}
+
+	result := GetSyncResponse{
+		Token:    encodeSyncToken(now),
+		Tasks:    make([]GetSyncTaskResponse, 0, len(tasks)),
+		Sessions: make([]GetSyncSessionResponse, 0, len(sessions)),
+		Media:    make([]GetSyncMediaResponse, 0, len(media)),
+		Links:    make([]GetSyncLinkResponse, 0, len(links)),
+		Deleted:  make([]GetTombstoneResponse, 0, len(tombstones)),
+	}
+
+	for _, task := range tasks {
+		progress, err := s.getTaskProgress(ctx, &task)
+		if err != nil {
+			return nil, err
+		}
+		result.Tasks = append(result.Tasks, TaskToGetSyncResponse(&task, progress))
+	}
+
+	for _, session := range sessions {
+		result.Sessions = append(result.Sessions, SessionToGetSyncResponse(&session))
+	}
+
+	for _, m := range media {
+		s3Key := fmt.Sprintf("%s/%s", m.TaskID, m.ID)
+		downloadURL, err := s.minio.GenerateDownloadURL(ctx, s.bucketName, s3Key, m.Filename)
+		if err != nil {
+			s.log.Error("failed to generate download URL", "fileID", m.ID, "objName", s3Key, "error", err)
+			return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+		}
+		result.Media = append(result.Media, MediaToGetSyncResponse(&m, downloadURL))
+	}
+
+	for _, link := range links {
+		result.Links = append(result.Links, LinkToGetSyncResponse(&link))
+	}
+
+	for _, tombstone := range tombstones {
+		result.Deleted = append(result.Deleted, TombstoneToGetResponse(&tombstone))
+	}
+
+	return &result, nil
+}
+
+// PushChanges applies the changes an offline client made. Each change is
+// applied on its own and reported in the order it was sent; a stale version
+// is reported as a conflict together with the current server state.
+func (s *service) PushChanges(ctx context.Context, req *SyncPushRequest, userID uuid.UUID) *SyncPushResponse {
+	results := make([]SyncChangeResult, 0, len(req.Tasks)+len(req.Sessions)+len(req.Links)+len(req.Deleted))
+
+	for _, change := range req.Tasks {
+		results = append(results, s.pushTask(ctx, &change, userID))
+	}
+
+	for _, change := range req.Sessions {
+		results = append(results, s.pushSession(ctx, &change, userID))
+	}
+
+	for _, change := range req.Links {
+		results = append(results, s.pushLink(ctx, &change, userID))
+	}
+
+	for _, deletion := range req.Deleted {
+		results = append(results, s.pushDeletion(ctx, &deletion, userID))
+	}
+
+	response := SyncPushResponse{Results: results}
+	for _, result := range results {
+		switch BatchItemStatus(result.Status) {
+		case BatchItemCreated, BatchItemUpdated, BatchItemDeleted, BatchItemDuplicate:
+			response.Applied++
+		case BatchItemConflict:
+			response.Conflicts++
+		default:
+			response.Failed++
+		}
+	}
+
+	return &response
+}
+
+func (s *service) pushTask(ctx context.Context, change *SyncTaskChange, userID uuid.UUID) SyncChangeResult {
+	result := SyncChangeResult{Type: string(EntityTask)}
+
+	if change.ID == nil {
+		task, err := s.CreateTask(ctx, &change.SaveTaskRequest, userID)
+		if err != nil {
+			return syncResultFromError(result, err)
+		}
+		result.ID = task.ID
+		result.Status = string(BatchItemCreated)
+		return result
+	}
+
+	result.ID = change.ID.String()
+	if change.Version <= 0 {
+		return syncResultFromError(result, fmt.Errorf(errors.ErrVersionRequired))
+	}
+
+	if _, err := s.UpdateTask(ctx, &change.SaveTaskRequest, *change.ID, userID); err != nil {
+		return syncResultFromError(result, err)
+	}
+	result.Status = string(BatchItemUpdated)
+
+	return result
+}
+
+func (s *service) pushSession(ctx context.Context, change *SyncSessionChange, userID uuid.UUID) SyncChangeResult {
+	result := SyncChangeResult{Type: string(EntitySession), ClientID: change.ClientID}
+
+	if change.ID == nil {
+		item := BatchSessionItemRequest{
+			ClientID:           change.ClientID,
+			TaskID:             change.TaskID,
+			SaveSessionRequest: change.SaveSessionRequest,
+		}
+		created := s.CreateSessionsBatch(ctx, []BatchSessionItemRequest{item}, userID)[0]
+		result.ID = created.ID
+		result.Status = created.Status
+		result.Message = created.Message
+		return result
+	}
+
+	result.ID = change.ID.String()
+	if change.Version <= 0 {
+		return syncResultFromError(result, fmt.Errorf(errors.ErrVersionRequired))
+	}
+
+	if _, err := s.UpdateSession(ctx, &change.SaveSessionRequest, *change.ID, userID); err != nil {
+		return syncResultFromError(result, err)
+	}
+	result.Status = string(BatchItemUpdated)
+
+	return result
+}
+
+func (s *service) pushLink(ctx context.Context, change *SyncLinkChange, userID uuid.UUID) SyncChangeResult {
+	result := SyncChangeResult{Type: string(EntityLink)}
+
+	if change.ID == nil {
+		if err := s.checkTaskOwner(ctx, change.TaskID, userID); err != nil {
+			return syncResultFromError(result, err)
+		}
+
+		link, err := s.SaveLink(ctx, &change.SaveLinkRequest, change.TaskID)
+		if err != nil {
+			return syncResultFromError(result, err)
+		}
+		result.ID = link.ID
+		result.Status = string(BatchItemCreated)
+		return result
+	}
+
+	result.ID = change.ID.String()
+	if change.Version <= 0 {
+		return syncResultFromError(result, fmt.Errorf(errors.ErrVersionRequired))
+	}
+
+	if _, err := s.UpdateLink(ctx, &change.SaveLinkRequest, *change.ID, userID); err != nil {
+		return syncResultFromError(result, err)
+	}
+	result.Status = string(BatchItemUpdated)
+
+	return result
+}
+
+// pushDeletion deletes a session, media or link. Deleting an entity that is
+// already gone succeeds, so a client can safely resend its deletions.
+func (s *service) pushDeletion(ctx context.Context, deletion *SyncDeletion, userID uuid.UUID) SyncChangeResult {
+	result := SyncChangeResult{Type: string(deletion.Type), ID: deletion.ID.String()}
+
+	var (
+		taskID uuid.UUID
+		err    error
+	)
+	if deletion.Type != EntityMedia && deletion.Version <= 0 {
+		return syncResultFromError(result, fmt.Errorf(errors.ErrVersionRequired))
+	}
+
+	switch deletion.Type {
+	case EntitySession:
+		var session *Session
+		if session, err = s.sessionRepo.GetByID(ctx, deletion.ID); err == nil {
+			taskID = session.TaskID
+		}
+	case EntityMedia:
+		var media *Media
+		if media, err = s.mediaRepo.GetByID(ctx, deletion.ID); err == nil {
+			taskID = media.TaskID
+		}
+	case EntityLink:
+		var link *Link
+		if link, err = s.linkRepo.GetByID(ctx, deletion.ID); err == nil {
+			taskID = link.TaskID
+		}
+	default:
+		return syncResultFromError(result, fmt.Errorf(errors.ErrInvalidData))
+	}
+
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		result.Status = string(BatchItemDeleted)
+		return result
+	}
+	if err != nil {
+		s.log.Error("failed to get entity from repository", "type", deletion.Type, "id", deletion.ID, "error", err)
+		return syncResultFromError(result, fmt.Errorf(errors.ErrFailedToLoadData))
+	}
+
+	if err := s.checkTaskOwner(ctx, taskID, userID); err != nil {
+		return syncResultFromError(result, err)
+	}
+
+	switch deletion.Type {
+	case EntitySession:
+		_, err = s.RemoveSession(ctx, deletion.ID, deletion.Version, userID)
+	case EntityMedia:
+		err = s.RemoveMedia(ctx, deletion.ID)
+	case EntityLink:
+		err = s.RemoveLink(ctx, deletion.ID, deletion.Version, userID)
+	}
+	if err != nil {
+		return syncResultFromError(result, err)
+	}
+	result.Status = string(BatchItemDeleted)
+
+	return result
+}
+
+func syncResultFromError(result SyncChangeResult, err error) SyncChangeResult {
+	result.Message = err.Error()
+
+	if conflict, ok := errors.AsConflict(err); ok {
+		result.Status = string(BatchItemConflict)
+		result.Current = conflict.Current
+		return result
+	}
+
+	switch err.Error() {
+	case errors.ErrAccessDenied:
+		result.Status = string(BatchItemForbidden)
+	case errors.ErrInvalidData, errors.ErrSessionState, errors.ErrVersionRequired:
+		result.Status = string(BatchItemInvalid)
+	default:
+		result.Status = string(BatchItemFailed)
+	}
+
+	return result
+}
+
+// encodeSyncToken turns a point in time into an opaque change token. Clients
+// must not rely on its format.
+func encodeSyncToken(at time.Time) string {
+	raw := syncTokenVersion + ":" + strconv.FormatInt(at.UnixMicro(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseSyncToken returns the zero time for an empty token, i.e. a full sync.
+func parseSyncToken(token string) (time.Time, error) {
+	if token == "" {
+		return time.Time{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	version, value, ok := strings.Cut(string(raw), ":")
+	if !ok || version != syncTokenVersion {
+		return time.Time{}, fmt.Errorf("unsupported sync token version %q", version)
+	}
+
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros <= 0 {
+		return time.Time{}, fmt.Errorf("invalid sync token time %q", value)
+	}
+
+	return time.UnixMicro(micros), nil
+}
@@ -3,7 +3,9 @@ package task
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -13,6 +15,8 @@ type TaskRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Task, error)
 	Create(ctx context.Context, task *Task, userID uuid.UUID) (*Task, error)
 	Update(ctx context.Context, task *Task) (*Task, error)
+	GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Task, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]Task, error)
 }
 
 type taskRepository struct {
@@ -25,7 +29,7 @@ func NewTaskRepository(pool *pgxpool.Pool) TaskRepository {
 
 func (r *taskRepository) Get(ctx context.Context, userID uuid.UUID, isCompleted bool) ([]Task, error) {
 	query := `
-		SELECT id, title, target_bpm, is_completed, created_at
+		SELECT id, user_id, title, target_bpm, COALESCE(composer, ''), COALESCE(time_signature, ''), COALESCE(duration, 0), COALESCE(clean_reps_goal, 0), tags, is_completed, version, created_at
 		FROM tasks
 		WHERE user_id = $1 AND is_completed = $2
 	`
@@ -41,9 +45,16 @@ func (r *taskRepository) Get(ctx context.Context, userID uuid.UUID, isCompleted
 		var task Task
 		err := rows.Scan(
 			&task.ID,
+			&task.UserID,
 			&task.Title,
 			&task.TargetBPM,
+			&task.Composer,
+			&task.TimeSignature,
+			&task.Duration,
+			&task.CleanRepsGoal,
+			&task.Tags,
 			&task.IsCompleted,
+			&task.Version,
 			&task.CreatedAt,
 		)
 		if err != nil {
@@ -58,7 +69,7 @@ func (r *taskRepository) Get(ctx context.Context, userID uuid.UUID, isCompleted
 
 func (r *taskRepository) GetByID(ctx context.Context, id uuid.UUID) (*Task, error) {
 	query := `
-		SELECT id, title, target_bpm, is_completed
+		SELECT id, user_id, title, target_bpm, COALESCE(composer, ''), COALESCE(time_signature, ''), COALESCE(duration, 0), COALESCE(clean_reps_goal, 0), tags, due_date, is_completed, completed_by_rule, version
 		FROM tasks
 		WHERE id = $1
 	`
@@ -70,9 +81,18 @@ func (r *taskRepository) GetByID(ctx context.Context, id uuid.UUID) (*Task, erro
 		id,
 	).Scan(
 		&task.ID,
+		&task.UserID,
 		&task.Title,
 		&task.TargetBPM,
+		&task.Composer,
+		&task.TimeSignature,
+		&task.Duration,
+		&task.CleanRepsGoal,
+		&task.Tags,
+		&task.DueDate,
 		&task.IsCompleted,
+		&task.CompletedByRule,
+		&task.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan task: %w", err)
@@ -83,9 +103,9 @@ func (r *taskRepository) GetByID(ctx context.Context, id uuid.UUID) (*Task, erro
 
 func (r *taskRepository) Create(ctx context.Context, task *Task, userID uuid.UUID) (*Task, error) {
 	query := `
-		INSERT INTO tasks(user_id, title, target_bpm)
-		VALUES ($1, $2, $3)
-		RETURNING id
+		INSERT INTO tasks(user_id, title, target_bpm, clean_reps_goal, tags, due_date)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+		RETURNING id, version
 	`
 
 	var id uuid.UUID
@@ -95,8 +115,12 @@ func (r *taskRepository) Create(ctx context.Context, task *Task, userID uuid.UUI
 		userID,
 		task.Title,
 		task.TargetBPM,
+		task.CleanRepsGoal,
+		tagsOrEmpty(task.Tags),
+		task.DueDate,
 	).Scan(
 		&id,
+		&task.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
@@ -112,21 +136,126 @@ func (r *taskRepository) Update(ctx context.Context, task *Task) (*Task, error)
 		UPDATE tasks
 		SET title = $2,
 			target_bpm = $3,
-			is_completed = $4
-		WHERE id = $1
+			is_completed = $4,
+			completed_at = CASE WHEN $4 THEN COALESCE(completed_at, NOW()) END,
+			completed_by_rule = $4 AND $9,
+			clean_reps_goal = NULLIF($6, 0),
+			tags = $7,
+			due_date = $8,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $1 AND version = $5
 	`
 
-	_, err := r.pool.Exec(
+	tag, err := r.pool.Exec(
 		ctx,
 		query,
 		task.ID,
 		task.Title,
 		task.TargetBPM,
 		task.IsCompleted,
+		task.Version,
+		task.CleanRepsGoal,
+		tagsOrEmpty(task.Tags),
+		task.DueDate,
+		task.CompletedByRule,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update task: %w", err)
 	}
 
+	if tag.RowsAffected() == 0 {
+		return nil, errors.ErrVersionConflict
+	}
+
+	task.Version++
+
 	return task, nil
 }
+
+// GetChangedSince returns the user's tasks created or updated after since.
+func (r *taskRepository) GetChangedSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]Task, error) {
+	query := `
+		SELECT id, user_id, title, target_bpm, COALESCE(composer, ''), COALESCE(time_signature, ''), COALESCE(duration, 0), COALESCE(clean_reps_goal, 0), tags, due_date, is_completed, version, created_at, updated_at
+		FROM tasks
+		WHERE user_id = $1 AND updated_at > $2
+		ORDER BY updated_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		var task Task
+		err := rows.Scan(
+			&task.ID,
+			&task.UserID,
+			&task.Title,
+			&task.TargetBPM,
+			&task.Composer,
+			&task.TimeSignature,
+			&task.Duration,
+			&task.CleanRepsGoal,
+			&task.Tags,
+			&task.DueDate,
+			&task.IsCompleted,
+			&task.Version,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// GetByUserID returns all tasks of the user, active and completed.
+func (r *taskRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Task, error) {
+	query := `
+		SELECT id, user_id, title, target_bpm, COALESCE(composer, ''), COALESCE(time_signature, ''), COALESCE(duration, 0), COALESCE(clean_reps_goal, 0), tags, is_completed, completed_at, version, created_at
+		FROM tasks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		var task Task
+		err := rows.Scan(
+			&task.ID,
+			&task.UserID,
+			&task.Title,
+			&task.TargetBPM,
+			&task.Composer,
+			&task.TimeSignature,
+			&task.Duration,
+			&task.CleanRepsGoal,
+			&task.Tags,
+			&task.IsCompleted,
+			&task.CompletedAt,
+			&task.Version,
+			&task.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
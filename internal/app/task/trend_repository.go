@@ -0,0 +1,263 @@
+package task
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TrendRepository keeps the plateau and regression flags of the tasks and
+// provides the practice history the analyzer works on.
+type TrendRepository interface {
+	GetCandidates(ctx context.Context, since time.Time) ([]TrendCandidate, error)
+	GetPracticeEntries(ctx context.Context, taskID uuid.UUID) ([]PracticeEntry, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]TaskTrend, error)
+	GetByTaskID(ctx context.Context, taskID uuid.UUID) (*TaskTrend, error)
+	Save(ctx context.Context, model *TaskTrend) error
+	MarkNotified(ctx context.Context, taskID uuid.UUID, at time.Time) error
+	DeleteByTaskID(ctx context.Context, taskID uuid.UUID) error
+	DeleteUnchecked(ctx context.Context, checkedBefore time.Time) (int64, error)
+}
+
+type trendRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTrendRepository(pool *pgxpool.Pool) TrendRepository {
+	return &trendRepository{pool}
+}
+
+// GetCandidates returns the active tasks with a finished session since the
+// given time.
+func (r *trendRepository) GetCandidates(ctx context.Context, since time.Time) ([]TrendCandidate, error) {
+	query := `
+		SELECT t.id, t.user_id, u.email, t.title, t.target_bpm
+		FROM tasks t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.is_completed = FALSE AND EXISTS (
+			SELECT 1
+			FROM sessions s
+			WHERE s.task_id = t.id AND s.status = 'finished' AND s.start_time >= $1
+		)
+		ORDER BY t.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]TrendCandidate, 0)
+	for rows.Next() {
+		var candidate TrendCandidate
+		err := rows.Scan(
+			&candidate.TaskID,
+			&candidate.UserID,
+			&candidate.Email,
+			&candidate.Title,
+			&candidate.TargetBPM,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trend candidate: %w", err)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+// GetPracticeEntries returns the finished sessions of the task, oldest first.
+func (r *trendRepository) GetPracticeEntries(ctx context.Context, taskID uuid.UUID) ([]PracticeEntry, error) {
+	query := `
+		SELECT task_id, start_time, COALESCE(end_time, start_time), COALESCE(bpm, 0), COALESCE(confidence, 0)
+		FROM sessions
+		WHERE task_id = $1 AND status = 'finished'
+		ORDER BY start_time
+	`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]PracticeEntry, 0)
+	for rows.Next() {
+		var entry PracticeEntry
+		err := rows.Scan(
+			&entry.TaskID,
+			&entry.StartTime,
+			&entry.EndTime,
+			&entry.BPM,
+			&entry.Confidence,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan practice entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetByUserID returns the flags of the user's active tasks.
+func (r *trendRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]TaskTrend, error) {
+	query := `
+		SELECT tr.task_id, tr.status, tr.reason, tr.best_bpm, tr.previous_best_bpm, tr.confidence,
+			tr.previous_confidence, tr.detected_at, tr.checked_at, tr.notified_at, tr.window_days, tr.min_sessions
+		FROM task_trends tr
+		JOIN tasks t ON t.id = tr.task_id
+		WHERE t.user_id = $1 AND t.is_completed = FALSE
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	trends := make([]TaskTrend, 0)
+	for rows.Next() {
+		trend, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		trends = append(trends, *trend)
+	}
+
+	return trends, rows.Err()
+}
+
+// GetByTaskID returns the flag of the task, or nil when there is none.
+func (r *trendRepository) GetByTaskID(ctx context.Context, taskID uuid.UUID) (*TaskTrend, error) {
+	query := `
+		SELECT task_id, status, reason, best_bpm, previous_best_bpm, confidence,
+			previous_confidence, detected_at, checked_at, notified_at, window_days, min_sessions
+		FROM task_trends
+		WHERE task_id = $1
+	`
+
+	return r.scan(r.pool.QueryRow(ctx, query, taskID))
+}
+
+func (r *trendRepository) Save(ctx context.Context, model *TaskTrend) error {
+	query := `
+		INSERT INTO task_trends(task_id, status, reason, best_bpm, previous_best_bpm, confidence,
+			previous_confidence, detected_at, checked_at, notified_at, window_days, min_sessions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (task_id) DO UPDATE
+		SET status = EXCLUDED.status,
+			reason = EXCLUDED.reason,
+			best_bpm = EXCLUDED.best_bpm,
+			previous_best_bpm = EXCLUDED.previous_best_bpm,
+			confidence = EXCLUDED.confidence,
+			previous_confidence = EXCLUDED.previous_confidence,
+			detected_at = EXCLUDED.detected_at,
+			checked_at = EXCLUDED.checked_at,
+			notified_at = EXCLUDED.notified_at,
+			window_days = EXCLUDED.window_days,
+			min_sessions = EXCLUDED.min_sessions
+	`
+
+	_, err := r.pool.Exec(
+		ctx,
+		query,
+		model.TaskID,
+		model.Status,
+		model.Reason,
+		model.BestBPM,
+		model.PreviousBestBPM,
+		model.Confidence,
+		model.PreviousConfidence,
+		model.DetectedAt,
+		model.CheckedAt,
+		model.NotifiedAt,
+		model.WindowDays,
+		model.MinSessions,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save task trend: %w", err)
+	}
+
+	return nil
+}
+
+func (r *trendRepository) MarkNotified(ctx context.Context, taskID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE task_trends
+		SET notified_at = $2
+		WHERE task_id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, taskID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark task trend notified: %w", err)
+	}
+
+	return nil
+}
+
+func (r *trendRepository) DeleteByTaskID(ctx context.Context, taskID uuid.UUID) error {
+	query := `
+		DELETE FROM task_trends
+		WHERE task_id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to delete task trend: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUnchecked removes the flags the last analysis did not confirm, e.g.
+// of tasks that have improved, were completed or are no longer practiced.
+func (r *trendRepository) DeleteUnchecked(ctx context.Context, checkedBefore time.Time) (int64, error) {
+	query := `
+		DELETE FROM task_trends
+		WHERE checked_at < $1
+	`
+
+	tag, err := r.pool.Exec(ctx, query, checkedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete task trends: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *trendRepository) scan(row pgx.Row) (*TaskTrend, error) {
+	var model TaskTrend
+	err := row.Scan(
+		&model.TaskID,
+		&model.Status,
+		&model.Reason,
+		&model.BestBPM,
+		&model.PreviousBestBPM,
+		&model.Confidence,
+		&model.PreviousConfidence,
+		&model.DetectedAt,
+		&model.CheckedAt,
+		&model.NotifiedAt,
+		&model.WindowDays,
+		&model.MinSessions,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan task trend: %w", err)
+	}
+
+	return &model, nil
+}
@@ -0,0 +1,283 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/events"
+	"github.com/google/uuid"
+)
+
+const (
+	// A best tempo in the window below this share of the earlier best is a
+	// regression rather than a bad day.
+	trendRegressionRatio = 0.95
+
+	// Average confidence has to fall by trendConfidenceDrop to count as a
+	// regression, while a rise of trendConfidenceGain is progress even when
+	// the tempo stays the same.
+	trendConfidenceDrop = 1.0
+	trendConfidenceGain = 0.5
+)
+
+// AnalyzeTrends looks at every active task practiced within the window and
+// flags those whose tempo has stalled or dropped compared to the sessions
+// before it. New flags are sent to the owner by email; flags that no longer
+// apply are removed. It returns the number of flagged tasks.
+func (s *analyticsService) AnalyzeTrends(ctx context.Context, window time.Duration, minSessions int) (int, error) {
+	now := time.Now()
+
+	candidates, err := s.trendRepo.GetCandidates(ctx, now.Add(-window))
+	if err != nil {
+		s.log.Error("failed to get trend candidates from repository", "error", err)
+		return 0, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	flagged := 0
+	failed := false
+	for _, candidate := range candidates {
+		entries, err := s.trendRepo.GetPracticeEntries(ctx, candidate.TaskID)
+		if err != nil {
+			s.log.Error("failed to get practice entries from repository", "taskID", candidate.TaskID, "error", err)
+			failed = true
+			continue
+		}
+
+		trend := analyzeTrend(entries, candidate.TargetBPM, now, window, minSessions)
+		if trend == nil {
+			continue
+		}
+
+		if err := s.saveTrend(ctx, &candidate, trend, now); err != nil {
+			failed = true
+			continue
+		}
+		flagged++
+	}
+
+	// Flags of tasks that could not be checked are kept until the next run.
+	if !failed {
+		removed, err := s.trendRepo.DeleteUnchecked(ctx, now)
+		if err != nil {
+			s.log.Error("failed to delete task trends from repository", "error", err)
+			return flagged, fmt.Errorf(errors.ErrFailedToDeleteData)
+		}
+		if removed > 0 {
+			s.log.Info("task trends cleared", "count", removed)
+		}
+	}
+
+	return flagged, nil
+}
+
+// getTrendsByTask returns the flags of the user's active tasks by task id.
+// The task lists are shown without flags when they cannot be loaded.
+func (s *analyticsService) getTrendsByTask(ctx context.Context, userID uuid.UUID) map[uuid.UUID]*TaskTrend {
+	trends, err := s.trendRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get task trends from repository", "userID", userID, "error", err)
+		return nil
+	}
+
+	result := make(map[uuid.UUID]*TaskTrend, len(trends))
+	for i := range trends {
+		result[trends[i].TaskID] = &trends[i]
+	}
+
+	return result
+}
+
+// saveTrend stores the flag, keeping when it was first detected while the
+// status stays the same, and notifies the owner once per status.
+func (s *analyticsService) saveTrend(ctx context.Context, candidate *TrendCandidate, trend *TaskTrend, now time.Time) error {
+	previous, err := s.trendRepo.GetByTaskID(ctx, candidate.TaskID)
+	if err != nil {
+		s.log.Error("failed to get task trend from repository", "taskID", candidate.TaskID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	trend.TaskID = candidate.TaskID
+	trend.DetectedAt = now
+	trend.CheckedAt = now
+	if previous != nil && previous.Status == trend.Status {
+		trend.DetectedAt = previous.DetectedAt
+		trend.NotifiedAt = previous.NotifiedAt
+	}
+
+	if err := s.trendRepo.Save(ctx, trend); err != nil {
+		s.log.Error("failed to save task trend in repository", "taskID", candidate.TaskID, "error", err)
+		return fmt.Errorf(errors.ErrFailedToSaveData)
+	}
+
+	if trend.NotifiedAt == nil {
+		s.notifyTrend(ctx, candidate, trend, now)
+	}
+
+	return nil
+}
+
+// refreshTrend repeats the last analysis of a flagged task after its sessions
+// changed, so that an edit or a deletion does not leave a stale flag behind. A
+// changed flag is notified by the next run of the analyzer.
+func (s *analyticsService) refreshTrend(ctx context.Context, task *Task) {
+	previous, err := s.trendRepo.GetByTaskID(ctx, task.ID)
+	if err != nil {
+		s.log.Error("failed to get task trend from repository", "taskID", task.ID, "error", err)
+		return
+	}
+	if previous == nil {
+		return
+	}
+
+	var trend *TaskTrend
+	if !task.IsCompleted {
+		entries, err := s.trendRepo.GetPracticeEntries(ctx, task.ID)
+		if err != nil {
+			s.log.Error("failed to get practice entries from repository", "taskID", task.ID, "error", err)
+			return
+		}
+
+		window := time.Duration(previous.WindowDays) * 24 * time.Hour
+		trend = analyzeTrend(entries, task.TargetBPM, time.Now(), window, previous.MinSessions)
+	}
+
+	if trend == nil {
+		if err := s.trendRepo.DeleteByTaskID(ctx, task.ID); err != nil {
+			s.log.Error("failed to delete task trend from repository", "taskID", task.ID, "error", err)
+		}
+		return
+	}
+
+	trend.TaskID = task.ID
+	trend.DetectedAt = time.Now()
+	trend.CheckedAt = trend.DetectedAt
+	if previous.Status == trend.Status {
+		trend.DetectedAt = previous.DetectedAt
+		trend.NotifiedAt = previous.NotifiedAt
+	}
+
+	if err := s.trendRepo.Save(ctx, trend); err != nil {
+		s.log.Error("failed to save task trend in repository", "taskID", task.ID, "error", err)
+	}
+}
+
+// notifyTrend publishes the flag for the mail service, which turns it into a
+// practice suggestion. A failed notification is retried on the next run.
+func (s *analyticsService) notifyTrend(ctx context.Context, candidate *TrendCandidate, trend *TaskTrend, now time.Time) {
+	if s.rabbitmq == nil {
+		s.log.Warn("event service not available - trend email not sent", "taskID", candidate.TaskID)
+		return
+	}
+
+	event := events.EmailEvent{
+		To:       candidate.Email,
+		Template: "practice_trend",
+		Subject:  "Темп перестал расти",
+		Data: map[string]interface{}{
+			"user_email":        candidate.Email,
+			"task_title":        candidate.Title,
+			"status":            string(trend.Status),
+			"reason":            trend.Reason,
+			"best_bpm":          strconv.Itoa(trend.BestBPM),
+			"previous_best_bpm": strconv.Itoa(trend.PreviousBestBPM),
+			"target_bpm":        strconv.Itoa(candidate.TargetBPM),
+		},
+	}
+	if trend.Status == TrendStatusRegression {
+		event.Subject = "Темп снизился"
+	}
+
+	if err := s.rabbitmq.PublishEmail(event); err != nil {
+		s.log.Error("failed to publish email event", "error", err)
+		return
+	}
+
+	if err := s.trendRepo.MarkNotified(ctx, candidate.TaskID, now); err != nil {
+		s.log.Error("failed to mark task trend notified in repository", "taskID", candidate.TaskID, "error", err)
+	}
+}
+
+// analyzeTrend compares the sessions within the window before now with the
+// earlier ones. A task needs at least minSessions sessions in the window and
+// some history before it to be judged. It returns nil when the task is
+// progressing, has reached its target or there is too little data.
+func analyzeTrend(entries []PracticeEntry, targetBPM int, now time.Time, window time.Duration, minSessions int) *TaskTrend {
+	start := now.Add(-window)
+	split := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].StartTime.Before(start)
+	})
+
+	earlier, recent := entries[:split], entries[split:]
+	if len(recent) < minSessions || len(earlier) == 0 {
+		return nil
+	}
+
+	best := bestEntryBPM(recent)
+	previousBest := bestEntryBPM(earlier)
+	if best == 0 || previousBest == 0 || (targetBPM > 0 && best >= targetBPM) {
+		return nil
+	}
+
+	// Confidence is compared with as many sessions right before the window
+	// as there are in it.
+	baseline := earlier[max(0, len(earlier)-len(recent)):]
+	confidence := averageEntryConfidence(recent)
+	previousConfidence := averageEntryConfidence(baseline)
+
+	days := int(window.Hours() / 24)
+	trend := &TaskTrend{
+		BestBPM:            best,
+		PreviousBestBPM:    previousBest,
+		Confidence:         math.Round(confidence*100) / 100,
+		PreviousConfidence: math.Round(previousConfidence*100) / 100,
+		WindowDays:         days,
+		MinSessions:        minSessions,
+	}
+
+	switch {
+	case float64(best) < float64(previousBest)*trendRegressionRatio:
+		trend.Status = TrendStatusRegression
+		trend.Reason = fmt.Sprintf("лучший темп за последние %d дн. — %d BPM, раньше был %d BPM", days, best, previousBest)
+	case previousConfidence > 0 && confidence <= previousConfidence-trendConfidenceDrop:
+		trend.Status = TrendStatusRegression
+		trend.Reason = fmt.Sprintf("уверенность за последние %d дн. снизилась с %.1f до %.1f", days, previousConfidence, confidence)
+	case best <= previousBest && (previousConfidence == 0 || confidence < previousConfidence+trendConfidenceGain):
+		trend.Status = TrendStatusPlateau
+		trend.Reason = fmt.Sprintf("темп не растёт %d дн.: за %d занятий не превышен прежний рекорд %d BPM", days, len(recent), previousBest)
+	default:
+		return nil
+	}
+
+	return trend
+}
+
+func bestEntryBPM(entries []PracticeEntry) int {
+	best := 0
+	for _, entry := range entries {
+		best = max(best, entry.BPM)
+	}
+
+	return best
+}
+
+// averageEntryConfidence skips sessions without a confidence rating.
+func averageEntryConfidence(entries []PracticeEntry) float64 {
+	sum, count := 0, 0
+	for _, entry := range entries {
+		if entry.Confidence > 0 {
+			sum += entry.Confidence
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return float64(sum) / float64(count)
+}
@@ -0,0 +1,108 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeTrend(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	session := func(daysAgo, bpm, confidence int) PracticeEntry {
+		return PracticeEntry{StartTime: now.AddDate(0, 0, -daysAgo), BPM: bpm, Confidence: confidence}
+	}
+	earlier := []PracticeEntry{session(40, 90, 3), session(35, 100, 3), session(30, 96, 3)}
+	with := func(recent ...PracticeEntry) []PracticeEntry {
+		return append(append([]PracticeEntry(nil), earlier...), recent...)
+	}
+
+	tests := []struct {
+		name           string
+		entries        []PracticeEntry
+		target         int
+		wantStatus     TrendStatus
+		wantBest       int
+		wantConfidence float64
+	}{
+		{
+			name:    "too few sessions in the window",
+			entries: with(session(10, 90, 3), session(5, 92, 3)),
+		},
+		{
+			name:    "no sessions before the window",
+			entries: []PracticeEntry{session(10, 90, 3), session(5, 92, 3), session(1, 91, 3)},
+		},
+		{
+			name:    "new record",
+			entries: with(session(10, 98, 3), session(5, 101, 3), session(1, 99, 3)),
+		},
+		{
+			name:    "target reached",
+			entries: with(session(10, 90, 3), session(5, 92, 3), session(1, 94, 3)),
+			target:  94,
+		},
+		{
+			name:           "tempo dropped",
+			entries:        with(session(10, 90, 3), session(5, 92, 3), session(1, 94, 3)),
+			target:         120,
+			wantStatus:     TrendStatusRegression,
+			wantBest:       94,
+			wantConfidence: 3,
+		},
+		{
+			name:           "confidence dropped",
+			entries:        with(session(10, 100, 2), session(5, 98, 2), session(1, 99, 2)),
+			wantStatus:     TrendStatusRegression,
+			wantBest:       100,
+			wantConfidence: 2,
+		},
+		{
+			name:           "plateau",
+			entries:        with(session(10, 96, 3), session(5, 100, 3), session(1, 97, 3)),
+			wantStatus:     TrendStatusPlateau,
+			wantBest:       100,
+			wantConfidence: 3,
+		},
+		{
+			name:    "same tempo played with more confidence",
+			entries: with(session(10, 96, 4), session(5, 100, 3), session(1, 97, 4)),
+		},
+		{
+			name:       "plateau without ratings",
+			entries:    []PracticeEntry{session(30, 100, 0), session(10, 96, 0), session(5, 100, 0), session(1, 97, 0)},
+			wantStatus: TrendStatusPlateau,
+			wantBest:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend := analyzeTrend(tt.entries, tt.target, now, 21*24*time.Hour, 3)
+
+			if tt.wantStatus == "" {
+				if trend != nil {
+					t.Fatalf("trend = %+v, want none", trend)
+				}
+				return
+			}
+			if trend == nil {
+				t.Fatalf("no trend, want %q", tt.wantStatus)
+			}
+
+			if trend.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", trend.Status, tt.wantStatus)
+			}
+			if trend.BestBPM != tt.wantBest || trend.PreviousBestBPM != 100 {
+				t.Errorf("best = %d after %d, want %d after 100", trend.BestBPM, trend.PreviousBestBPM, tt.wantBest)
+			}
+			if trend.Confidence != tt.wantConfidence {
+				t.Errorf("confidence = %v, want %v", trend.Confidence, tt.wantConfidence)
+			}
+			if trend.WindowDays != 21 || trend.MinSessions != 3 {
+				t.Errorf("window = %d days, %d sessions, want 21 days, 3 sessions", trend.WindowDays, trend.MinSessions)
+			}
+			if trend.Reason == "" {
+				t.Errorf("reason is empty")
+			}
+		})
+	}
+}
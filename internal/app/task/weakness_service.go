@@ -0,0 +1,336 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	// weaknessSessions is how many of the latest sessions of a task its
+	// confidence is averaged over; only the sessions of the last
+	// weaknessWindowDays are considered.
+	weaknessSessions   = 5
+	weaknessWindowDays = 90
+
+	// Tags scoring below weakAreaMinScore are not weak enough to suggest work
+	// on; at most weakAreaLimit of them are returned.
+	weakAreaMinScore = 0.25
+	weakAreaLimit    = 3
+
+	weakAreaTasks     = 2
+	weakAreaExercises = 2
+
+	// The score of a task weighs the lack of confidence, the distance to the
+	// target tempo and a plateau or regression.
+	weaknessConfidenceWeight = 0.5
+	weaknessTempoWeight      = 0.3
+	weaknessTrendWeight      = 0.2
+)
+
+// taskWeakness is a practiced active task along with how weak it is.
+type taskWeakness struct {
+	Task       *Task
+	Score      float64
+	Confidence float64
+	BestBPM    int
+	Trend      *TaskTrend
+}
+
+// weakArea groups the weaknesses of the tasks with the same tag.
+type weakArea struct {
+	Tag   string
+	Score float64
+	Tasks []*taskWeakness
+}
+
+// GetRecommendations finds the tags where the user's active tasks are the
+// weakest and suggests the tasks and catalog exercises to practice for each.
+func (s *analyticsService) GetRecommendations(ctx context.Context, userID uuid.UUID) (*GetRecommendationsResponse, error) {
+	tasks, err := s.taskRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get tasks from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	now := time.Now()
+	entries, err := s.statsRepo.GetPracticeEntriesSince(ctx, userID, now.AddDate(0, 0, -weaknessWindowDays), now)
+	if err != nil {
+		s.log.Error("failed to get practice entries from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	bestBPM, err := s.statsRepo.GetBestBPMs(ctx, userID, now)
+	if err != nil {
+		s.log.Error("failed to get best bpms from repository", "userID", userID, "error", err)
+		return nil, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	weaknesses := weighTasks(tasks, entries, bestBPM, s.getTrendsByTask(ctx, userID))
+	result := recommendPractice(findWeakAreas(weaknesses))
+
+	return &result, nil
+}
+
+// weighTasks scores the active tasks that have been practiced. Confidence
+// comes from the recent entries, the best tempo from the all-time bestBPM.
+func weighTasks(tasks []Task, entries []PracticeEntry, bestBPM map[uuid.UUID]int, trends map[uuid.UUID]*TaskTrend) []taskWeakness {
+	byTask := make(map[uuid.UUID][]PracticeEntry)
+	for _, entry := range entries {
+		byTask[entry.TaskID] = append(byTask[entry.TaskID], entry)
+	}
+
+	result := make([]taskWeakness, 0, len(tasks))
+	for i := range tasks {
+		task := &tasks[i]
+		best, practiced := bestBPM[task.ID]
+		if task.IsCompleted || !practiced {
+			continue
+		}
+
+		taskEntries := byTask[task.ID]
+
+		// Entries are oldest first, so the latest sessions are at the end.
+		rated := make([]PracticeEntry, 0, weaknessSessions)
+		for j := len(taskEntries) - 1; j >= 0 && len(rated) < weaknessSessions; j-- {
+			if taskEntries[j].Confidence > 0 {
+				rated = append(rated, taskEntries[j])
+			}
+		}
+
+		weakness := taskWeakness{
+			Task:       task,
+			Confidence: averageEntryConfidence(rated),
+			BestBPM:    best,
+			Trend:      trends[task.ID],
+		}
+		weakness.Score = weaknessScore(&weakness)
+
+		result = append(result, weakness)
+	}
+
+	return result
+}
+
+// weaknessScore is from 0 to 1. Parts without data, such as the confidence of
+// a task never rated, are left out of the weighting.
+func weaknessScore(weakness *taskWeakness) float64 {
+	sum, weight := 0.0, weaknessTrendWeight
+
+	if weakness.Confidence > 0 {
+		sum += weaknessConfidenceWeight * (5 - weakness.Confidence) / 4
+		weight += weaknessConfidenceWeight
+	}
+
+	if progress := tempoProgress(weakness); progress > 0 {
+		sum += weaknessTempoWeight * (1 - progress)
+		weight += weaknessTempoWeight
+	}
+
+	if weakness.Trend != nil {
+		switch weakness.Trend.Status {
+		case TrendStatusRegression:
+			sum += weaknessTrendWeight
+		case TrendStatusPlateau:
+			sum += weaknessTrendWeight / 2
+		}
+	}
+
+	return sum / weight
+}
+
+// tempoProgress is the share of the target tempo reached, or 0 when the task
+// has no target or no tempo.
+func tempoProgress(weakness *taskWeakness) float64 {
+	if weakness.Task.TargetBPM <= 0 || weakness.BestBPM <= 0 {
+		return 0
+	}
+
+	return math.Min(1, float64(weakness.BestBPM)/float64(weakness.Task.TargetBPM))
+}
+
+// findWeakAreas groups the tasks by tag and returns the weakest tags, weakest
+// first. Tags are compared case-insensitively.
+func findWeakAreas(weaknesses []taskWeakness) []weakArea {
+	byTag := make(map[string]*weakArea)
+	for i := range weaknesses {
+		for _, tag := range weaknesses[i].Task.Tags {
+			key := strings.ToLower(strings.TrimSpace(tag))
+			if key == "" {
+				continue
+			}
+
+			area, ok := byTag[key]
+			if !ok {
+				area = &weakArea{Tag: strings.TrimSpace(tag)}
+				byTag[key] = area
+			}
+			area.Tasks = append(area.Tasks, &weaknesses[i])
+		}
+	}
+
+	areas := make([]weakArea, 0, len(byTag))
+	for _, area := range byTag {
+		for _, weakness := range area.Tasks {
+			area.Score += weakness.Score
+		}
+		area.Score /= float64(len(area.Tasks))
+
+		if area.Score >= weakAreaMinScore {
+			areas = append(areas, *area)
+		}
+	}
+
+	sort.Slice(areas, func(i, j int) bool {
+		if areas[i].Score != areas[j].Score {
+			return areas[i].Score > areas[j].Score
+		}
+		return areas[i].Tag < areas[j].Tag
+	})
+
+	return areas[:min(len(areas), weakAreaLimit)]
+}
+
+// recommendPractice suggests the weakest tasks of every area, then the
+// catalog exercises for it that the user does not have as a task yet. A task
+// is suggested for one area only.
+func recommendPractice(areas []weakArea) GetRecommendationsResponse {
+	result := GetRecommendationsResponse{
+		WeakAreas: make([]GetWeakAreaResponse, 0, len(areas)),
+		Items:     make([]GetRecommendationItemResponse, 0),
+	}
+
+	owned := make(map[string]bool)
+	for _, area := range areas {
+		for _, weakness := range area.Tasks {
+			owned[strings.ToLower(weakness.Task.Title)] = true
+		}
+	}
+
+	suggested := make(map[uuid.UUID]bool)
+	for _, area := range areas {
+		summary := weakAreaToResponse(&area)
+		result.WeakAreas = append(result.WeakAreas, summary)
+
+		tasks := append([]*taskWeakness(nil), area.Tasks...)
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return tasks[i].Score > tasks[j].Score
+		})
+
+		count := 0
+		for _, weakness := range tasks {
+			if count == weakAreaTasks || weakness.Score < weakAreaMinScore {
+				break
+			}
+			if suggested[weakness.Task.ID] {
+				continue
+			}
+			suggested[weakness.Task.ID] = true
+			count++
+
+			result.Items = append(result.Items, GetRecommendationItemResponse{
+				Kind:      string(RecommendationKindTask),
+				Tag:       area.Tag,
+				TaskID:    weakness.Task.ID.String(),
+				Title:     weakness.Task.Title,
+				TargetBPM: weakness.Task.TargetBPM,
+				Rationale: fmt.Sprintf("Одна из самых слабых задач в «%s»: %s.", area.Tag, strings.Join(weaknessReasons(weakness), ", ")),
+			})
+		}
+
+		count = 0
+		for _, exercise := range exerciseCatalog {
+			if count == weakAreaExercises {
+				break
+			}
+			if owned[strings.ToLower(exercise.Title)] || !exerciseTargets(&exercise, area.Tag) {
+				continue
+			}
+			owned[strings.ToLower(exercise.Title)] = true
+			count++
+
+			result.Items = append(result.Items, GetRecommendationItemResponse{
+				Kind:        string(RecommendationKindExercise),
+				Tag:         area.Tag,
+				ExerciseID:  exercise.ID,
+				Title:       exercise.Title,
+				Description: exercise.Description,
+				TargetBPM:   exercise.TargetBPM,
+				Rationale:   fmt.Sprintf("Упражнение для «%s», одной из самых слабых областей: %s.", area.Tag, summary.Rationale),
+			})
+		}
+	}
+
+	return result
+}
+
+func weakAreaToResponse(area *weakArea) GetWeakAreaResponse {
+	result := GetWeakAreaResponse{
+		Tag:   area.Tag,
+		Score: math.Round(area.Score*100) / 100,
+		Tasks: len(area.Tasks),
+	}
+
+	confidence, rated := 0.0, 0
+	progress, withTarget := 0.0, 0
+	for _, weakness := range area.Tasks {
+		if weakness.Confidence > 0 {
+			confidence += weakness.Confidence
+			rated++
+		}
+		if p := tempoProgress(weakness); p > 0 {
+			progress += p
+			withTarget++
+		}
+		if weakness.Trend != nil {
+			result.Stalled++
+		}
+	}
+
+	reasons := make([]string, 0, 3)
+	if rated > 0 {
+		result.Confidence = math.Round(confidence/float64(rated)*10) / 10
+		reasons = append(reasons, fmt.Sprintf("средняя уверенность %.1f из 5", result.Confidence))
+	}
+	if withTarget > 0 {
+		result.Progress = math.Round(progress/float64(withTarget)*100) / 100
+		reasons = append(reasons, fmt.Sprintf("в среднем %d%% целевого темпа", int(math.Round(result.Progress*100))))
+	}
+	if result.Stalled > 0 {
+		reasons = append(reasons, fmt.Sprintf("темп не растёт в %d из %d задач", result.Stalled, result.Tasks))
+	}
+	result.Rationale = strings.Join(reasons, ", ")
+
+	return result
+}
+
+func weaknessReasons(weakness *taskWeakness) []string {
+	reasons := make([]string, 0, 3)
+	if weakness.Confidence > 0 {
+		reasons = append(reasons, fmt.Sprintf("уверенность %.1f из 5", weakness.Confidence))
+	}
+	if tempoProgress(weakness) > 0 {
+		reasons = append(reasons, fmt.Sprintf("темп %d из %d BPM", weakness.BestBPM, weakness.Task.TargetBPM))
+	}
+	if weakness.Trend != nil {
+		reasons = append(reasons, weakness.Trend.Reason)
+	}
+
+	return reasons
+}
+
+func exerciseTargets(exercise *catalogExercise, tag string) bool {
+	for _, target := range exercise.Tags {
+		if strings.EqualFold(target, tag) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,211 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWeaknessScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		confidence float64
+		bestBPM    int
+		targetBPM  int
+		trend      TrendStatus
+		want       float64
+	}{
+		{name: "all parts", confidence: 3, bestBPM: 80, targetBPM: 100, want: 0.31},
+		{name: "never rated", bestBPM: 50, targetBPM: 100, want: 0.3},
+		{name: "no target", confidence: 1, bestBPM: 80, want: 0.5 / 0.7},
+		{name: "no tempo", confidence: 1, targetBPM: 100, want: 0.5 / 0.7},
+		{name: "no data", targetBPM: 100},
+		{name: "confident and at the target", confidence: 5, bestBPM: 120, targetBPM: 100},
+		{name: "regression", confidence: 5, bestBPM: 100, targetBPM: 100, trend: TrendStatusRegression, want: 0.2},
+		{name: "plateau without other data", trend: TrendStatusPlateau, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weakness := taskWeakness{
+				Task:       &Task{TargetBPM: tt.targetBPM},
+				Confidence: tt.confidence,
+				BestBPM:    tt.bestBPM,
+			}
+			if tt.trend != "" {
+				weakness.Trend = &TaskTrend{Status: tt.trend}
+			}
+
+			if got := weaknessScore(&weakness); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("weaknessScore = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeighTasks(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	active := Task{ID: uuid.New(), TargetBPM: 120}
+	completed := Task{ID: uuid.New(), TargetBPM: 120, IsCompleted: true}
+	unpracticed := Task{ID: uuid.New(), TargetBPM: 120}
+
+	entries := make([]PracticeEntry, 0)
+	for i, confidence := range []int{1, 1, 5, 4, 0, 5, 5, 4} {
+		bpm := 80 + i
+		if confidence == 0 {
+			bpm = 96
+		}
+		entries = append(entries, PracticeEntry{TaskID: active.ID, StartTime: start.AddDate(0, 0, i), BPM: bpm, Confidence: confidence})
+	}
+	entries = append(entries, PracticeEntry{TaskID: completed.ID, StartTime: start, BPM: 60, Confidence: 1})
+
+	// The idle task was practiced, but not within the loaded window.
+	idle := Task{ID: uuid.New(), TargetBPM: 120}
+	bestBPM := map[uuid.UUID]int{active.ID: 110, completed.ID: 60, idle.ID: 100}
+
+	trend := &TaskTrend{TaskID: active.ID, Status: TrendStatusPlateau}
+	trends := map[uuid.UUID]*TaskTrend{active.ID: trend}
+
+	result := weighTasks([]Task{active, completed, unpracticed, idle}, entries, bestBPM, trends)
+
+	if len(result) != 2 || result[0].Task.ID != active.ID || result[1].Task.ID != idle.ID {
+		t.Fatalf("weighed %d tasks, want the active practiced tasks only", len(result))
+	}
+	if result[1].Confidence != 0 || result[1].BestBPM != 100 {
+		t.Errorf("idle task: confidence = %v, best tempo = %d, want 0 and 100", result[1].Confidence, result[1].BestBPM)
+	}
+	weakness := result[0]
+	if weakness.Confidence != 4.6 {
+		t.Errorf("confidence = %v, want the average of the latest 5 rated sessions 4.6", weakness.Confidence)
+	}
+	if weakness.BestBPM != 110 {
+		t.Errorf("best tempo = %d, want the all-time best 110", weakness.BestBPM)
+	}
+	if weakness.Trend != trend {
+		t.Errorf("trend = %+v, want %+v", weakness.Trend, trend)
+	}
+	if want := weaknessScore(&weakness); weakness.Score != want {
+		t.Errorf("score = %v, want %v", weakness.Score, want)
+	}
+}
+
+func TestFindWeakAreas(t *testing.T) {
+	weak := func(score float64, tags ...string) taskWeakness {
+		return taskWeakness{Task: &Task{ID: uuid.New(), Tags: tags}, Score: score}
+	}
+
+	tests := []struct {
+		name       string
+		weaknesses []taskWeakness
+		want       []string
+	}{
+		{
+			name:       "tags grouped case-insensitively",
+			weaknesses: []taskWeakness{weak(0.6, "Scales", ""), weak(0.4, " scales ")},
+			want:       []string{"Scales: 2 tasks, 0.50"},
+		},
+		{
+			name:       "areas below the minimum score",
+			weaknesses: []taskWeakness{weak(0.6, "scales"), weak(0.2, "rhythm"), weak(0.25, "chords")},
+			want:       []string{"scales: 1 tasks, 0.60", "chords: 1 tasks, 0.25"},
+		},
+		{
+			name:       "weakest areas first",
+			weaknesses: []taskWeakness{weak(0.5, "b"), weak(0.9, "d"), weak(0.5, "a"), weak(0.3, "c")},
+			want:       []string{"d: 1 tasks, 0.90", "a: 1 tasks, 0.50", "b: 1 tasks, 0.50"},
+		},
+		{
+			name:       "no tags",
+			weaknesses: []taskWeakness{weak(0.9)},
+			want:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, area := range findWeakAreas(tt.weaknesses) {
+				got = append(got, fmt.Sprintf("%s: %d tasks, %.2f", area.Tag, len(area.Tasks), area.Score))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("areas = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendPractice(t *testing.T) {
+	weak := func(title string, score float64) *taskWeakness {
+		return &taskWeakness{Task: &Task{ID: uuid.New(), Title: title, TargetBPM: 100}, Score: score}
+	}
+	area := func(tag string, tasks ...*taskWeakness) weakArea {
+		return weakArea{Tag: tag, Score: 0.5, Tasks: tasks}
+	}
+	shared := weak("Etude", 0.8)
+
+	tests := []struct {
+		name  string
+		areas []weakArea
+		want  []string
+	}{
+		{
+			name:  "weakest tasks first",
+			areas: []weakArea{area("scales", weak("A", 0.4), weak("B", 0.9), weak("C", 0.6))},
+			want:  []string{"task B @ scales", "task C @ scales", "exercise scales-thirds @ scales", "exercise scales-rhythms @ scales"},
+		},
+		{
+			name:  "tasks below the minimum score",
+			areas: []weakArea{area("Scales", weak("A", 0.5), weak("B", 0.1))},
+			want:  []string{"task A @ Scales", "exercise scales-thirds @ Scales", "exercise scales-rhythms @ Scales"},
+		},
+		{
+			name:  "task in two areas",
+			areas: []weakArea{area("scales", shared), area("technique", shared, weak("Sonata", 0.5))},
+			want: []string{
+				"task Etude @ scales", "exercise scales-thirds @ scales", "exercise scales-rhythms @ scales",
+				"task Sonata @ technique", "exercise arpeggios-broken @ technique", "exercise technique-chromatic @ technique",
+			},
+		},
+		{
+			name:  "exercise the user already has",
+			areas: []weakArea{area("scales", weak("гаммы ТЕРЦИЯМИ", 0.6))},
+			want:  []string{"task гаммы ТЕРЦИЯМИ @ scales", "exercise scales-rhythms @ scales"},
+		},
+		{
+			name:  "no exercises for the tag",
+			areas: []weakArea{area("ballads", weak("A", 0.6))},
+			want:  []string{"task A @ ballads"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := recommendPractice(tt.areas)
+
+			got := make([]string, 0, len(result.Items))
+			for _, item := range result.Items {
+				name := item.Title
+				if item.Kind == string(RecommendationKindExercise) {
+					name = item.ExerciseID
+				}
+				got = append(got, fmt.Sprintf("%s %s @ %s", item.Kind, name, item.Tag))
+
+				if item.Rationale == "" {
+					t.Errorf("%s has no rationale", name)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %q, want %q", got, tt.want)
+			}
+			if len(result.WeakAreas) != len(tt.areas) {
+				t.Errorf("weak areas = %d, want %d", len(result.WeakAreas), len(tt.areas))
+			}
+		})
+	}
+}
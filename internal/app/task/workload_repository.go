@@ -0,0 +1,109 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WorkloadRepository keeps track of the overpractice warnings sent to the
+// users.
+type WorkloadRepository interface {
+	GetCandidates(ctx context.Context, since time.Time) ([]WorkloadCandidate, error)
+	GetNotifiedAt(ctx context.Context, userID uuid.UUID) (map[WorkloadWarningKind]time.Time, error)
+	MarkNotified(ctx context.Context, userID uuid.UUID, kind WorkloadWarningKind, at time.Time) error
+}
+
+type workloadRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWorkloadRepository(pool *pgxpool.Pool) WorkloadRepository {
+	return &workloadRepository{pool}
+}
+
+// GetCandidates returns the users with a finished session since the given
+// time.
+func (r *workloadRepository) GetCandidates(ctx context.Context, since time.Time) ([]WorkloadCandidate, error) {
+	query := `
+		SELECT u.id, u.email, u.time_zone
+		FROM users u
+		WHERE EXISTS (
+			SELECT 1
+			FROM sessions s
+			JOIN tasks t ON t.id = s.task_id
+			WHERE t.user_id = u.id AND s.status = 'finished' AND s.start_time >= $1
+		)
+		ORDER BY u.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]WorkloadCandidate, 0)
+	for rows.Next() {
+		var candidate WorkloadCandidate
+		err := rows.Scan(
+			&candidate.UserID,
+			&candidate.Email,
+			&candidate.TimeZone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workload candidate: %w", err)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+// GetNotifiedAt returns when the user was last warned, by kind of warning.
+func (r *workloadRepository) GetNotifiedAt(ctx context.Context, userID uuid.UUID) (map[WorkloadWarningKind]time.Time, error) {
+	query := `
+		SELECT kind, notified_at
+		FROM workload_alerts
+		WHERE user_id = $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[WorkloadWarningKind]time.Time)
+	for rows.Next() {
+		var kind WorkloadWarningKind
+		var notifiedAt time.Time
+		if err := rows.Scan(&kind, &notifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workload alert: %w", err)
+		}
+
+		result[kind] = notifiedAt
+	}
+
+	return result, rows.Err()
+}
+
+func (r *workloadRepository) MarkNotified(ctx context.Context, userID uuid.UUID, kind WorkloadWarningKind, at time.Time) error {
+	query := `
+		INSERT INTO workload_alerts(user_id, kind, notified_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, kind) DO UPDATE
+		SET notified_at = EXCLUDED.notified_at
+	`
+
+	_, err := r.pool.Exec(ctx, query, userID, kind, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark workload alert notified: %w", err)
+	}
+
+	return nil
+}
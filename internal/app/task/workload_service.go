@@ -0,0 +1,179 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/events"
+)
+
+const (
+	// The acute load is the practice of the last workloadAcuteDays; the
+	// chronic load is the weekly average over the last workloadChronicDays.
+	workloadAcuteDays   = 7
+	workloadChronicDays = 28
+
+	// An acute:chronic ratio above workloadSpikeRatio is a sudden jump in
+	// volume, unless the week is too short to matter.
+	workloadSpikeRatio      = 1.5
+	workloadMinAcuteMinutes = 60
+
+	// Pain or tension rated workloadPainLevel or higher in workloadPainReports
+	// sessions of the last week is a warning sign.
+	workloadPainLevel   = 3
+	workloadPainReports = 2
+)
+
+// CheckWorkloads warns the users who practiced within the last week about load
+// spikes and repeated pain. A kind of warning is sent to a user at most once
+// per cooldown. It returns the number of emails sent.
+func (s *analyticsService) CheckWorkloads(ctx context.Context, cooldown time.Duration) (int, error) {
+	now := time.Now()
+
+	candidates, err := s.workloadRepo.GetCandidates(ctx, now.AddDate(0, 0, -workloadAcuteDays))
+	if err != nil {
+		s.log.Error("failed to get workload candidates from repository", "error", err)
+		return 0, fmt.Errorf(errors.ErrFailedToLoadData)
+	}
+
+	sent := 0
+	for _, candidate := range candidates {
+		loc, err := time.LoadLocation(candidate.TimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+
+		// Only the sessions within the chronic window count.
+		end := startOfDay(now.In(loc)).AddDate(0, 0, 1)
+		entries, err := s.statsRepo.GetPracticeEntriesSince(ctx, candidate.UserID, end.AddDate(0, 0, -workloadChronicDays), now)
+		if err != nil {
+			s.log.Error("failed to get practice entries from repository", "userID", candidate.UserID, "error", err)
+			continue
+		}
+
+		workload := analyzeWorkload(entries, end)
+		if len(workload.Warnings) == 0 {
+			continue
+		}
+
+		notifiedAt, err := s.workloadRepo.GetNotifiedAt(ctx, candidate.UserID)
+		if err != nil {
+			s.log.Error("failed to get workload alerts from repository", "userID", candidate.UserID, "error", err)
+			continue
+		}
+
+		warnings := make([]GetWorkloadWarningResponse, 0, len(workload.Warnings))
+		for _, warning := range workload.Warnings {
+			if at, ok := notifiedAt[WorkloadWarningKind(warning.Kind)]; ok && now.Sub(at) < cooldown {
+				continue
+			}
+			warnings = append(warnings, warning)
+		}
+
+		if len(warnings) > 0 && s.notifyWorkload(ctx, &candidate, &workload, warnings, now) {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// notifyWorkload publishes the warnings for the mail service. Warnings that
+// could not be sent are retried on the next run.
+func (s *analyticsService) notifyWorkload(ctx context.Context, candidate *WorkloadCandidate, workload *GetWorkloadResponse, warnings []GetWorkloadWarningResponse, now time.Time) bool {
+	if s.rabbitmq == nil {
+		s.log.Warn("event service not available - workload email not sent", "userID", candidate.UserID)
+		return false
+	}
+
+	data := map[string]interface{}{
+		"user_email":      candidate.Email,
+		"acute_minutes":   strconv.Itoa(workload.AcuteMinutes),
+		"chronic_minutes": strconv.Itoa(workload.ChronicMinutes),
+	}
+	for _, warning := range warnings {
+		data[warning.Kind] = warning.Message
+	}
+
+	event := events.EmailEvent{
+		To:       candidate.Email,
+		Template: "practice_workload",
+		Subject:  "Берегите руки",
+		Data:     data,
+	}
+
+	if err := s.rabbitmq.PublishEmail(event); err != nil {
+		s.log.Error("failed to publish email event", "error", err)
+		return false
+	}
+
+	for _, warning := range warnings {
+		if err := s.workloadRepo.MarkNotified(ctx, candidate.UserID, WorkloadWarningKind(warning.Kind), now); err != nil {
+			s.log.Error("failed to mark workload alert notified in repository", "userID", candidate.UserID, "error", err)
+		}
+	}
+
+	return true
+}
+
+// analyzeWorkload sums up the sessions started within the weeks before end.
+// The ratio is only computed when there was practice before the last week,
+// so that the first week of practice is not a spike.
+func analyzeWorkload(entries []PracticeEntry, end time.Time) GetWorkloadResponse {
+	acuteStart := end.AddDate(0, 0, -workloadAcuteDays)
+	chronicStart := end.AddDate(0, 0, -workloadChronicDays)
+
+	acute, earlier := 0, 0
+	painReports := 0
+	for i := range entries {
+		entry := &entries[i]
+		if entry.StartTime.Before(chronicStart) || !entry.StartTime.Before(end) {
+			continue
+		}
+
+		if entry.StartTime.Before(acuteStart) {
+			earlier += entry.GetDurationSeconds()
+			continue
+		}
+
+		acute += entry.GetDurationSeconds()
+		if entry.Pain >= workloadPainLevel || entry.Tension >= workloadPainLevel {
+			painReports++
+		}
+	}
+
+	weeks := float64(workloadChronicDays) / workloadAcuteDays
+	chronic := float64(acute+earlier) / weeks
+
+	result := GetWorkloadResponse{
+		AcuteMinutes:   acute / 60,
+		ChronicMinutes: int(math.Round(chronic / 60)),
+		PainReports:    painReports,
+		Warnings:       make([]GetWorkloadWarningResponse, 0),
+	}
+
+	if earlier > 0 {
+		ratio := float64(acute) / chronic
+		result.Ratio = math.Round(ratio*100) / 100
+
+		if ratio > workloadSpikeRatio && result.AcuteMinutes >= workloadMinAcuteMinutes {
+			result.Warnings = append(result.Warnings, GetWorkloadWarningResponse{
+				Kind:    string(WorkloadWarningLoadSpike),
+				Message: fmt.Sprintf("за последнюю неделю вы занимались %d мин. — в %.1f раза больше, чем в среднем за четыре недели (%d мин.)", result.AcuteMinutes, ratio, result.ChronicMinutes),
+			})
+		}
+	}
+
+	if painReports >= workloadPainReports {
+		result.Warnings = append(result.Warnings, GetWorkloadWarningResponse{
+			Kind:    string(WorkloadWarningPain),
+			Message: fmt.Sprintf("за последнюю неделю боль или напряжение отмечены в %d занятиях", painReports),
+		})
+	}
+
+	return result
+}
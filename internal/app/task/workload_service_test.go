@@ -0,0 +1,119 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeWorkload(t *testing.T) {
+	end := time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC)
+	session := func(daysAgo, minutes, pain, tension int) PracticeEntry {
+		start := end.AddDate(0, 0, -daysAgo).Add(18 * time.Hour)
+		return PracticeEntry{StartTime: start, EndTime: start.Add(time.Duration(minutes) * time.Minute), Pain: pain, Tension: tension}
+	}
+	played := func(daysAgo, minutes int) PracticeEntry {
+		return session(daysAgo, minutes, 0, 0)
+	}
+
+	tests := []struct {
+		name        string
+		entries     []PracticeEntry
+		wantAcute   int
+		wantChronic int
+		wantPain    int
+		wantRatio   float64
+		wantKinds   []WorkloadWarningKind
+	}{
+		{
+			name:        "first week of practice",
+			entries:     []PracticeEntry{played(1, 60), played(2, 60), played(3, 60)},
+			wantAcute:   180,
+			wantChronic: 45,
+		},
+		{
+			name:        "steady load",
+			entries:     []PracticeEntry{played(3, 60), played(10, 60), played(17, 60), played(24, 60)},
+			wantAcute:   60,
+			wantChronic: 60,
+			wantRatio:   1,
+		},
+		{
+			name:        "load spike",
+			entries:     []PracticeEntry{played(1, 60), played(2, 60), played(3, 60), played(10, 60), played(17, 60), played(24, 60)},
+			wantAcute:   180,
+			wantChronic: 90,
+			wantRatio:   2,
+			wantKinds:   []WorkloadWarningKind{WorkloadWarningLoadSpike},
+		},
+		{
+			name:        "spike in a short week",
+			entries:     []PracticeEntry{played(1, 50), played(20, 10)},
+			wantAcute:   50,
+			wantChronic: 15,
+			wantRatio:   3.33,
+		},
+		{
+			name:        "pain in two sessions",
+			entries:     []PracticeEntry{session(1, 30, 3, 0), session(2, 30, 0, 4), played(10, 100)},
+			wantAcute:   60,
+			wantChronic: 40,
+			wantPain:    2,
+			wantRatio:   1.5,
+			wantKinds:   []WorkloadWarningKind{WorkloadWarningPain},
+		},
+		{
+			name:        "pain before the last week",
+			entries:     []PracticeEntry{session(1, 30, 3, 0), session(2, 30, 2, 2), session(10, 60, 5, 5)},
+			wantAcute:   60,
+			wantChronic: 30,
+			wantPain:    1,
+			wantRatio:   2,
+			wantKinds:   []WorkloadWarningKind{WorkloadWarningLoadSpike},
+		},
+		{
+			name:        "sessions outside the window",
+			entries:     []PracticeEntry{played(0, 120), played(1, 60), played(29, 200)},
+			wantAcute:   60,
+			wantChronic: 15,
+		},
+		{
+			name:        "spike and pain",
+			entries:     []PracticeEntry{session(1, 90, 4, 0), session(2, 90, 0, 3), played(15, 60)},
+			wantAcute:   180,
+			wantChronic: 60,
+			wantPain:    2,
+			wantRatio:   3,
+			wantKinds:   []WorkloadWarningKind{WorkloadWarningLoadSpike, WorkloadWarningPain},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzeWorkload(tt.entries, end)
+
+			if result.AcuteMinutes != tt.wantAcute || result.ChronicMinutes != tt.wantChronic {
+				t.Errorf("load = %d/%d min, want %d/%d min", result.AcuteMinutes, result.ChronicMinutes, tt.wantAcute, tt.wantChronic)
+			}
+			if result.PainReports != tt.wantPain {
+				t.Errorf("pain reports = %d, want %d", result.PainReports, tt.wantPain)
+			}
+			if result.Ratio != tt.wantRatio {
+				t.Errorf("ratio = %v, want %v", result.Ratio, tt.wantRatio)
+			}
+
+			kinds := make([]WorkloadWarningKind, 0, len(result.Warnings))
+			for _, warning := range result.Warnings {
+				if warning.Message == "" {
+					t.Errorf("%s warning has no message", warning.Kind)
+				}
+				kinds = append(kinds, WorkloadWarningKind(warning.Kind))
+			}
+			if len(kinds) > 0 || len(tt.wantKinds) > 0 {
+				if !reflect.DeepEqual(kinds, tt.wantKinds) {
+					t.Errorf("warnings = %v, want %v", kinds, tt.wantKinds)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package user
 
 type GetUserResponse struct {
-	ID        string `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Username  string `json:"username"`
-	AvatarURL string `json:"avatar_url"`
+	ID                  string `json:"id"`
+	FirstName           string `json:"first_name"`
+	LastName            string `json:"last_name"`
+	Username            string `json:"username"`
+	TimeZone            string `json:"time_zone"`
+	TempoAggressiveness string `json:"tempo_aggressiveness"`
+	AvatarURL           string `json:"avatar_url"`
+	Version             int    `json:"version"`
 }
 
 type GetUploadURLResponse struct {
@@ -16,8 +19,13 @@ type ConfirmUploadAvatarResponse struct {
 	URL string
 }
 
+// SaveUserRequest leaves the time zone and the tempo aggressiveness as they
+// are when empty. The aggressiveness tunes the next-tempo recommendations.
 type SaveUserRequest struct {
-	FirstName string `json:"first_name" validate:"required,min=1,max=50"`
-	LastName  string `json:"last_name" validate:"required,min=1,max=50"`
-	Username  string `json:"username" validate:"required,min=1,max=50"`
+	FirstName           string `json:"first_name" validate:"required,min=1,max=50"`
+	LastName            string `json:"last_name" validate:"required,min=1,max=50"`
+	Username            string `json:"username" validate:"required,min=1,max=50"`
+	TimeZone            string `json:"time_zone" validate:"omitempty,timezone"`
+	TempoAggressiveness string `json:"tempo_aggressiveness" validate:"omitempty,oneof=cautious moderate aggressive"`
+	Version             int    `json:"version"`
 }
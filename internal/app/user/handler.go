@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/RuLap/trackmus-api/internal/pkg/httpx"
 	validation "github.com/RuLap/trackmus-api/internal/pkg/validator"
 	"github.com/darahayes/go-boom"
 	"github.com/go-chi/chi/v5"
@@ -36,6 +37,7 @@ func (h *Handler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	httpx.SetETag(w, response.Version)
 	h.sendJSON(w, response, http.StatusOK)
 }
 
@@ -54,14 +56,26 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromContext(r.Context())
 	if err != nil {
 		boom.BadRequest(w, err)
+		return
+	}
+
+	req.Version, err = httpx.GetVersion(r, req.Version)
+	if err != nil {
+		httpx.SendVersionError(w, err)
+		return
 	}
 
 	response, err := h.service.UpdateUser(r.Context(), &req, *userID)
 	if err != nil {
+		if conflict, ok := errors.AsConflict(err); ok {
+			httpx.SendConflict(w, conflict)
+			return
+		}
 		boom.Internal(w, err)
 		return
 	}
 
+	httpx.SetETag(w, response.Version)
 	h.sendJSON(w, response, http.StatusOK)
 }
 
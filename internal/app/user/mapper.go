@@ -1,19 +1,28 @@
 package user
 
+import "github.com/google/uuid"
+
 func UserToGetResponse(model *User, avatarURL string) GetUserResponse {
 	return GetUserResponse{
-		ID:        model.ID.String(),
-		FirstName: model.FirstName,
-		LastName:  model.LastName,
-		Username:  model.Username,
-		AvatarURL: avatarURL,
+		ID:                  model.ID.String(),
+		FirstName:           model.FirstName,
+		LastName:            model.LastName,
+		Username:            model.Username,
+		TimeZone:            model.TimeZone,
+		TempoAggressiveness: model.TempoAggressiveness,
+		AvatarURL:           avatarURL,
+		Version:             model.Version,
 	}
 }
 
-func SaveRequestToUser(req *SaveUserRequest) User {
+func SaveRequestToUser(req *SaveUserRequest, id uuid.UUID) User {
 	return User{
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Username:  req.Username,
+		ID:                  id,
+		FirstName:           req.FirstName,
+		LastName:            req.LastName,
+		Username:            req.Username,
+		TimeZone:            req.TimeZone,
+		TempoAggressiveness: req.TempoAggressiveness,
+		Version:             req.Version,
 	}
 }
@@ -3,8 +3,11 @@ package user
 import "github.com/google/uuid"
 
 type User struct {
-	ID        uuid.UUID `db:"id"`
-	FirstName string    `db:"first_name"`
-	LastName  string    `db:"last_name"`
-	Username  string    `db:"username"`
+	ID                  uuid.UUID `db:"id"`
+	FirstName           string    `db:"first_name"`
+	LastName            string    `db:"last_name"`
+	Username            string    `db:"username"`
+	TimeZone            string    `db:"time_zone"`
+	TempoAggressiveness string    `db:"tempo_aggressiveness"`
+	Version             int       `db:"version"`
 }
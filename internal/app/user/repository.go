@@ -2,9 +2,12 @@ package user
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -23,7 +26,7 @@ func NewRepository(pool *pgxpool.Pool) Repository {
 
 func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, first_name, last_name, username
+		SELECT id, first_name, last_name, username, time_zone, tempo_aggressiveness, version
 		FROM users
 		WHERE id = $1
 	`
@@ -38,6 +41,9 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 		&user.FirstName,
 		&user.LastName,
 		&user.Username,
+		&user.TimeZone,
+		&user.TempoAggressiveness,
+		&user.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -49,23 +55,38 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 func (r *repository) Update(ctx context.Context, model *User) (*User, error) {
 	query := `
 		UPDATE users
-		SET first_name = $2
-			last_name = $3
-			username = $4
-		WHERE id = $1
+		SET first_name = $2,
+			last_name = $3,
+			username = $4,
+			time_zone = COALESCE(NULLIF($6, ''), time_zone),
+			tempo_aggressiveness = COALESCE(NULLIF($7, ''), tempo_aggressiveness),
+			version = version + 1
+		WHERE id = $1 AND version = $5
+		RETURNING time_zone, tempo_aggressiveness
 	`
 
-	_, err := r.pool.Exec(
+	err := r.pool.QueryRow(
 		ctx,
 		query,
 		model.ID,
 		model.FirstName,
 		model.LastName,
 		model.Username,
+		model.Version,
+		model.TimeZone,
+		model.TempoAggressiveness,
+	).Scan(
+		&model.TimeZone,
+		&model.TempoAggressiveness,
 	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.ErrVersionConflict
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	model.Version++
+
 	return model, nil
 }
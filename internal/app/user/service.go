@@ -56,10 +56,18 @@ func (s *service) GetUserByID(ctx context.Context, id uuid.UUID) (*GetUserRespon
 }
 
 func (s *service) UpdateUser(ctx context.Context, req *SaveUserRequest, id uuid.UUID) (*GetUserResponse, error) {
-	model := SaveRequestToUser(req)
+	model := SaveRequestToUser(req, id)
 
 	user, err := s.repo.Update(ctx, &model)
 	if err != nil {
+		if errors.IsVersionConflict(err) {
+			s.log.Info("user version conflict", "id", id, "version", req.Version)
+			current, err := s.GetUserByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &errors.ConflictError{Current: current}
+		}
 		s.log.Error("failed to update user in repository", "req", req, "error", err)
 		return nil, fmt.Errorf(errors.ErrFailedToSaveData)
 	}
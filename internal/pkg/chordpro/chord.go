@@ -0,0 +1,168 @@
+package chordpro
+
+import (
+	"strings"
+)
+
+var (
+	sharpNotes = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNotes  = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+
+	// flatKeys are the keys conventionally written with flats. Chords of a
+	// song in one of them are spelled with flats after transposition.
+	flatKeys = map[string]bool{
+		"F": true, "Bb": true, "Eb": true, "Ab": true, "Db": true, "Gb": true,
+		"Dm": true, "Gm": true, "Cm": true, "Fm": true, "Bbm": true, "Ebm": true,
+	}
+
+	// noChords are tokens allowed in brackets that are not transposed.
+	noChords = map[string]bool{"N.C.": true, "NC": true, "N.C": true, "%": true, "|": true, "-": true}
+)
+
+const qualityChars = "abdgijmnorsuM0123456789#+-()°ø^,."
+
+type Chord struct {
+	Root    string
+	Quality string
+	Bass    string
+}
+
+// ParseChord splits a chord name like "F#m7b5/C#" into root, quality and bass.
+func ParseChord(name string) (Chord, bool) {
+	root, rest, ok := cutNote(name)
+	if !ok {
+		return Chord{}, false
+	}
+
+	chord := Chord{Root: root}
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		bass, tail, ok := cutNote(rest[i+1:])
+		if !ok || tail != "" {
+			return Chord{}, false
+		}
+		chord.Bass = bass
+		rest = rest[:i]
+	}
+
+	for _, r := range rest {
+		if !strings.ContainsRune(qualityChars, r) {
+			return Chord{}, false
+		}
+	}
+	chord.Quality = rest
+
+	return chord, true
+}
+
+func (c Chord) String() string {
+	if c.Bass == "" {
+		return c.Root + c.Quality
+	}
+
+	return c.Root + c.Quality + "/" + c.Bass
+}
+
+// Transpose shifts the chord by the given number of semitones.
+func (c Chord) Transpose(semitones int, flats bool) Chord {
+	c.Root = transposeNote(c.Root, semitones, flats)
+	if c.Bass != "" {
+		c.Bass = transposeNote(c.Bass, semitones, flats)
+	}
+
+	return c
+}
+
+// TransposeName transposes a chord name, leaving tokens that are not chords
+// untouched.
+func TransposeName(name string, semitones int, flats bool) string {
+	chord, ok := ParseChord(name)
+	if !ok || semitones%12 == 0 {
+		return name
+	}
+
+	return chord.Transpose(semitones, flats).String()
+}
+
+// TransposeKey transposes a key like "Bb" or "F#m" and reports whether the
+// resulting key is written with flats.
+func TransposeKey(key string, semitones int) (string, bool) {
+	chord, ok := ParseChord(key)
+	if !ok {
+		return key, false
+	}
+
+	minor := chord.Quality == "m"
+	flats := chord.Transpose(semitones, true)
+	if minor {
+		if flatKeys[flats.Root+"m"] {
+			return flats.Root + "m", true
+		}
+		return chord.Transpose(semitones, false).Root + "m", false
+	}
+
+	if flatKeys[flats.Root] {
+		return flats.Root, true
+	}
+
+	return chord.Transpose(semitones, false).Root, false
+}
+
+func usesFlats(name string) bool {
+	chord, ok := ParseChord(name)
+	if !ok {
+		return false
+	}
+
+	return strings.HasSuffix(chord.Root, "b") || strings.HasSuffix(chord.Bass, "b")
+}
+
+func isNoChord(name string) bool {
+	return noChords[name]
+}
+
+func cutNote(s string) (string, string, bool) {
+	if s == "" || s[0] < 'A' || s[0] > 'G' {
+		return "", "", false
+	}
+
+	if len(s) > 1 && (s[1] == '#' || s[1] == 'b') {
+		return s[:2], s[2:], true
+	}
+
+	return s[:1], s[1:], true
+}
+
+func transposeNote(note string, semitones int, flats bool) string {
+	index := noteIndex(note)
+	if index < 0 {
+		return note
+	}
+
+	index = ((index+semitones)%12 + 12) % 12
+	if flats {
+		return flatNotes[index]
+	}
+
+	return sharpNotes[index]
+}
+
+func noteIndex(note string) int {
+	for i := range sharpNotes {
+		if sharpNotes[i] == note || flatNotes[i] == note {
+			return i
+		}
+	}
+
+	switch note {
+	case "Cb":
+		return 11
+	case "B#":
+		return 0
+	case "Fb":
+		return 4
+	case "E#":
+		return 5
+	}
+
+	return -1
+}
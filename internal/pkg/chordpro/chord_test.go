@@ -0,0 +1,95 @@
+package chordpro
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Chord
+		wantOK bool
+	}{
+		{name: "C", want: Chord{Root: "C"}, wantOK: true},
+		{name: "F#m7b5/C#", want: Chord{Root: "F#", Quality: "m7b5", Bass: "C#"}, wantOK: true},
+		{name: "Bbmaj7", want: Chord{Root: "Bb", Quality: "maj7"}, wantOK: true},
+		{name: "Dsus4", want: Chord{Root: "D", Quality: "sus4"}, wantOK: true},
+		{name: "G/B", want: Chord{Root: "G", Bass: "B"}, wantOK: true},
+		{name: "H7", wantOK: false},
+		{name: "Cxyz", wantOK: false},
+		{name: "C/Bm", wantOK: false},
+		{name: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chord, ok := ParseChord(tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && chord != tt.want {
+				t.Errorf("chord = %+v, want %+v", chord, tt.want)
+			}
+			if ok && chord.String() != tt.name {
+				t.Errorf("String() = %q, want %q", chord.String(), tt.name)
+			}
+		})
+	}
+}
+
+func TestTransposeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		semitones int
+		flats     bool
+		want      string
+	}{
+		{name: "C", semitones: 2, want: "D"},
+		{name: "A", semitones: 3, want: "C"},
+		{name: "C", semitones: -1, want: "B"},
+		{name: "C", semitones: 1, want: "C#"},
+		{name: "C", semitones: 1, flats: true, want: "Db"},
+		{name: "F#m7/C#", semitones: 2, want: "G#m7/D#"},
+		{name: "Bb", semitones: 14, flats: true, want: "C"},
+		{name: "Cb", semitones: 1, want: "C"},
+		{name: "Eb", semitones: 12, want: "Eb"},
+		{name: "N.C.", semitones: 2, want: "N.C."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TransposeName(tt.name, tt.semitones, tt.flats); got != tt.want {
+				t.Errorf("TransposeName(%q, %d) = %q, want %q", tt.name, tt.semitones, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransposeKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		semitones int
+		want      string
+		wantFlats bool
+	}{
+		{key: "C", semitones: 5, want: "F", wantFlats: true},
+		{key: "C", semitones: 2, want: "D"},
+		{key: "G", semitones: 4, want: "B"},
+		{key: "G", semitones: -1, want: "Gb", wantFlats: true},
+		{key: "A", semitones: 1, want: "Bb", wantFlats: true},
+		{key: "Am", semitones: 5, want: "Dm", wantFlats: true},
+		{key: "Em", semitones: 1, want: "Fm", wantFlats: true},
+		{key: "Dm", semitones: 2, want: "Em"},
+		{key: "Bbm", semitones: 0, want: "Bbm", wantFlats: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s%+d", tt.key, tt.semitones), func(t *testing.T) {
+			got, flats := TransposeKey(tt.key, tt.semitones)
+			if got != tt.want || flats != tt.wantFlats {
+				t.Errorf("TransposeKey(%q, %d) = %q, %v, want %q, %v", tt.key, tt.semitones, got, flats, tt.want, tt.wantFlats)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package chordpro
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// RenderHTML renders the song as an HTML fragment. Chords are placed above
+// the lyrics they belong to; styling is left to the client via CSS classes.
+func RenderHTML(song *Song) string {
+	var b strings.Builder
+
+	b.WriteString(`<div class="chordpro">`)
+	writeTag(&b, "h1", "title", song.Title)
+	writeTag(&b, "h2", "subtitle", song.Subtitle)
+	writeTag(&b, "p", "artist", song.Artist)
+
+	var meta []string
+	if song.Key != "" {
+		meta = append(meta, "Key: "+song.Key)
+	}
+	if song.Capo > 0 {
+		meta = append(meta, fmt.Sprintf("Capo: %d", song.Capo))
+	}
+	if song.Tempo > 0 {
+		meta = append(meta, fmt.Sprintf("Tempo: %d", song.Tempo))
+	}
+	if song.Time != "" {
+		meta = append(meta, "Time: "+song.Time)
+	}
+	writeTag(&b, "p", "meta", strings.Join(meta, " · "))
+
+	for _, section := range song.Sections {
+		fmt.Fprintf(&b, `<section class="section %s">`, html.EscapeString(section.Type))
+		writeTag(&b, "h3", "label", section.Label)
+
+		for _, line := range section.Lines {
+			writeLine(&b, line)
+		}
+
+		b.WriteString(`</section>`)
+	}
+
+	b.WriteString(`</div>`)
+
+	return b.String()
+}
+
+func writeLine(b *strings.Builder, line Line) {
+	switch line.Type {
+	case LineEmpty:
+		b.WriteString(`<div class="line empty"></div>`)
+	case LineComment:
+		writeTag(b, "p", "comment", line.Text)
+	case LineChorusRef:
+		text := line.Text
+		if text == "" {
+			text = "Chorus"
+		}
+		writeTag(b, "p", "chorus-ref", text)
+	case LineTab:
+		writeTag(b, "pre", "tab", line.Text)
+	default:
+		b.WriteString(`<div class="line">`)
+		for _, segment := range line.Segments {
+			b.WriteString(`<span class="segment">`)
+			fmt.Fprintf(b, `<span class="chord">%s</span>`, html.EscapeString(segment.Chord))
+			fmt.Fprintf(b, `<span class="lyrics">%s</span>`, html.EscapeString(segment.Lyrics))
+			b.WriteString(`</span>`)
+		}
+		b.WriteString(`</div>`)
+	}
+}
+
+func writeTag(b *strings.Builder, tag, class, text string) {
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(b, `<%s class="%s">%s</%s>`, tag, class, html.EscapeString(text), tag)
+}
@@ -0,0 +1,368 @@
+package chordpro
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	SectionNone   = "none"
+	SectionVerse  = "verse"
+	SectionChorus = "chorus"
+	SectionBridge = "bridge"
+	SectionTab    = "tab"
+	SectionGrid   = "grid"
+
+	LineLyrics    = "lyrics"
+	LineComment   = "comment"
+	LineTab       = "tab"
+	LineEmpty     = "empty"
+	LineChorusRef = "chorus_ref"
+)
+
+const maxCapo = 11
+
+type Song struct {
+	Title    string
+	Subtitle string
+	Artist   string
+	Composer string
+	Key      string
+	Capo     int
+	Tempo    int
+	Time     string
+	Sections []Section
+}
+
+type Section struct {
+	Type  string
+	Label string
+	Lines []Line
+}
+
+type Line struct {
+	Type     string
+	Text     string
+	Segments []Segment
+}
+
+// Segment is a piece of lyrics with the chord played at its beginning. Either
+// of the fields may be empty.
+type Segment struct {
+	Chord  string
+	Lyrics string
+}
+
+type ParseError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Errors collects every problem found in a document, so that the whole list
+// can be shown to the user at once.
+type Errors []ParseError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+var sectionDirectives = map[string]struct {
+	kind  string
+	start bool
+}{
+	"start_of_verse":  {SectionVerse, true},
+	"sov":             {SectionVerse, true},
+	"end_of_verse":    {SectionVerse, false},
+	"eov":             {SectionVerse, false},
+	"start_of_chorus": {SectionChorus, true},
+	"soc":             {SectionChorus, true},
+	"end_of_chorus":   {SectionChorus, false},
+	"eoc":             {SectionChorus, false},
+	"start_of_bridge": {SectionBridge, true},
+	"sob":             {SectionBridge, true},
+	"end_of_bridge":   {SectionBridge, false},
+	"eob":             {SectionBridge, false},
+	"start_of_tab":    {SectionTab, true},
+	"sot":             {SectionTab, true},
+	"end_of_tab":      {SectionTab, false},
+	"eot":             {SectionTab, false},
+	"start_of_grid":   {SectionGrid, true},
+	"sog":             {SectionGrid, true},
+	"end_of_grid":     {SectionGrid, false},
+	"eog":             {SectionGrid, false},
+}
+
+type parser struct {
+	song    *Song
+	current *Section
+	errs    Errors
+}
+
+// Parse reads a ChordPro document. When the document has problems, the
+// returned error is of type Errors and lists all of them with line numbers.
+func Parse(text string) (*Song, error) {
+	p := &parser{song: &Song{}}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	number := 0
+	for scanner.Scan() {
+		number++
+		p.parseLine(strings.TrimRight(scanner.Text(), " \t\r"), number)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ChordPro document: %w", err)
+	}
+
+	if p.current != nil && p.current.Type != SectionNone {
+		p.fail(number, "section %q is not closed", p.current.Type)
+	}
+	p.closeSection()
+
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+
+	return p.song, nil
+}
+
+func (p *parser) parseLine(text string, number int) {
+	trimmed := strings.TrimSpace(text)
+
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		return
+	case strings.HasPrefix(trimmed, "{"):
+		if !strings.HasSuffix(trimmed, "}") {
+			p.fail(number, "directive is not closed")
+			return
+		}
+		p.parseDirective(trimmed[1:len(trimmed)-1], number)
+	case p.current != nil && (p.current.Type == SectionTab || p.current.Type == SectionGrid):
+		p.addLine(Line{Type: LineTab, Text: text})
+	case trimmed == "":
+		p.addLine(Line{Type: LineEmpty})
+	default:
+		if segments, ok := p.parseLyrics(text, number); ok {
+			p.addLine(Line{Type: LineLyrics, Segments: segments})
+		}
+	}
+}
+
+func (p *parser) parseDirective(body string, number int) {
+	name, value, _ := strings.Cut(body, ":")
+	if !strings.Contains(body, ":") {
+		name, value, _ = strings.Cut(body, " ")
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+
+	if section, ok := sectionDirectives[name]; ok {
+		if section.start {
+			p.startSection(section.kind, value, number)
+		} else {
+			p.endSection(section.kind, number)
+		}
+		return
+	}
+
+	switch name {
+	case "title", "t":
+		p.song.Title = value
+	case "subtitle", "st":
+		p.song.Subtitle = value
+	case "artist":
+		p.song.Artist = value
+	case "composer":
+		p.song.Composer = value
+	case "time":
+		p.song.Time = value
+	case "key":
+		if _, ok := ParseChord(value); !ok {
+			p.fail(number, "invalid key %q", value)
+			return
+		}
+		p.song.Key = value
+	case "capo":
+		capo, err := strconv.Atoi(value)
+		if err != nil || capo < 0 || capo > maxCapo {
+			p.fail(number, "capo must be a number from 0 to %d", maxCapo)
+			return
+		}
+		p.song.Capo = capo
+	case "tempo":
+		tempo, err := strconv.Atoi(value)
+		if err != nil || tempo <= 0 {
+			p.fail(number, "tempo must be a positive number")
+			return
+		}
+		p.song.Tempo = tempo
+	case "comment", "c", "comment_italic", "ci", "comment_box", "cb", "highlight":
+		p.addLine(Line{Type: LineComment, Text: value})
+	case "chorus":
+		p.addLine(Line{Type: LineChorusRef, Text: value})
+	default:
+		if !strings.HasPrefix(name, "x_") && name != "meta" && name != "new_song" && name != "ns" {
+			p.fail(number, "unknown directive %q", name)
+		}
+	}
+}
+
+func (p *parser) parseLyrics(text string, number int) ([]Segment, bool) {
+	var segments []Segment
+	segment := Segment{}
+	ok := true
+
+	for len(text) > 0 {
+		open := strings.IndexByte(text, '[')
+		if open < 0 {
+			segment.Lyrics += text
+			break
+		}
+
+		if closeBracket := strings.IndexByte(text, ']'); closeBracket >= 0 && closeBracket < open {
+			p.fail(number, "unexpected ]")
+			return nil, false
+		}
+		segment.Lyrics += text[:open]
+		text = text[open+1:]
+
+		end := strings.IndexByte(text, ']')
+		if end < 0 {
+			p.fail(number, "chord is not closed")
+			return nil, false
+		}
+		chord := strings.TrimSpace(text[:end])
+		text = text[end+1:]
+
+		switch {
+		case chord == "":
+			p.fail(number, "empty chord")
+			ok = false
+		case strings.ContainsRune(chord, '['):
+			p.fail(number, "nested [ in chord %q", chord)
+			ok = false
+		case !isNoChord(chord):
+			if _, valid := ParseChord(chord); !valid {
+				p.fail(number, "unknown chord %q", chord)
+				ok = false
+			}
+		}
+
+		if segment.Chord != "" || segment.Lyrics != "" {
+			segments = append(segments, segment)
+		}
+		segment = Segment{Chord: chord}
+	}
+
+	if strings.ContainsRune(segment.Lyrics, ']') {
+		p.fail(number, "unexpected ]")
+		return nil, false
+	}
+	if segment.Chord != "" || segment.Lyrics != "" {
+		segments = append(segments, segment)
+	}
+
+	return segments, ok
+}
+
+func (p *parser) startSection(kind, label string, number int) {
+	if p.current != nil && p.current.Type != SectionNone {
+		p.fail(number, "section %q starts before %q is closed", kind, p.current.Type)
+		return
+	}
+
+	p.closeSection()
+	p.current = &Section{Type: kind, Label: label}
+}
+
+func (p *parser) endSection(kind string, number int) {
+	if p.current == nil || p.current.Type != kind {
+		p.fail(number, "end of %q without start", kind)
+		return
+	}
+
+	p.closeSection()
+}
+
+func (p *parser) addLine(line Line) {
+	if p.current == nil {
+		p.current = &Section{Type: SectionNone}
+	}
+
+	p.current.Lines = append(p.current.Lines, line)
+}
+
+// closeSection appends the current section to the song. Blank lines around
+// the section body carry no meaning and are dropped.
+func (p *parser) closeSection() {
+	if p.current == nil {
+		return
+	}
+
+	lines := p.current.Lines
+	for len(lines) > 0 && lines[0].Type == LineEmpty {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1].Type == LineEmpty {
+		lines = lines[:len(lines)-1]
+	}
+	p.current.Lines = lines
+
+	if len(lines) > 0 || p.current.Type != SectionNone {
+		p.song.Sections = append(p.song.Sections, *p.current)
+	}
+	p.current = nil
+}
+
+func (p *parser) fail(line int, format string, args ...interface{}) {
+	p.errs = append(p.errs, ParseError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// Transpose returns a copy of the song with the key and every chord shifted by
+// the given number of semitones.
+func (s *Song) Transpose(semitones int) *Song {
+	result := *s
+	result.Sections = make([]Section, len(s.Sections))
+
+	flats := false
+	if s.Key != "" {
+		result.Key, flats = TransposeKey(s.Key, semitones)
+	}
+
+	for i, section := range s.Sections {
+		lines := make([]Line, len(section.Lines))
+		for j, line := range section.Lines {
+			lines[j] = line
+			if line.Segments == nil {
+				continue
+			}
+
+			lines[j].Segments = make([]Segment, len(line.Segments))
+			for k, segment := range line.Segments {
+				chordFlats := flats
+				if s.Key == "" {
+					chordFlats = usesFlats(segment.Chord)
+				}
+				segment.Chord = TransposeName(segment.Chord, semitones, chordFlats)
+				lines[j].Segments[k] = segment
+			}
+		}
+
+		result.Sections[i] = Section{Type: section.Type, Label: section.Label, Lines: lines}
+	}
+
+	return &result
+}
@@ -0,0 +1,218 @@
+package chordpro
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *Song
+	}{
+		{
+			name: "directives and sections",
+			text: `# comment
+{title: Song}
+{artist: Band}
+{key: G}
+{capo: 2}
+{tempo: 96}
+
+{start_of_verse: Verse 1}
+[G]Hello [C]world[D]
+
+[N.C.]again
+{end_of_verse}
+{soc}
+[Em]La
+{eoc}
+{chorus}`,
+			want: &Song{
+				Title:  "Song",
+				Artist: "Band",
+				Key:    "G",
+				Capo:   2,
+				Tempo:  96,
+				Sections: []Section{
+					{
+						Type:  SectionVerse,
+						Label: "Verse 1",
+						Lines: []Line{
+							{Type: LineLyrics, Segments: []Segment{{Chord: "G", Lyrics: "Hello "}, {Chord: "C", Lyrics: "world"}, {Chord: "D"}}},
+							{Type: LineEmpty},
+							{Type: LineLyrics, Segments: []Segment{{Chord: "N.C.", Lyrics: "again"}}},
+						},
+					},
+					{
+						Type:  SectionChorus,
+						Lines: []Line{{Type: LineLyrics, Segments: []Segment{{Chord: "Em", Lyrics: "La"}}}},
+					},
+					{
+						Type:  SectionNone,
+						Lines: []Line{{Type: LineChorusRef}},
+					},
+				},
+			},
+		},
+		{
+			name: "tab kept verbatim",
+			text: "{sot}\ne|--[0]--|\n{eot}",
+			want: &Song{
+				Sections: []Section{
+					{Type: SectionTab, Lines: []Line{{Type: LineTab, Text: "e|--[0]--|"}}},
+				},
+			},
+		},
+		{
+			name: "lyrics before the first chord",
+			text: "Oh [Am]my",
+			want: &Song{
+				Sections: []Section{
+					{Type: SectionNone, Lines: []Line{{Type: LineLyrics, Segments: []Segment{{Lyrics: "Oh "}, {Chord: "Am", Lyrics: "my"}}}}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(song, tt.want) {
+				t.Errorf("song = %+v, want %+v", song, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Errors
+	}{
+		{
+			name: "unknown chord",
+			text: "[Hm]text",
+			want: Errors{{Line: 1, Message: `unknown chord "Hm"`}},
+		},
+		{
+			name: "chord not closed",
+			text: "ok\n[G text",
+			want: Errors{{Line: 2, Message: "chord is not closed"}},
+		},
+		{
+			name: "every problem listed",
+			text: "{capo: 20}\n{key: X}\n{foo: bar}\n[]\n{sov}",
+			want: Errors{
+				{Line: 1, Message: "capo must be a number from 0 to 11"},
+				{Line: 2, Message: `invalid key "X"`},
+				{Line: 3, Message: `unknown directive "foo"`},
+				{Line: 4, Message: "empty chord"},
+				{Line: 5, Message: `section "verse" is not closed`},
+			},
+		},
+		{
+			name: "end without start",
+			text: "{eoc}",
+			want: Errors{{Line: 1, Message: `end of "chorus" without start`}},
+		},
+		{
+			name: "nested sections",
+			text: "{sov}\n{soc}\n{eov}",
+			want: Errors{{Line: 2, Message: `section "chorus" starts before "verse" is closed`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.text)
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("error = %v, want Errors", err)
+			}
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("errors = %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestSongTranspose(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		semitones  int
+		wantKey    string
+		wantChords []string
+	}{
+		{
+			name:       "up into a flat key",
+			text:       "{key: C}\n[C]a [G/B]b [Am]c",
+			semitones:  3,
+			wantKey:    "Eb",
+			wantChords: []string{"Eb", "Bb/D", "Cm"},
+		},
+		{
+			name:       "down into a sharp key",
+			text:       "{key: Bb}\n[Bb]a [F]b",
+			semitones:  -3,
+			wantKey:    "G",
+			wantChords: []string{"G", "D"},
+		},
+		{
+			name:       "no key keeps the spelling of each chord",
+			text:       "[Db]a [E]b",
+			semitones:  1,
+			wantChords: []string{"D", "F"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			song, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			transposed := song.Transpose(tt.semitones)
+			if transposed.Key != tt.wantKey {
+				t.Errorf("key = %q, want %q", transposed.Key, tt.wantKey)
+			}
+
+			var chords []string
+			for _, segment := range transposed.Sections[0].Lines[0].Segments {
+				chords = append(chords, segment.Chord)
+			}
+			if !reflect.DeepEqual(chords, tt.wantChords) {
+				t.Errorf("chords = %v, want %v", chords, tt.wantChords)
+			}
+
+			if song.Sections[0].Lines[0].Segments[0].Chord == chords[0] {
+				t.Errorf("the original song was changed")
+			}
+		})
+	}
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	song, err := Parse("{title: <b>Song</b>}\n[G]<script>alert(1)</script>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	html := RenderHTML(song)
+	if strings.Contains(html, "<script>") || strings.Contains(html, "<b>") {
+		t.Errorf("html is not escaped: %s", html)
+	}
+	if !strings.Contains(html, "&lt;script&gt;") {
+		t.Errorf("html lost the lyrics: %s", html)
+	}
+}
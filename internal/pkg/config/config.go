@@ -20,6 +20,12 @@ type Config struct {
 	Redis              RedisConfig    `yaml:"redis"`
 	RabbitMQ           RabbitMQConfig `yaml:"rabbitmq"`
 	MinioConfig        MinioConfig    `yaml:"minio"`
+	Sessions           Sessions       `yaml:"sessions"`
+	Idempotency        Idempotency    `yaml:"idempotency"`
+	Reports            Reports        `yaml:"reports"`
+	Imports            Imports        `yaml:"imports"`
+	Trends             Trends         `yaml:"trends"`
+	Workload           Workload       `yaml:"workload"`
 }
 
 type HTTPServer struct {
@@ -72,6 +78,36 @@ type MinioConfig struct {
 	UseSSL    bool   `yaml:"use_ssl"`
 }
 
+type Sessions struct {
+	AbandonTimeout time.Duration `yaml:"abandon_timeout"`
+	CheckInterval  time.Duration `yaml:"check_interval"`
+}
+
+type Idempotency struct {
+	TTL         time.Duration `yaml:"ttl"`
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+}
+
+type Reports struct {
+	FontPath string `yaml:"font_path"`
+}
+
+type Imports struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	StaleTimeout time.Duration `yaml:"stale_timeout"`
+}
+
+type Trends struct {
+	CheckInterval time.Duration `yaml:"check_interval"`
+	WindowDays    int           `yaml:"window_days"`
+	MinSessions   int           `yaml:"min_sessions"`
+}
+
+type Workload struct {
+	CheckInterval time.Duration `yaml:"check_interval"`
+	AlertCooldown time.Duration `yaml:"alert_cooldown"`
+}
+
 func MustLoad() *Config {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
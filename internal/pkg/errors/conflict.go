@@ -0,0 +1,32 @@
+package errors
+
+import (
+	stderrors "errors"
+)
+
+// ErrVersionConflict is returned by repositories when an update did not match
+// the version the client has read, i.e. the row was changed concurrently.
+var ErrVersionConflict = stderrors.New("version conflict")
+
+// ConflictError carries the current state of an entity so that the handler
+// can return it to the client together with 409 Conflict.
+type ConflictError struct {
+	Current interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return ErrStaleData
+}
+
+func IsVersionConflict(err error) bool {
+	return stderrors.Is(err, ErrVersionConflict)
+}
+
+func AsConflict(err error) (*ConflictError, bool) {
+	var conflict *ConflictError
+	if stderrors.As(err, &conflict) {
+		return conflict, true
+	}
+
+	return nil, false
+}
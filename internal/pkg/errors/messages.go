@@ -1,10 +1,28 @@
 package errors
 
 const (
-	ErrFailedToLoadData   = "не удалось загрузить данные"
-	ErrFailedToSaveData   = "не удалось сохранить данные"
-	ErrFailedToDeleteData = "не удалось удалить данные"
-	ErrAccessDenied       = "доступ запрещен"
-	ErrInvalidData        = "неверные данные"
-	ErrCommon             = "произошла ошибка"
+	ErrFailedToLoadData     = "не удалось загрузить данные"
+	ErrFailedToSaveData     = "не удалось сохранить данные"
+	ErrFailedToDeleteData   = "не удалось удалить данные"
+	ErrAccessDenied         = "доступ запрещен"
+	ErrInvalidData          = "неверные данные"
+	ErrCommon               = "произошла ошибка"
+	ErrStaleData            = "данные были изменены, обновите их и повторите попытку"
+	ErrVersionRequired      = "необходимо указать версию в заголовке If-Match или в поле version"
+	ErrInvalidVersion       = "неверный формат версии"
+	ErrInvalidScore         = "не удалось разобрать файл партитуры"
+	ErrTempoNotFound        = "не удалось определить темп, укажите target_bpm"
+	ErrUnsupportedFile      = "неподдерживаемый формат файла"
+	ErrInvalidChart         = "ошибки в тексте аккордов ChordPro"
+	ErrSessionState         = "действие недоступно для сессии в текущем состоянии"
+	ErrInvalidSyncToken     = "неверный токен синхронизации, выполните полную синхронизацию"
+	ErrFailedToSendEmail    = "не удалось отправить письмо"
+	ErrShareNotFound        = "ссылка не найдена или отозвана"
+	ErrImportNotFound       = "импорт не найден"
+	ErrInvalidImportFile    = "не удалось прочитать CSV файл"
+	ErrImportColumnNotFound = "в файле нет столбца, указанного в сопоставлении"
+	ErrImportTooLarge       = "слишком много строк в файле"
+	ErrJournalEntryNotFound = "запись журнала не найдена"
+	ErrInvitationExpired    = "срок действия приглашения истек"
+	ErrAlreadyMember        = "пользователь уже состоит в ансамбле"
 )
@@ -0,0 +1,145 @@
+// Package forecast extrapolates learning curves, such as the tempo a piece is
+// played at over time, to estimate when a level will be reached.
+package forecast
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	ErrTooFewPoints = errors.New("forecast: too few points")
+	ErrOutOfRange   = errors.New("forecast: value outside the curve range")
+)
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+type line struct {
+	slope     float64
+	intercept float64
+}
+
+// Logistic is the curve y = Ceiling / (1 + exp(-(Slope*x + Intercept))),
+// which grows fast at first and levels off towards Ceiling.
+type Logistic struct {
+	Ceiling   float64
+	Slope     float64
+	Intercept float64
+
+	slower line
+	faster line
+}
+
+// FitLogistic fits a logistic curve with the given ceiling to the points. The
+// points are linearized with the logit and fitted with the Theil-Sen
+// estimator, the median of the slopes between all pairs of points, so a few
+// outliers barely move the curve. z sets the width of the slope interval used
+// by Solve, e.g. 1.28 for 80%. Points with a non-positive Y are skipped.
+func FitLogistic(points []Point, ceiling, z float64) (*Logistic, error) {
+	logits := make([]Point, 0, len(points))
+	for _, p := range points {
+		if p.Y <= 0 {
+			continue
+		}
+		if p.Y >= ceiling {
+			return nil, ErrOutOfRange
+		}
+		logits = append(logits, Point{X: p.X, Y: logit(p.Y / ceiling)})
+	}
+
+	slopes := make([]float64, 0, len(logits)*(len(logits)-1)/2)
+	for i := range logits {
+		for j := i + 1; j < len(logits); j++ {
+			if dx := logits[j].X - logits[i].X; dx != 0 {
+				slopes = append(slopes, (logits[j].Y-logits[i].Y)/dx)
+			}
+		}
+	}
+	if len(slopes) < 2 {
+		return nil, ErrTooFewPoints
+	}
+	sort.Float64s(slopes)
+
+	// Sen's confidence interval for the slope: the ranks around the median
+	// follow Kendall's tau, whose variance is n(n-1)(2n+5)/18.
+	n := float64(len(logits))
+	spread := z * math.Sqrt(n*(n-1)*(2*n+5)/18)
+	total := float64(len(slopes))
+	lower := clampIndex(int(math.Floor((total-spread)/2)), len(slopes))
+	upper := clampIndex(int(math.Ceil((total+spread)/2)), len(slopes))
+
+	slope := median(slopes)
+
+	return &Logistic{
+		Ceiling:   ceiling,
+		Slope:     slope,
+		Intercept: interceptFor(logits, slope),
+		slower:    line{slopes[lower], interceptFor(logits, slopes[lower])},
+		faster:    line{slopes[upper], interceptFor(logits, slopes[upper])},
+	}, nil
+}
+
+// At returns the value of the curve at x.
+func (l *Logistic) At(x float64) float64 {
+	return l.Ceiling / (1 + math.Exp(-(l.Slope*x + l.Intercept)))
+}
+
+// Solve returns where the curve reaches y, together with the earliest and the
+// latest x within the slope interval. The latest x is +Inf when the slower
+// curve does not grow. ok is false when the fitted curve itself does not grow
+// or y is outside (0, Ceiling).
+func (l *Logistic) Solve(y float64) (x, earliest, latest float64, ok bool) {
+	if y <= 0 || y >= l.Ceiling || l.Slope <= 0 {
+		return 0, 0, 0, false
+	}
+
+	target := logit(y / l.Ceiling)
+	x = (target - l.Intercept) / l.Slope
+
+	earliest, latest = x, x
+	for _, bound := range []line{l.slower, l.faster} {
+		if bound.slope <= 0 {
+			latest = math.Inf(1)
+			continue
+		}
+		at := (target - bound.intercept) / bound.slope
+		earliest = math.Min(earliest, at)
+		latest = math.Max(latest, at)
+	}
+
+	return x, earliest, latest, true
+}
+
+// interceptFor returns the median intercept of lines with the given slope
+// through the points.
+func interceptFor(points []Point, slope float64) float64 {
+	intercepts := make([]float64, len(points))
+	for i, p := range points {
+		intercepts[i] = p.Y - slope*p.X
+	}
+	sort.Float64s(intercepts)
+
+	return median(intercepts)
+}
+
+// median expects sorted values.
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
+
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
+
+func clampIndex(i, length int) int {
+	return max(0, min(i, length-1))
+}
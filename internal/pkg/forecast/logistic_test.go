@@ -0,0 +1,155 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func curvePoints(ceiling, slope, intercept float64, xs ...float64) []Point {
+	points := make([]Point, 0, len(xs))
+	for _, x := range xs {
+		points = append(points, Point{X: x, Y: ceiling / (1 + math.Exp(-(slope*x + intercept)))})
+	}
+	return points
+}
+
+func TestFitLogistic(t *testing.T) {
+	outliers := curvePoints(150, 0.1, -1, 0, 2, 4, 6, 8, 10, 12)
+	outliers[3].Y = 30
+
+	tests := []struct {
+		name          string
+		points        []Point
+		ceiling       float64
+		wantSlope     float64
+		wantIntercept float64
+		wantErr       error
+	}{
+		{
+			name:          "exact curve",
+			points:        curvePoints(150, 0.1, -1, 0, 1, 3, 7, 10),
+			ceiling:       150,
+			wantSlope:     0.1,
+			wantIntercept: -1,
+		},
+		{
+			name:          "outlier barely moves the fit",
+			points:        outliers,
+			ceiling:       150,
+			wantSlope:     0.1,
+			wantIntercept: -1,
+		},
+		{
+			name:          "non-positive points are skipped",
+			points:        append(curvePoints(150, 0.1, -1, 0, 5, 10), Point{X: 3, Y: 0}, Point{X: 4, Y: -5}),
+			ceiling:       150,
+			wantSlope:     0.1,
+			wantIntercept: -1,
+		},
+		{
+			name:    "point at the ceiling",
+			points:  []Point{{X: 0, Y: 100}, {X: 1, Y: 120}, {X: 2, Y: 150}},
+			ceiling: 150,
+			wantErr: ErrOutOfRange,
+		},
+		{
+			name:    "two points",
+			points:  []Point{{X: 0, Y: 60}, {X: 1, Y: 70}},
+			ceiling: 150,
+			wantErr: ErrTooFewPoints,
+		},
+		{
+			name:    "same day",
+			points:  []Point{{X: 0, Y: 60}, {X: 0, Y: 70}, {X: 0, Y: 80}},
+			ceiling: 150,
+			wantErr: ErrTooFewPoints,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve, err := FitLogistic(tt.points, tt.ceiling, 1.28)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if math.Abs(curve.Slope-tt.wantSlope) > 1e-6 || math.Abs(curve.Intercept-tt.wantIntercept) > 1e-6 {
+				t.Errorf("curve = %v·x + %v, want %v·x + %v", curve.Slope, curve.Intercept, tt.wantSlope, tt.wantIntercept)
+			}
+			if curve.Ceiling != tt.ceiling {
+				t.Errorf("ceiling = %v, want %v", curve.Ceiling, tt.ceiling)
+			}
+		})
+	}
+}
+
+func TestLogisticSolve(t *testing.T) {
+	noisy := []Point{{X: 0, Y: 60}, {X: 1, Y: 66}, {X: 2, Y: 63}, {X: 4, Y: 72}, {X: 5, Y: 70}, {X: 7, Y: 80}}
+	flat := []Point{{X: 0, Y: 60}, {X: 1, Y: 61}, {X: 2, Y: 59}, {X: 3, Y: 60}, {X: 4, Y: 60}}
+
+	tests := []struct {
+		name   string
+		points []Point
+		y      float64
+		wantX  float64
+		wantOK bool
+	}{
+		{
+			name:   "exact curve",
+			points: curvePoints(150, 0.1, -1, 0, 1, 3, 7, 10),
+			y:      75,
+			wantX:  10,
+			wantOK: true,
+		},
+		{
+			name:   "noisy growth",
+			points: noisy,
+			y:      120,
+			wantOK: true,
+		},
+		{
+			name:   "no growth",
+			points: flat,
+			y:      120,
+		},
+		{
+			name:   "above the ceiling",
+			points: noisy,
+			y:      150,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve, err := FitLogistic(tt.points, 150, 1.28)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			x, earliest, latest, ok := curve.Solve(tt.y)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			if tt.wantX != 0 && math.Abs(x-tt.wantX) > 1e-6 {
+				t.Errorf("x = %v, want %v", x, tt.wantX)
+			}
+			if earliest > x || latest < x {
+				t.Errorf("interval [%v, %v] does not contain %v", earliest, latest, x)
+			}
+			if math.Abs(curve.At(x)-tt.y) > 1e-6 {
+				t.Errorf("At(%v) = %v, want %v", x, curve.At(x), tt.y)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/errors"
+	"github.com/darahayes/go-boom"
+)
+
+// GetVersion returns the version the client has based its changes on. The
+// If-Match header takes precedence over the version field of the body.
+func GetVersion(r *http.Request, bodyVersion int) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		if bodyVersion <= 0 {
+			return 0, fmt.Errorf(errors.ErrVersionRequired)
+		}
+		return bodyVersion, nil
+	}
+
+	ifMatch = strings.TrimPrefix(ifMatch, "W/")
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf(errors.ErrInvalidVersion)
+	}
+
+	return version, nil
+}
+
+// SendVersionError answers 428 when no version was given and 400 when it
+// could not be read.
+func SendVersionError(w http.ResponseWriter, err error) {
+	if err.Error() == errors.ErrVersionRequired {
+		boom.PreconditionRequired(w, err)
+		return
+	}
+
+	boom.BadRequest(w, err)
+}
+
+// SendConflict answers 409 with the current state of the entity.
+func SendConflict(w http.ResponseWriter, conflict *errors.ConflictError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": conflict.Error(),
+		"current": conflict.Current,
+	})
+}
+
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
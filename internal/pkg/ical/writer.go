@@ -0,0 +1,142 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateTimeLayout = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+type Calendar struct {
+	ProductID string
+	Name      string
+	// RefreshInterval tells calendar apps how often to poll the feed.
+	RefreshInterval time.Duration
+}
+
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Categories  []string
+	Stamp       time.Time
+}
+
+// Writer streams an iCalendar (RFC 5545) document event by event. Close must
+// be called to finish the calendar.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer, calendar Calendar) *Writer {
+	writer := &Writer{w: bufio.NewWriter(w)}
+
+	writer.line("BEGIN:VCALENDAR")
+	writer.line("VERSION:2.0")
+	writer.property("PRODID", calendar.ProductID)
+	writer.line("CALSCALE:GREGORIAN")
+	writer.line("METHOD:PUBLISH")
+	if calendar.Name != "" {
+		writer.property("X-WR-CALNAME", escape(calendar.Name))
+	}
+	if calendar.RefreshInterval > 0 {
+		refresh := formatDuration(calendar.RefreshInterval)
+		writer.line("REFRESH-INTERVAL;VALUE=DURATION:" + refresh)
+		writer.line("X-PUBLISHED-TTL:" + refresh)
+	}
+
+	return writer
+}
+
+func (w *Writer) WriteEvent(event Event) error {
+	w.line("BEGIN:VEVENT")
+	w.property("UID", event.UID)
+	w.property("DTSTAMP", event.Stamp.UTC().Format(dateTimeLayout))
+	w.property("DTSTART", event.Start.UTC().Format(dateTimeLayout))
+	w.property("DTEND", event.End.UTC().Format(dateTimeLayout))
+	w.property("SUMMARY", escape(event.Summary))
+	if event.Description != "" {
+		w.property("DESCRIPTION", escape(event.Description))
+	}
+	if len(event.Categories) > 0 {
+		escaped := make([]string, 0, len(event.Categories))
+		for _, category := range event.Categories {
+			escaped = append(escaped, escape(category))
+		}
+		w.property("CATEGORIES", strings.Join(escaped, ","))
+	}
+	w.line("TRANSP:TRANSPARENT")
+	w.line("END:VEVENT")
+
+	return w.err
+}
+
+func (w *Writer) Close() error {
+	w.line("END:VCALENDAR")
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.w.Flush()
+}
+
+func (w *Writer) property(name, value string) {
+	w.line(name + ":" + value)
+}
+
+// line writes a content line folded to 75 octets, without splitting a UTF-8
+// sequence.
+func (w *Writer) line(text string) {
+	if w.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range text {
+		size := utf8.RuneLen(r)
+		if width+size > maxLineOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+
+	_, w.err = w.w.WriteString(b.String())
+}
+
+// escape escapes a TEXT value.
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes%60 == 0 {
+		return "PT" + strconv.Itoa(minutes/60) + "H"
+	}
+
+	return "PT" + strconv.Itoa(minutes) + "M"
+}
@@ -0,0 +1,150 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Scales", want: "Scales"},
+		{text: "C, G; D", want: `C\, G\; D`},
+		{text: `a\b`, want: `a\\b`},
+		{text: "one\r\ntwo\nthree\rfour", want: `one\ntwo\nthree\nfour`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := escape(tt.text); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: time.Hour, want: "PT1H"},
+		{d: 6 * time.Hour, want: "PT6H"},
+		{d: 90 * time.Minute, want: "PT90M"},
+		{d: 10 * time.Second, want: "PT1M"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			if got := formatDuration(tt.d); got != tt.want {
+				t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterLineFolding(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "ascii", summary: strings.Repeat("a", 200)},
+		{name: "cyrillic", summary: strings.Repeat("гамма ", 40)},
+		{name: "short", summary: "Etude"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := NewWriter(&b, Calendar{ProductID: "-//Test//EN"})
+			if err := w.WriteEvent(Event{UID: "1", Summary: tt.summary}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			output := b.String()
+			if !strings.HasSuffix(output, "\r\n") {
+				t.Fatalf("output does not end with CRLF")
+			}
+
+			var unfolded []string
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line of %d octets: %q", len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line splits a UTF-8 sequence: %q", line)
+				}
+				if strings.HasPrefix(line, " ") {
+					unfolded[len(unfolded)-1] += line[1:]
+					continue
+				}
+				unfolded = append(unfolded, line)
+			}
+
+			want := "SUMMARY:" + tt.summary
+			found := false
+			for _, line := range unfolded {
+				found = found || line == want
+			}
+			if !found {
+				t.Errorf("unfolded lines %q do not contain %q", unfolded, want)
+			}
+		})
+	}
+}
+
+func TestWriterEvent(t *testing.T) {
+	start := time.Date(2026, 3, 1, 18, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	var b strings.Builder
+	w := NewWriter(&b, Calendar{ProductID: "-//Test//EN", Name: "Practice", RefreshInterval: time.Hour})
+	err := w.WriteEvent(Event{
+		UID:         "session-1@test",
+		Start:       start,
+		End:         start.Add(45 * time.Minute),
+		Summary:     "Etude, op. 1",
+		Description: "96 BPM\nclean",
+		Categories:  []string{"scales", "a,b"},
+		Stamp:       start,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Test//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Practice",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+		"BEGIN:VEVENT",
+		"UID:session-1@test",
+		"DTSTAMP:20260301T153000Z",
+		"DTSTART:20260301T153000Z",
+		"DTEND:20260301T161500Z",
+		`SUMMARY:Etude\, op. 1`,
+		`DESCRIPTION:96 BPM\nclean`,
+		`CATEGORIES:scales,a\,b`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	if got := b.String(); got != want {
+		t.Errorf("calendar =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// RenderHTML renders a subset of Markdown as an HTML fragment: paragraphs,
+// headings, lists, block quotes, code, rules, emphasis and links. HTML in the
+// source is escaped instead of passed through and links are kept only for the
+// http, https and mailto schemes, so the result is safe to embed in a page.
+// Line breaks inside a paragraph are kept.
+func RenderHTML(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+
+	var b strings.Builder
+	writeBlocks(&b, strings.Split(source, "\n"))
+
+	return b.String()
+}
+
+func writeBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+
+		switch {
+		case line == "":
+			i++
+
+		case isFence(line):
+			fence := line[:3]
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+				end++
+			}
+
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(lines[i+1:end], "\n")))
+			b.WriteString("</code></pre>\n")
+			i = end + 1
+
+		case isRule(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case headingLevel(line) > 0:
+			level := headingLevel(line)
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+
+			fmt.Fprintf(b, "<h%d>", level)
+			writeInline(b, text, true)
+			fmt.Fprintf(b, "</h%d>\n", level)
+			i++
+
+		case strings.HasPrefix(line, ">"):
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				text := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(text, " "))
+				i++
+			}
+
+			b.WriteString("<blockquote>\n")
+			writeBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case isListItem(line):
+			i = writeList(b, lines, i)
+
+		default:
+			var paragraph []string
+			for i < len(lines) {
+				text := strings.TrimSpace(lines[i])
+				if text == "" || (len(paragraph) > 0 && startsBlock(text)) {
+					break
+				}
+				paragraph = append(paragraph, text)
+				i++
+			}
+
+			b.WriteString("<p>")
+			writeLines(b, paragraph)
+			b.WriteString("</p>\n")
+		}
+	}
+}
+
+// writeList writes the list starting at line i and returns the index of the
+// first line after it. Lists are flat; indented lines continue the item.
+func writeList(b *strings.Builder, lines []string, i int) int {
+	ordered, start, _ := listItem(strings.TrimSpace(lines[i]))
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	if ordered && start != 1 {
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", start)
+	} else {
+		fmt.Fprintf(b, "<%s>\n", tag)
+	}
+
+	for i < len(lines) {
+		isOrdered, _, text := listItem(strings.TrimSpace(lines[i]))
+		if !isListItem(strings.TrimSpace(lines[i])) || isOrdered != ordered {
+			break
+		}
+
+		item := []string{text}
+		i++
+		for i < len(lines) {
+			next := lines[i]
+			trimmed := strings.TrimSpace(next)
+			if trimmed == "" || trimmed == next || isListItem(trimmed) {
+				break
+			}
+			item = append(item, trimmed)
+			i++
+		}
+
+		b.WriteString("<li>")
+		writeLines(b, item)
+		b.WriteString("</li>\n")
+	}
+
+	fmt.Fprintf(b, "</%s>\n", tag)
+
+	return i
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br>\n")
+		}
+		writeInline(b, line, true)
+	}
+}
+
+func startsBlock(line string) bool {
+	return isFence(line) || isRule(line) || headingLevel(line) > 0 || strings.HasPrefix(line, ">") || isListItem(line)
+}
+
+func isFence(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")
+}
+
+// isRule reports a line of three or more -, * or _, optionally spaced.
+func isRule(line string) bool {
+	compact := strings.ReplaceAll(line, " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+
+	c := compact[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+
+	return strings.Count(compact, string(c)) == len(compact)
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+
+	return level
+}
+
+func isListItem(line string) bool {
+	_, _, text := listItem(line)
+	return text != "" || line == "-" || line == "*" || line == "+"
+}
+
+// listItem splits a list item line into its kind, number and text. The text
+// is empty when the line is not a list item.
+func listItem(line string) (bool, int, string) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return false, 0, strings.TrimSpace(line[2:])
+	}
+
+	digits := 0
+	for digits < len(line) && digits < 9 && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(line) || (line[digits] != '.' && line[digits] != ')') || line[digits+1] != ' ' {
+		return false, 0, ""
+	}
+
+	number, _ := strconv.Atoi(line[:digits])
+
+	return true, number, strings.TrimSpace(line[digits+2:])
+}
@@ -0,0 +1,137 @@
+package markdown
+
+import "testing"
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "paragraphs and line breaks",
+			source: "one\ntwo\r\n\r\nthree",
+			want:   "<p>one<br>\ntwo</p>\n<p>three</p>\n",
+		},
+		{
+			name:   "headings",
+			source: "# Title #\n###### Small\n####### too deep\n#not a heading",
+			want:   "<h1>Title</h1>\n<h6>Small</h6>\n<p>####### too deep<br>\n#not a heading</p>\n",
+		},
+		{
+			name:   "lists",
+			source: "- one\n  more\n- two\n\n3. three\n4. four",
+			want:   "<ul>\n<li>one<br>\nmore</li>\n<li>two</li>\n</ul>\n<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n",
+		},
+		{
+			name:   "block quote and rule",
+			source: "> quoted\n> **text**\n\n* * *",
+			want:   "<blockquote>\n<p>quoted<br>\n<strong>text</strong></p>\n</blockquote>\n<hr>\n",
+		},
+		{
+			name:   "code block",
+			source: "```go\nif a < b {\n```",
+			want:   "<pre><code>if a &lt; b {</code></pre>\n",
+		},
+		{
+			name:   "emphasis",
+			source: "*em* __strong__ ~~del~~ ***both*** snake_case_name",
+			want:   "<p><em>em</em> <strong>strong</strong> <del>del</del> <strong><em>both</em></strong> snake_case_name</p>\n",
+		},
+		{
+			name:   "code span and escapes",
+			source: "`<b>` \\*not em\\*",
+			want:   "<p><code>&lt;b&gt;</code> *not em*</p>\n",
+		},
+		{
+			name:   "link",
+			source: "[the *site*](https://example.com/a?b=1&c=2 \"title\")",
+			want:   "<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow noopener noreferrer\">the <em>site</em></a></p>\n",
+		},
+		{
+			name:   "mailto link",
+			source: "[mail](<mailto:teacher@example.com>)",
+			want:   "<p><a href=\"mailto:teacher@example.com\" rel=\"nofollow noopener noreferrer\">mail</a></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderHTML(tt.source); got != tt.want {
+				t.Errorf("RenderHTML(%q) =\n%q\nwant\n%q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHTMLSanitizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "javascript link",
+			source: "[click](javascript:alert(1))",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "javascript link in upper case",
+			source: "[click](JavaScript:alert(document.cookie))",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "data link",
+			source: "[click](data:text/html;base64,PHNjcmlwdD4=)",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "vbscript link",
+			source: "[click](vbscript:msgbox)",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "relative link",
+			source: "[click](/admin)",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "script tag",
+			source: "<script>alert(1)</script>",
+			want:   "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name:   "event handler attribute",
+			source: "<img src=x onerror=\"alert(1)\">",
+			want:   "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n",
+		},
+		{
+			name:   "quote breaking out of href",
+			source: "[x](https://example.com/\"onmouseover=\"alert(1))",
+			want:   "<p><a href=\"https://example.com/&#34;onmouseover=&#34;alert(1)\" rel=\"nofollow noopener noreferrer\">x</a></p>\n",
+		},
+		{
+			name:   "html inside emphasis and headings",
+			source: "# <i>title</i>\n**<b>bold</b>**",
+			want:   "<h1>&lt;i&gt;title&lt;/i&gt;</h1>\n<p><strong>&lt;b&gt;bold&lt;/b&gt;</strong></p>\n",
+		},
+		{
+			name:   "html inside link text",
+			source: "[<img src=x>](https://example.com)",
+			want:   "<p><a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">&lt;img src=x&gt;</a></p>\n",
+		},
+		{
+			name:   "entities are escaped",
+			source: "&lt;script&gt; & \"quotes\"",
+			want:   "<p>&amp;lt;script&amp;gt; &amp; &#34;quotes&#34;</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderHTML(tt.source); got != tt.want {
+				t.Errorf("RenderHTML(%q) =\n%q\nwant\n%q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// linkSchemes are the URL schemes kept in links; others, like javascript:,
+// leave only the link text.
+var linkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// writeInline writes a line with code spans, emphasis and, unless inside a
+// link text, links. Everything else is escaped.
+func writeInline(b *strings.Builder, s string, links bool) {
+	plain := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[plain:end]))
+	}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			flush(i)
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			plain = i
+			continue
+
+		case c == '`':
+			n := runLength(s, i)
+			closing := strings.Index(s[i+n:], s[i:i+n])
+			if closing < 0 {
+				i += n
+				continue
+			}
+
+			flush(i)
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(strings.TrimSpace(s[i+n : i+n+closing])))
+			b.WriteString("</code>")
+			i += n + closing + n
+			plain = i
+			continue
+
+		case c == '[' && links:
+			text, target, end, ok := parseLink(s, i)
+			if !ok {
+				break
+			}
+
+			flush(i)
+			if safeURL(target) {
+				b.WriteString(`<a href="` + html.EscapeString(target) + `" rel="nofollow noopener noreferrer">`)
+				writeInline(b, text, false)
+				b.WriteString("</a>")
+			} else {
+				writeInline(b, text, false)
+			}
+			i = end
+			plain = i
+			continue
+
+		case c == '*' || c == '_' || c == '~':
+			tag, inner, end, ok := parseEmphasis(s, i)
+			if !ok {
+				i += runLength(s, i)
+				continue
+			}
+
+			flush(i)
+			b.WriteString("<" + tag + ">")
+			writeInline(b, inner, links)
+			b.WriteString("</" + tag + ">")
+			i = end
+			plain = i
+			continue
+		}
+
+		i++
+	}
+
+	flush(len(s))
+}
+
+// parseEmphasis matches *em*, _em_, **strong**, __strong__ and ~~del~~
+// starting at i. Underscores inside words, as in snake_case, are literal.
+func parseEmphasis(s string, i int) (string, string, int, bool) {
+	c := s[i]
+	n := 1
+	if i+1 < len(s) && s[i+1] == c {
+		n = 2
+	}
+	if c == '~' && n != 2 {
+		return "", "", 0, false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", "", 0, false
+	}
+
+	start := i + n
+	if start >= len(s) || s[start] == ' ' {
+		return "", "", 0, false
+	}
+
+	for j := start + 1; j < len(s); j++ {
+		if s[j] != c {
+			continue
+		}
+
+		// A closing delimiter may end a longer run, so that ***text*** is
+		// strong text around emphasis, while a double delimiter inside
+		// emphasis belongs to nested strong text.
+		run := runLength(s, j)
+		if run < n || (n == 1 && run == 2) || s[j-1] == ' ' || (c == '_' && j+run < len(s) && isWordByte(s[j+run])) {
+			j += run - 1
+			continue
+		}
+
+		tag := "em"
+		switch {
+		case c == '~':
+			tag = "del"
+		case n == 2:
+			tag = "strong"
+		}
+
+		return tag, s[start : j+run-n], j + run, true
+	}
+
+	return "", "", 0, false
+}
+
+// parseLink matches [text](target) starting at i and returns the text, the
+// target and the index after the link.
+func parseLink(s string, i int) (string, string, int, bool) {
+	depth := 0
+	closing := -1
+	for j := i; j < len(s) && closing < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return "", "", 0, false
+	}
+
+	end := -1
+	depth = 0
+	for j := closing + 1; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+
+	text := s[i+1 : closing]
+	if end < 0 || strings.TrimSpace(text) == "" {
+		return "", "", 0, false
+	}
+
+	target := strings.TrimSpace(s[closing+2 : end])
+	if space := strings.IndexAny(target, " \t"); space >= 0 {
+		// Drop the optional title.
+		target = target[:space]
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+
+	return text, target, end + 1, true
+}
+
+func safeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	return linkSchemes[strings.ToLower(u.Scheme)]
+}
+
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+
+	return n
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("\\`*_{}[]()#+-.!~>|<&\"'", c) >= 0
+}
+
+// isWordByte reports letters and digits, including any byte of a non-ASCII
+// character.
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
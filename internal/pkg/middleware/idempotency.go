@@ -0,0 +1,343 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/config"
+	"github.com/darahayes/go-boom"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// MaxIdempotentBodySize is enough for the JSON bodies of the API. Routes
+	// taking file uploads pass their own limit.
+	MaxIdempotentBodySize = 4 << 20
+
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyTimeout = time.Minute
+
+	// publicIdempotencyScope is the scope of keys sent to routes without
+	// authentication.
+	publicIdempotencyScope = "public"
+)
+
+// IdempotencyStore keeps the idempotency records. It is implemented by
+// redis.Service.
+type IdempotencyStore interface {
+	SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	SetJSONNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+	GetJSON(ctx context.Context, key string, dest interface{}) error
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// replayedHeaders are the response headers stored together with the body, so
+// a replayed response can be used the same way as the original one.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type idempotencyRecord struct {
+	Fingerprint string              `json:"fingerprint"`
+	Completed   bool                `json:"completed"`
+	Status      int                 `json:"status,omitempty"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// Idempotency makes mutating requests safe to retry. The first response for a
+// user, Idempotency-Key and route is stored and replayed for later requests
+// with the same key. A retry sent while the first request is still running is
+// rejected with 409, and reusing a key for a different payload with 422.
+// Requests without the header are passed through unchanged.
+//
+// The body is buffered to fingerprint it, up to maxBodySize bytes; larger
+// requests are rejected with 413. The in-flight lock expires after the lock
+// timeout and is refreshed while the handler runs, so a slow request keeps it
+// and a crashed one releases it.
+//
+// It relies on the user id set by AuthMiddleware, so it must be mounted after
+// it.
+func Idempotency(store IdempotencyStore, cfg *config.Idempotency, log *slog.Logger, maxBodySize int64) func(http.Handler) http.Handler {
+	return idempotency(store, cfg, log, maxBodySize, userIdempotencyScope)
+}
+
+// PublicIdempotency is Idempotency for routes without authentication, such as
+// login and registration. Keys are shared by all clients, but a response is
+// only replayed for a request with the same body, so a client never gets the
+// response to someone else's request.
+func PublicIdempotency(store IdempotencyStore, cfg *config.Idempotency, log *slog.Logger, maxBodySize int64) func(http.Handler) http.Handler {
+	return idempotency(store, cfg, log, maxBodySize, func(*http.Request) (string, bool) {
+		return publicIdempotencyScope, true
+	})
+}
+
+func userIdempotencyScope(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value("user_id").(string)
+	return userID, ok && userID != ""
+}
+
+func idempotency(
+	store IdempotencyStore,
+	cfg *config.Idempotency,
+	log *slog.Logger,
+	maxBodySize int64,
+	scope func(r *http.Request) (string, bool),
+) func(http.Handler) http.Handler {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+
+	lockTimeout := cfg.LockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = defaultIdempotencyTimeout
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				boom.BadRequest(w, "Idempotency-Key слишком длинный")
+				return
+			}
+
+			owner, ok := scope(r)
+			if !ok {
+				boom.Unathorized(w, "Authorization required for Idempotency-Key")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					boom.EntityTooLarge(w, "тело запроса слишком большое")
+					return
+				}
+				boom.BadRequest(w, "не удалось прочитать тело запроса")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := idempotencyStoreKey(owner, idempotencyKey, r)
+			fingerprint := requestFingerprint(r, body)
+
+			locked, err := store.SetJSONNX(r.Context(), key, idempotencyRecord{Fingerprint: fingerprint}, lockTimeout)
+			if err != nil {
+				log.Error("failed to lock idempotency key, processing without it", "key", key, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !locked {
+				replayIdempotent(w, r, store, log, key, fingerprint)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			release := keepLocked(r.Context(), store, log, key, lockTimeout)
+			next.ServeHTTP(recorder, r)
+			release()
+
+			// Server errors are not stored, so the client can retry with the
+			// same key once the problem is gone.
+			ctx := context.WithoutCancel(r.Context())
+			if recorder.status >= http.StatusInternalServerError {
+				if err := store.Delete(ctx, key); err != nil {
+					log.Error("failed to release idempotency key", "key", key, "error", err)
+				}
+				return
+			}
+
+			record := idempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      recorder.status,
+				Header:      make(map[string][]string),
+				Body:        recorder.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if values := w.Header().Values(name); len(values) > 0 {
+					record.Header[name] = values
+				}
+			}
+
+			if err := store.SetJSON(ctx, key, record, ttl); err != nil {
+				log.Error("failed to store idempotent response", "key", key, "error", err)
+			}
+		})
+	}
+}
+
+// keepLocked extends the lock every half of its timeout until the returned
+// function is called. It returns once the refreshing has stopped, so that a
+// late refresh cannot shorten the TTL of the stored response.
+func keepLocked(ctx context.Context, store IdempotencyStore, log *slog.Logger, key string, lockTimeout time.Duration) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(lockTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.Expire(context.WithoutCancel(ctx), key, lockTimeout); err != nil {
+					log.Error("failed to extend idempotency lock", "key", key, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, r *http.Request, store IdempotencyStore, log *slog.Logger, key, fingerprint string) {
+	var record idempotencyRecord
+	if err := store.GetJSON(r.Context(), key, &record); err != nil {
+		log.Error("failed to get idempotency record", "key", key, "error", err)
+		boom.Conflict(w, "запрос с этим Idempotency-Key ещё выполняется, повторите позже")
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		log.Info("idempotency key reused for another payload", "key", key)
+		boom.BadData(w, "Idempotency-Key уже использован для другого запроса")
+		return
+	}
+
+	if !record.Completed {
+		log.Info("idempotent request is still in flight", "key", key)
+		boom.Conflict(w, "запрос с этим Idempotency-Key ещё выполняется, повторите позже")
+		return
+	}
+
+	for name, values := range record.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotencyStoreKey scopes the key to the user and the route, so the same
+// key sent by different users or to different endpoints never collides.
+func idempotencyStoreKey(owner, idempotencyKey string, r *http.Request) string {
+	hash := sha256.Sum256([]byte(idempotencyKey))
+	return fmt.Sprintf("idempotency:%s:%s:%s:%s", owner, r.Method, r.URL.Path, hex.EncodeToString(hash[:]))
+}
+
+// requestFingerprint hashes the query and the body. Multipart bodies are
+// hashed by their fields, since a client retrying an upload usually picks a
+// new boundary.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.URL.RawQuery))
+	hash.Write([]byte{0})
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/form-data" {
+		if fields, err := multipartFingerprint(body, params["boundary"]); err == nil {
+			hash.Write([]byte(fields))
+			return hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// multipartFingerprint lists the name, file name and content digest of every
+// part, sorted, so neither the boundary nor the order of the fields matters.
+func multipartFingerprint(body []byte, boundary string) (string, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	fields := make([]string, 0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		digest := sha256.New()
+		if _, err := io.Copy(digest, part); err != nil {
+			return "", err
+		}
+		fields = append(fields, fmt.Sprintf("%q %q %x", part.FormName(), part.FileName(), digest.Sum(nil)))
+	}
+	sort.Strings(fields)
+
+	var b bytes.Buffer
+	for _, field := range fields {
+		b.WriteString(field)
+		b.WriteByte('\n')
+	}
+
+	return b.String(), nil
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,359 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuLap/trackmus-api/internal/pkg/config"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+	expires int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{records: make(map[string][]byte)}
+}
+
+func (s *fakeStore) SetJSON(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = data
+	return nil
+}
+
+func (s *fakeStore) SetJSONNX(_ context.Context, key string, value interface{}, _ time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[key]; ok {
+		return false, nil
+	}
+	s.records[key] = data
+	return true, nil
+}
+
+func (s *fakeStore) GetJSON(_ context.Context, key string, dest interface{}) error {
+	s.mu.Lock()
+	data, ok := s.records[key]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("key %s not found", key)
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (s *fakeStore) Expire(_ context.Context, key string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expires++
+	return nil
+}
+
+func (s *fakeStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+type idempotentRequest struct {
+	user         string
+	method       string
+	path         string
+	key          string
+	contentType  string
+	body         string
+	wantStatus   int
+	wantReplayed bool
+}
+
+func (tr idempotentRequest) build() *http.Request {
+	r := httptest.NewRequest(tr.method, tr.path, strings.NewReader(tr.body))
+	if tr.key != "" {
+		r.Header.Set(IdempotencyKeyHeader, tr.key)
+	}
+	if tr.contentType != "" {
+		r.Header.Set("Content-Type", tr.contentType)
+	}
+	if tr.user != "" {
+		r = r.WithContext(context.WithValue(r.Context(), "user_id", tr.user))
+	}
+	return r
+}
+
+func multipartBody(boundary, filename, content string) (string, string) {
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+	writer.SetBoundary(boundary)
+	writer.WriteField("title", "Etude")
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	writer.Close()
+
+	return writer.FormDataContentType(), b.String()
+}
+
+func TestIdempotency(t *testing.T) {
+	post := func(user, path, key, body string, wantStatus int, wantReplayed bool) idempotentRequest {
+		return idempotentRequest{
+			user:         user,
+			method:       http.MethodPost,
+			path:         path,
+			key:          key,
+			contentType:  "application/json",
+			body:         body,
+			wantStatus:   wantStatus,
+			wantReplayed: wantReplayed,
+		}
+	}
+	upload := func(boundary, content string, wantStatus int, wantReplayed bool) idempotentRequest {
+		contentType, body := multipartBody(boundary, "etude.mid", content)
+		return idempotentRequest{
+			user:         "user-1",
+			method:       http.MethodPost,
+			path:         "/tasks/import/midi",
+			key:          "upload",
+			contentType:  contentType,
+			body:         body,
+			wantStatus:   wantStatus,
+			wantReplayed: wantReplayed,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		maxBodySize int64
+		requests    []idempotentRequest
+		wantCalls   int
+	}{
+		{
+			name: "retry is replayed",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-1", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, true),
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "requests without a key",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-1", "/tasks", "", `{"title":"a"}`, http.StatusCreated, false),
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "reads are not stored",
+			requests: []idempotentRequest{
+				{user: "user-1", method: http.MethodGet, path: "/tasks/active", key: "key", wantStatus: http.StatusCreated},
+				{user: "user-1", method: http.MethodGet, path: "/tasks/active", key: "key", wantStatus: http.StatusCreated},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "keys are scoped to the user",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-2", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, false),
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "keys are scoped to the route",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-1", "/links", "key", `{"title":"a"}`, http.StatusCreated, false),
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "key reused for another payload",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "key", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-1", "/tasks", "key", `{"title":"b"}`, http.StatusUnprocessableEntity, false),
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "key reused with another query",
+			requests: []idempotentRequest{
+				post("user-1", "/tasks?dry_run=1", "key", `{"title":"a"}`, http.StatusCreated, false),
+				post("user-1", "/tasks?dry_run=0", "key", `{"title":"a"}`, http.StatusUnprocessableEntity, false),
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "server errors are not stored",
+			requests: []idempotentRequest{
+				post("user-1", "/fail", "key", `{}`, http.StatusInternalServerError, false),
+				post("user-1", "/fail", "key", `{}`, http.StatusInternalServerError, false),
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "upload retried with a new boundary",
+			requests: []idempotentRequest{
+				upload("boundary-1", "MThd", http.StatusCreated, false),
+				upload("boundary-2", "MThd", http.StatusCreated, true),
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "key reused for another file",
+			requests: []idempotentRequest{
+				upload("boundary-1", "MThd", http.StatusCreated, false),
+				upload("boundary-1", "MTrk", http.StatusUnprocessableEntity, false),
+			},
+			wantCalls: 1,
+		},
+		{
+			name:        "body too large",
+			maxBodySize: 64,
+			requests: []idempotentRequest{
+				post("user-1", "/tasks", "key", strings.Repeat("a", 65), http.StatusRequestEntityTooLarge, false),
+			},
+		},
+		{
+			name: "key without a user",
+			requests: []idempotentRequest{
+				post("", "/tasks", "key", `{}`, http.StatusUnauthorized, false),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				if r.URL.Path == "/fail" {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("ETag", `"1"`)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, "call %d, %d bytes", calls, len(body))
+			})
+
+			maxBodySize := tt.maxBodySize
+			if maxBodySize == 0 {
+				maxBodySize = 64 << 10
+			}
+			server := Idempotency(newFakeStore(), &config.Idempotency{}, slog.New(slog.DiscardHandler), maxBodySize)(handler)
+
+			var first *httptest.ResponseRecorder
+			for i, req := range tt.requests {
+				w := httptest.NewRecorder()
+				server.ServeHTTP(w, req.build())
+
+				if w.Code != req.wantStatus {
+					t.Fatalf("request %d: status = %d, want %d", i, w.Code, req.wantStatus)
+				}
+				replayed := w.Header().Get(IdempotencyReplayedHeader) == "true"
+				if replayed != req.wantReplayed {
+					t.Fatalf("request %d: replayed = %v, want %v", i, replayed, req.wantReplayed)
+				}
+				if replayed {
+					if w.Body.String() != first.Body.String() || w.Header().Get("ETag") != first.Header().Get("ETag") {
+						t.Errorf("request %d: replayed %q with ETag %s, want %q with ETag %s",
+							i, w.Body.String(), w.Header().Get("ETag"), first.Body.String(), first.Header().Get("ETag"))
+					}
+				}
+				if first == nil {
+					first = w
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	store := newFakeStore()
+	log := slog.New(slog.DiscardHandler)
+	request := idempotentRequest{user: "user-1", method: http.MethodPost, path: "/tasks", key: "key", body: `{}`}
+
+	var retry *httptest.ResponseRecorder
+	var server http.Handler
+	server = Idempotency(store, &config.Idempotency{LockTimeout: 20 * time.Millisecond}, log, 1<<10)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The lock must survive a handler slower than the lock timeout.
+			time.Sleep(50 * time.Millisecond)
+
+			retry = httptest.NewRecorder()
+			server.ServeHTTP(retry, request.build())
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, request.build())
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if retry.Code != http.StatusConflict {
+		t.Errorf("retry status = %d, want %d", retry.Code, http.StatusConflict)
+	}
+	if store.expires == 0 {
+		t.Errorf("lock was not extended")
+	}
+}
+
+func TestPublicIdempotency(t *testing.T) {
+	calls := 0
+	server := PublicIdempotency(newFakeStore(), &config.Idempotency{}, slog.New(slog.DiscardHandler), 1<<10)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			fmt.Fprintf(w, "token %d", calls)
+		}),
+	)
+
+	requests := []idempotentRequest{
+		{method: http.MethodPost, path: "/auth/login", key: "key", body: `{"email":"a@example.com"}`, wantStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/auth/login", key: "key", body: `{"email":"a@example.com"}`, wantStatus: http.StatusOK, wantReplayed: true},
+		{method: http.MethodPost, path: "/auth/login", key: "key", body: `{"email":"b@example.com"}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for i, req := range requests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req.build())
+
+		if w.Code != req.wantStatus {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, req.wantStatus)
+		}
+		if replayed := w.Header().Get(IdempotencyReplayedHeader) == "true"; replayed != req.wantReplayed {
+			t.Errorf("request %d: replayed = %v, want %v", i, replayed, req.wantReplayed)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
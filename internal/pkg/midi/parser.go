@@ -0,0 +1,344 @@
+package midi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// defaultTempo is the tempo of a Standard MIDI File until the first tempo
+// event, in microseconds per quarter note (120 BPM).
+const defaultTempo = 500000
+
+var (
+	ErrInvalidHeader = errors.New("invalid SMF header")
+	ErrInvalidTrack  = errors.New("invalid SMF track")
+)
+
+type File struct {
+	Format     int
+	TrackCount int
+	// Division is the number of ticks per quarter note. It is zero for SMPTE
+	// based files, which use TicksPerSecond instead.
+	Division        int
+	TicksPerSecond  float64
+	Title           string
+	Tempos          []Tempo
+	TimeSignatures  []TimeSignature
+	EndTick         int
+	Bars            int
+	DurationSeconds float64
+}
+
+type Tempo struct {
+	Tick    int
+	Bar     int
+	BPM     float64
+	Seconds float64
+}
+
+type TimeSignature struct {
+	Tick        int
+	Bar         int
+	Numerator   int
+	Denominator int
+}
+
+func (ts TimeSignature) String() string {
+	return fmt.Sprintf("%d/%d", ts.Numerator, ts.Denominator)
+}
+
+func Parse(data []byte) (*File, error) {
+	id, chunk, rest, err := readChunk(data)
+	if err != nil || id != "MThd" || len(chunk) < 6 {
+		return nil, ErrInvalidHeader
+	}
+
+	f := &File{
+		Format:     int(binary.BigEndian.Uint16(chunk[0:2])),
+		TrackCount: int(binary.BigEndian.Uint16(chunk[2:4])),
+	}
+
+	division := binary.BigEndian.Uint16(chunk[4:6])
+	if division&0x8000 != 0 {
+		fps := -int(int8(division >> 8))
+		f.TicksPerSecond = float64(fps * int(division&0xFF))
+	} else {
+		f.Division = int(division)
+	}
+
+	if f.Division == 0 && f.TicksPerSecond == 0 {
+		return nil, ErrInvalidHeader
+	}
+
+	track := 0
+	for len(rest) > 0 {
+		id, chunk, rest, err = readChunk(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		if id != "MTrk" {
+			continue
+		}
+
+		if err := f.parseTrack(chunk, track); err != nil {
+			return nil, fmt.Errorf("track %d: %w", track, err)
+		}
+		track++
+	}
+
+	if track == 0 {
+		return nil, fmt.Errorf("%w: no tracks", ErrInvalidTrack)
+	}
+
+	f.buildTempoMap()
+
+	return f, nil
+}
+
+func readChunk(data []byte) (string, []byte, []byte, error) {
+	if len(data) < 8 {
+		return "", nil, nil, fmt.Errorf("%w: truncated chunk", ErrInvalidTrack)
+	}
+
+	length := int(binary.BigEndian.Uint32(data[4:8]))
+	if length < 0 || len(data)-8 < length {
+		return "", nil, nil, fmt.Errorf("%w: chunk length out of range", ErrInvalidTrack)
+	}
+
+	return string(data[0:4]), data[8 : 8+length], data[8+length:], nil
+}
+
+func (f *File) parseTrack(data []byte, track int) error {
+	pos, tick := 0, 0
+	var running byte
+
+	for pos < len(data) {
+		delta, n, err := readVarInt(data[pos:])
+		if err != nil {
+			return err
+		}
+		pos += n
+		tick += delta
+
+		if pos >= len(data) {
+			return fmt.Errorf("%w: truncated event", ErrInvalidTrack)
+		}
+
+		status := data[pos]
+		if status < 0x80 {
+			if running == 0 {
+				return fmt.Errorf("%w: data byte without status", ErrInvalidTrack)
+			}
+			status = running
+		} else {
+			pos++
+		}
+
+		switch {
+		case status == 0xFF:
+			running = 0
+			if pos >= len(data) {
+				return fmt.Errorf("%w: truncated meta event", ErrInvalidTrack)
+			}
+			metaType := data[pos]
+			pos++
+
+			length, n, err := readVarInt(data[pos:])
+			if err != nil {
+				return err
+			}
+			pos += n
+			if len(data)-pos < length {
+				return fmt.Errorf("%w: meta event out of range", ErrInvalidTrack)
+			}
+			payload := data[pos : pos+length]
+			pos += length
+
+			if metaType == 0x2F {
+				pos = len(data)
+				break
+			}
+			f.handleMeta(metaType, payload, tick, track)
+		case status == 0xF0 || status == 0xF7:
+			running = 0
+			length, n, err := readVarInt(data[pos:])
+			if err != nil {
+				return err
+			}
+			pos += n + length
+		case status >= 0x80 && status < 0xF0:
+			running = status
+			if kind := status & 0xF0; kind == 0xC0 || kind == 0xD0 {
+				pos++
+			} else {
+				pos += 2
+			}
+		default:
+			return fmt.Errorf("%w: unexpected status 0x%X", ErrInvalidTrack, status)
+		}
+	}
+
+	if pos > len(data) {
+		return fmt.Errorf("%w: truncated event", ErrInvalidTrack)
+	}
+
+	if tick > f.EndTick {
+		f.EndTick = tick
+	}
+
+	return nil
+}
+
+func (f *File) handleMeta(metaType byte, payload []byte, tick, track int) {
+	switch metaType {
+	case 0x03:
+		if track == 0 && f.Title == "" {
+			f.Title = string(payload)
+		}
+	case 0x51:
+		if len(payload) != 3 {
+			return
+		}
+		micros := int(payload[0])<<16 | int(payload[1])<<8 | int(payload[2])
+		if micros > 0 {
+			f.Tempos = append(f.Tempos, Tempo{Tick: tick, BPM: 60000000 / float64(micros)})
+		}
+	case 0x58:
+		if len(payload) < 2 || payload[1] > 6 {
+			return
+		}
+		f.TimeSignatures = append(f.TimeSignatures, TimeSignature{
+			Tick:        tick,
+			Numerator:   int(payload[0]),
+			Denominator: 1 << payload[1],
+		})
+	}
+}
+
+// buildTempoMap sorts the tempo and meter changes, drops repeated values and
+// computes bar numbers, wall clock offsets and the total duration.
+func (f *File) buildTempoMap() {
+	sort.SliceStable(f.Tempos, func(i, j int) bool { return f.Tempos[i].Tick < f.Tempos[j].Tick })
+	sort.SliceStable(f.TimeSignatures, func(i, j int) bool { return f.TimeSignatures[i].Tick < f.TimeSignatures[j].Tick })
+
+	if len(f.Tempos) == 0 || f.Tempos[0].Tick > 0 {
+		f.Tempos = append([]Tempo{{Tick: 0, BPM: 60000000 / float64(defaultTempo)}}, f.Tempos...)
+	}
+	f.Tempos = dedupTempos(f.Tempos)
+
+	if len(f.TimeSignatures) == 0 || f.TimeSignatures[0].Tick > 0 {
+		f.TimeSignatures = append([]TimeSignature{{Tick: 0, Numerator: 4, Denominator: 4}}, f.TimeSignatures...)
+	}
+
+	for i := range f.TimeSignatures {
+		f.TimeSignatures[i].Bar = f.BarAt(f.TimeSignatures[i].Tick)
+	}
+
+	for i := range f.Tempos {
+		f.Tempos[i].Bar = f.BarAt(f.Tempos[i].Tick)
+		f.Tempos[i].Seconds = f.SecondsAt(f.Tempos[i].Tick)
+	}
+
+	f.DurationSeconds = f.SecondsAt(f.EndTick)
+	f.Bars = f.BarAt(f.EndTick)
+	if f.Division > 0 && f.EndTick > 0 && f.startsBar(f.EndTick) {
+		f.Bars--
+	}
+}
+
+// SecondsAt converts an absolute tick to seconds from the start of the file.
+func (f *File) SecondsAt(tick int) float64 {
+	if f.Division == 0 {
+		return float64(tick) / f.TicksPerSecond
+	}
+
+	seconds := 0.0
+	for i, t := range f.Tempos {
+		if t.Tick >= tick {
+			break
+		}
+
+		end := tick
+		if i+1 < len(f.Tempos) && f.Tempos[i+1].Tick < tick {
+			end = f.Tempos[i+1].Tick
+		}
+
+		seconds += float64(end-t.Tick) / float64(f.Division) * 60 / t.BPM
+	}
+
+	return seconds
+}
+
+// BarAt returns the 1-based bar number containing the tick.
+func (f *File) BarAt(tick int) int {
+	if f.Division == 0 {
+		return 1
+	}
+
+	bar := 1
+	for i, ts := range f.TimeSignatures {
+		ticksPerBar := f.ticksPerBar(ts)
+		if i+1 < len(f.TimeSignatures) && f.TimeSignatures[i+1].Tick <= tick {
+			span := f.TimeSignatures[i+1].Tick - ts.Tick
+			bar += (span + ticksPerBar - 1) / ticksPerBar
+			continue
+		}
+
+		return bar + (tick-ts.Tick)/ticksPerBar
+	}
+
+	return bar
+}
+
+func (f *File) startsBar(tick int) bool {
+	ts := f.TimeSignatures[0]
+	for _, t := range f.TimeSignatures {
+		if t.Tick <= tick {
+			ts = t
+		}
+	}
+
+	return (tick-ts.Tick)%f.ticksPerBar(ts) == 0
+}
+
+func (f *File) ticksPerBar(ts TimeSignature) int {
+	ticks := f.Division * 4 * ts.Numerator / ts.Denominator
+	if ticks <= 0 {
+		return f.Division * 4
+	}
+
+	return ticks
+}
+
+func dedupTempos(tempos []Tempo) []Tempo {
+	result := make([]Tempo, 0, len(tempos))
+	for _, t := range tempos {
+		if n := len(result); n > 0 && result[n-1].Tick == t.Tick {
+			result[n-1] = t
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1].BPM == t.BPM {
+			continue
+		}
+
+		result = append(result, t)
+	}
+
+	return result
+}
+
+func readVarInt(data []byte) (int, int, error) {
+	value := 0
+	for i := 0; i < 4 && i < len(data); i++ {
+		value = value<<7 | int(data[i]&0x7F)
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("%w: bad variable length quantity", ErrInvalidTrack)
+}
@@ -0,0 +1,223 @@
+package midi
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type event struct {
+	delta int
+	data  []byte
+}
+
+func varInt(value int) []byte {
+	b := []byte{byte(value & 0x7F)}
+	for value >>= 7; value > 0; value >>= 7 {
+		b = append([]byte{byte(value&0x7F | 0x80)}, b...)
+	}
+	return b
+}
+
+func chunk(id string, data []byte) []byte {
+	b := append([]byte(id), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func header(format, tracks int, division uint16) []byte {
+	data := make([]byte, 6)
+	binary.BigEndian.PutUint16(data[0:], uint16(format))
+	binary.BigEndian.PutUint16(data[2:], uint16(tracks))
+	binary.BigEndian.PutUint16(data[4:], division)
+	return chunk("MThd", data)
+}
+
+func track(events ...event) []byte {
+	var data []byte
+	for _, e := range events {
+		data = append(data, varInt(e.delta)...)
+		data = append(data, e.data...)
+	}
+	return chunk("MTrk", data)
+}
+
+func meta(delta int, kind byte, payload ...byte) event {
+	data := append([]byte{0xFF, kind}, varInt(len(payload))...)
+	return event{delta, append(data, payload...)}
+}
+
+func tempo(delta, micros int) event {
+	return meta(delta, 0x51, byte(micros>>16), byte(micros>>8), byte(micros))
+}
+
+func join(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	const bar = 4 * 480
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *File
+		wantErr error
+	}{
+		{
+			name: "tempo change",
+			data: join(
+				header(1, 2, 480),
+				track(
+					meta(0, 0x03, []byte("Etude")...),
+					meta(0, 0x58, 4, 2, 24, 8),
+					tempo(0, 500000),
+					tempo(4*bar, 1000000),
+					meta(4*bar, 0x2F),
+				),
+				// Notes with running status in the second track.
+				track(
+					event{0, []byte{0x90, 60, 100}},
+					event{480, []byte{60, 0}},
+					meta(0, 0x2F),
+				),
+			),
+			want: &File{
+				Format:          1,
+				TrackCount:      2,
+				Division:        480,
+				Title:           "Etude",
+				Tempos:          []Tempo{{Tick: 0, Bar: 1, BPM: 120}, {Tick: 4 * bar, Bar: 5, BPM: 60, Seconds: 8}},
+				TimeSignatures:  []TimeSignature{{Tick: 0, Bar: 1, Numerator: 4, Denominator: 4}},
+				EndTick:         8 * bar,
+				Bars:            8,
+				DurationSeconds: 24,
+			},
+		},
+		{
+			name: "default tempo and meter",
+			data: join(
+				header(0, 1, 96),
+				track(meta(3*96, 0x2F)),
+			),
+			want: &File{
+				Format:          0,
+				TrackCount:      1,
+				Division:        96,
+				Tempos:          []Tempo{{Tick: 0, Bar: 1, BPM: 120}},
+				TimeSignatures:  []TimeSignature{{Tick: 0, Bar: 1, Numerator: 4, Denominator: 4}},
+				EndTick:         3 * 96,
+				Bars:            1,
+				DurationSeconds: 1.5,
+			},
+		},
+		{
+			name: "SMPTE division",
+			data: join(
+				header(0, 1, 0xE728),
+				track(meta(1000, 0x2F)),
+			),
+			want: &File{
+				TrackCount:      1,
+				TicksPerSecond:  1000,
+				Tempos:          []Tempo{{Tick: 0, Bar: 1, BPM: 120}},
+				TimeSignatures:  []TimeSignature{{Tick: 0, Bar: 1, Numerator: 4, Denominator: 4}},
+				EndTick:         1000,
+				Bars:            1,
+				DurationSeconds: 1,
+			},
+		},
+		{
+			name:    "not a MIDI file",
+			data:    chunk("RIFF", make([]byte, 6)),
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:    "no tracks",
+			data:    header(0, 0, 480),
+			wantErr: ErrInvalidTrack,
+		},
+		{
+			name:    "data byte without status",
+			data:    join(header(0, 1, 480), track(event{0, []byte{60, 100}})),
+			wantErr: ErrInvalidTrack,
+		},
+		{
+			name:    "truncated chunk",
+			data:    join(header(0, 1, 480), []byte("MTrk\x00\x00\x01\x00")),
+			wantErr: ErrInvalidTrack,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(file, tt.want) {
+				t.Errorf("file = %+v, want %+v", file, tt.want)
+			}
+		})
+	}
+}
+
+func TestTempoSections(t *testing.T) {
+	tests := []struct {
+		name      string
+		tempos    []Tempo
+		bars      int
+		duration  float64
+		want      []Section
+		wantTempo float64
+	}{
+		{
+			name:      "two tempos",
+			tempos:    []Tempo{{Bar: 1, BPM: 120}, {Bar: 5, BPM: 60, Seconds: 8}},
+			bars:      8,
+			duration:  24,
+			want:      []Section{{StartBar: 1, EndBar: 4, BPM: 120}, {StartBar: 5, EndBar: 8, BPM: 60}},
+			wantTempo: 60,
+		},
+		{
+			name:      "fluctuation within tolerance",
+			tempos:    []Tempo{{Bar: 1, BPM: 120}, {Bar: 3, BPM: 118, Seconds: 4}, {Bar: 5, BPM: 121, Seconds: 8}},
+			bars:      8,
+			duration:  16,
+			want:      []Section{{StartBar: 1, EndBar: 8, BPM: 120}},
+			wantTempo: 121,
+		},
+		{
+			name:      "short ritardando merged",
+			tempos:    []Tempo{{Bar: 1, BPM: 120}, {Bar: 8, BPM: 80, Seconds: 14}},
+			bars:      8,
+			duration:  17,
+			want:      []Section{{StartBar: 1, EndBar: 8, BPM: 120}},
+			wantTempo: 120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &File{Tempos: tt.tempos, Bars: tt.bars, DurationSeconds: tt.duration}
+
+			if got := file.TempoSections(0.05, 2); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sections = %+v, want %+v", got, tt.want)
+			}
+			if got := file.PredominantBPM(); got != tt.wantTempo {
+				t.Errorf("predominant tempo = %v, want %v", got, tt.wantTempo)
+			}
+		})
+	}
+}
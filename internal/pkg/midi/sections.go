@@ -0,0 +1,69 @@
+package midi
+
+import "math"
+
+type Section struct {
+	StartBar int
+	EndBar   int
+	BPM      float64
+}
+
+// TempoSections splits the file into sections at tempo changes. Changes
+// smaller than tolerance (a fraction of the current tempo) are treated as
+// expressive fluctuation, and sections shorter than minBars are merged into
+// the preceding one so that ritardandos do not produce dozens of sections.
+func (f *File) TempoSections(tolerance float64, minBars int) []Section {
+	if len(f.Tempos) == 0 || f.Bars == 0 {
+		return nil
+	}
+
+	sections := []Section{{StartBar: f.Tempos[0].Bar, BPM: f.Tempos[0].BPM}}
+	for _, t := range f.Tempos[1:] {
+		current := &sections[len(sections)-1]
+		if math.Abs(t.BPM-current.BPM)/current.BPM < tolerance || t.Bar == current.StartBar {
+			continue
+		}
+
+		sections = append(sections, Section{StartBar: t.Bar, BPM: t.BPM})
+	}
+
+	for i := range sections {
+		sections[i].EndBar = f.Bars
+		if i+1 < len(sections) {
+			sections[i].EndBar = sections[i+1].StartBar - 1
+		}
+	}
+
+	merged := make([]Section, 0, len(sections))
+	for _, s := range sections {
+		if n := len(merged); n > 0 && s.EndBar-s.StartBar+1 < minBars {
+			merged[n-1].EndBar = s.EndBar
+			continue
+		}
+
+		merged = append(merged, s)
+	}
+
+	return merged
+}
+
+// PredominantBPM returns the tempo that is in effect for the longest time.
+func (f *File) PredominantBPM() float64 {
+	durations := make(map[float64]float64)
+	for i, t := range f.Tempos {
+		end := f.DurationSeconds
+		if i+1 < len(f.Tempos) {
+			end = f.Tempos[i+1].Seconds
+		}
+		durations[t.BPM] += end - t.Seconds
+	}
+
+	best, bestDuration := 0.0, -1.0
+	for _, t := range f.Tempos {
+		if durations[t.BPM] > bestDuration {
+			best, bestDuration = t.BPM, durations[t.BPM]
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,163 @@
+package musicxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type container struct {
+	RootFiles []rootFile `xml:"rootfiles>rootfile"`
+}
+
+type rootFile struct {
+	FullPath  string `xml:"full-path,attr"`
+	MediaType string `xml:"media-type,attr"`
+}
+
+type scorePartwise struct {
+	XMLName       xml.Name
+	WorkTitle     string    `xml:"work>work-title"`
+	MovementTitle string    `xml:"movement-title"`
+	Creators      []creator `xml:"identification>creator"`
+	Parts         []part    `xml:"part"`
+}
+
+type creator struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type part struct {
+	Measures []measure `xml:"measure"`
+}
+
+type measure struct {
+	Implicit   string       `xml:"implicit,attr"`
+	Attributes []attributes `xml:"attributes"`
+	Directions []direction  `xml:"direction"`
+	Sounds     []sound      `xml:"sound"`
+}
+
+type attributes struct {
+	Times []timeSignature `xml:"time"`
+}
+
+type timeSignature struct {
+	Beats    string `xml:"beats"`
+	BeatType string `xml:"beat-type"`
+}
+
+type direction struct {
+	Types []directionType `xml:"direction-type"`
+	Sound *sound          `xml:"sound"`
+}
+
+type directionType struct {
+	Rehearsals []string   `xml:"rehearsal"`
+	Words      []string   `xml:"words"`
+	Metronome  *metronome `xml:"metronome"`
+}
+
+type metronome struct {
+	BeatUnit  string     `xml:"beat-unit"`
+	Dots      []struct{} `xml:"beat-unit-dot"`
+	PerMinute string     `xml:"per-minute"`
+}
+
+type sound struct {
+	Tempo string `xml:"tempo,attr"`
+}
+
+func (s *scorePartwise) composer() string {
+	for _, c := range s.Creators {
+		if c.Type == "composer" {
+			return strings.TrimSpace(c.Value)
+		}
+	}
+
+	return ""
+}
+
+func (m *measure) timeSignature() string {
+	for _, a := range m.Attributes {
+		for _, t := range a.Times {
+			if t.Beats != "" && t.BeatType != "" {
+				return fmt.Sprintf("%s/%s", t.Beats, t.BeatType)
+			}
+		}
+	}
+
+	return ""
+}
+
+func (d *direction) rehearsals() []string {
+	var names []string
+	for _, t := range d.Types {
+		for _, r := range t.Rehearsals {
+			if name := strings.TrimSpace(r); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// tempo extracts the tempo of a direction. The sound element is always in
+// quarter notes per minute and wins over the printed metronome mark.
+func (d *direction) tempo() (Tempo, bool) {
+	var tempo Tempo
+	var words []string
+
+	for _, t := range d.Types {
+		for _, w := range t.Words {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, w)
+			}
+		}
+
+		if t.Metronome != nil && tempo.BPM == 0 {
+			tempo.BPM = t.Metronome.quarterBPM()
+		}
+	}
+
+	if d.Sound != nil {
+		if bpm, err := strconv.ParseFloat(d.Sound.Tempo, 64); err == nil && bpm > 0 {
+			tempo.BPM = bpm
+		}
+	}
+
+	tempo.Text = strings.Join(words, " ")
+
+	return tempo, tempo.BPM > 0
+}
+
+func (m *metronome) quarterBPM() float64 {
+	perMinute, err := strconv.ParseFloat(strings.TrimSpace(m.PerMinute), 64)
+	if err != nil || perMinute <= 0 {
+		return 0
+	}
+
+	quarters := map[string]float64{
+		"whole":   4,
+		"half":    2,
+		"quarter": 1,
+		"eighth":  0.5,
+		"16th":    0.25,
+	}
+
+	factor, ok := quarters[m.BeatUnit]
+	if !ok {
+		return 0
+	}
+
+	dot := factor / 2
+	for range m.Dots {
+		factor += dot
+		dot /= 2
+	}
+
+	return perMinute * factor
+}
@@ -0,0 +1,189 @@
+package musicxml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const maxScoreSize = 50 << 20
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported MusicXML format")
+	ErrNoParts           = errors.New("score has no parts")
+)
+
+type Score struct {
+	Title         string
+	Composer      string
+	TimeSignature string
+	Measures      int
+	Tempos        []Tempo
+	Sections      []Section
+}
+
+type Tempo struct {
+	Bar  int
+	BPM  float64
+	Text string
+}
+
+type Section struct {
+	Name     string
+	StartBar int
+	EndBar   int
+}
+
+// Parse reads an uncompressed MusicXML document or a compressed .mxl archive,
+// detecting the format by the zip signature.
+func Parse(data []byte) (*Score, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ParseMXL(data)
+	}
+
+	return ParseXML(bytes.NewReader(data))
+}
+
+func ParseMXL(data []byte) (*Score, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mxl archive: %w", err)
+	}
+
+	rootPath, err := findRootFile(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range archive.File {
+		if file.Name != rootPath {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		defer rc.Close()
+
+		return ParseXML(io.LimitReader(rc, maxScoreSize))
+	}
+
+	return nil, fmt.Errorf("root file %s not found in mxl archive", rootPath)
+}
+
+func ParseXML(r io.Reader) (*Score, error) {
+	var doc scorePartwise
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode MusicXML: %w", err)
+	}
+
+	if doc.XMLName.Local != "score-partwise" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, doc.XMLName.Local)
+	}
+
+	if len(doc.Parts) == 0 {
+		return nil, ErrNoParts
+	}
+
+	score := &Score{
+		Title:    strings.TrimSpace(doc.WorkTitle),
+		Composer: doc.composer(),
+	}
+	if score.Title == "" {
+		score.Title = strings.TrimSpace(doc.MovementTitle)
+	}
+
+	var marks []Section
+	bar := 0
+	for i, m := range doc.Parts[0].Measures {
+		if m.Implicit != "yes" || i > 0 {
+			bar++
+		}
+
+		if score.TimeSignature == "" {
+			score.TimeSignature = m.timeSignature()
+		}
+
+		for _, d := range m.Directions {
+			for _, name := range d.rehearsals() {
+				marks = append(marks, Section{Name: name, StartBar: bar})
+			}
+
+			if tempo, ok := d.tempo(); ok {
+				tempo.Bar = bar
+				score.Tempos = append(score.Tempos, tempo)
+			}
+		}
+
+		for _, snd := range m.Sounds {
+			if bpm, err := strconv.ParseFloat(snd.Tempo, 64); err == nil && bpm > 0 {
+				score.Tempos = append(score.Tempos, Tempo{Bar: bar, BPM: bpm})
+			}
+		}
+	}
+	score.Measures = bar
+	score.Sections = closeSections(marks, bar)
+
+	return score, nil
+}
+
+// closeSections turns rehearsal marks into bar ranges: each section lasts
+// until the bar before the next mark, the last one until the end of the piece.
+func closeSections(marks []Section, lastBar int) []Section {
+	sections := make([]Section, 0, len(marks))
+	for i, mark := range marks {
+		mark.EndBar = lastBar
+		if i+1 < len(marks) {
+			mark.EndBar = marks[i+1].StartBar - 1
+		}
+
+		if mark.EndBar < mark.StartBar {
+			continue
+		}
+
+		sections = append(sections, mark)
+	}
+
+	return sections
+}
+
+func findRootFile(archive *zip.Reader) (string, error) {
+	for _, file := range archive.File {
+		if file.Name != "META-INF/container.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open container.xml: %w", err)
+		}
+		defer rc.Close()
+
+		var c container
+		if err := xml.NewDecoder(rc).Decode(&c); err != nil {
+			return "", fmt.Errorf("failed to decode container.xml: %w", err)
+		}
+
+		for _, root := range c.RootFiles {
+			if root.MediaType == "" || strings.Contains(root.MediaType, "musicxml") {
+				return root.FullPath, nil
+			}
+		}
+	}
+
+	for _, file := range archive.File {
+		ext := strings.ToLower(path.Ext(file.Name))
+		if !strings.HasPrefix(file.Name, "META-INF/") && (ext == ".xml" || ext == ".musicxml") {
+			return file.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no score in mxl archive", ErrUnsupportedFormat)
+}
@@ -0,0 +1,153 @@
+package musicxml
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const etude = `<?xml version="1.0" encoding="UTF-8"?>
+<score-partwise version="4.0">
+  <work><work-title> Etude </work-title></work>
+  <identification><creator type="composer">Czerny</creator></identification>
+  <part id="P1">
+    <measure number="0" implicit="yes">
+      <attributes><time><beats>3</beats><beat-type>4</beat-type></time></attributes>
+      <direction>
+        <direction-type><words>Allegro</words></direction-type>
+        <direction-type><metronome><beat-unit>quarter</beat-unit><per-minute>120</per-minute></metronome></direction-type>
+      </direction>
+    </measure>
+    <measure number="1">
+      <direction><direction-type><rehearsal>A</rehearsal></direction-type></direction>
+    </measure>
+    <measure number="2"/>
+    <measure number="3">
+      <direction><direction-type><rehearsal>B</rehearsal></direction-type></direction>
+      <direction>
+        <direction-type><metronome><beat-unit>half</beat-unit><beat-unit-dot/><per-minute>40</per-minute></metronome></direction-type>
+      </direction>
+    </measure>
+    <measure number="4"><sound tempo="90"/></measure>
+  </part>
+</score-partwise>`
+
+func TestParseXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    *Score
+		wantErr error
+	}{
+		{
+			name: "tempos and rehearsal marks",
+			doc:  etude,
+			want: &Score{
+				Title:         "Etude",
+				Composer:      "Czerny",
+				TimeSignature: "3/4",
+				Measures:      4,
+				Tempos: []Tempo{
+					{Bar: 0, BPM: 120, Text: "Allegro"},
+					{Bar: 3, BPM: 120},
+					{Bar: 4, BPM: 90},
+				},
+				Sections: []Section{
+					{Name: "A", StartBar: 1, EndBar: 2},
+					{Name: "B", StartBar: 3, EndBar: 4},
+				},
+			},
+		},
+		{
+			name: "sound overrides the metronome mark",
+			doc: `<score-partwise><movement-title>Waltz</movement-title><part><measure>
+				<direction>
+					<direction-type><metronome><beat-unit>eighth</beat-unit><per-minute>200</per-minute></metronome></direction-type>
+					<sound tempo="96"/>
+				</direction>
+			</measure></part></score-partwise>`,
+			want: &Score{
+				Title:    "Waltz",
+				Measures: 1,
+				Tempos:   []Tempo{{Bar: 1, BPM: 96}},
+				Sections: []Section{},
+			},
+		},
+		{
+			name:    "timewise score",
+			doc:     `<score-timewise><part/></score-timewise>`,
+			wantErr: ErrUnsupportedFormat,
+		},
+		{
+			name:    "no parts",
+			doc:     `<score-partwise></score-partwise>`,
+			wantErr: ErrNoParts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := ParseXML(bytes.NewReader([]byte(tt.doc)))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(score, tt.want) {
+				t.Errorf("score = %+v, want %+v", score, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMXL(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name: "root file from container",
+			files: map[string]string{
+				"META-INF/container.xml": `<container><rootfiles><rootfile full-path="score/etude.xml" media-type="application/vnd.recordare.musicxml+xml"/></rootfiles></container>`,
+				"score/etude.xml":        etude,
+			},
+		},
+		{
+			name: "without container",
+			files: map[string]string{
+				"etude.musicxml": etude,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			archive := zip.NewWriter(&b)
+			for name, content := range tt.files {
+				w, err := archive.Create(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(content))
+			}
+			if err := archive.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			score, err := Parse(b.Bytes())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if score.Title != "Etude" || score.Measures != 4 {
+				t.Errorf("score = %+v, want the etude", score)
+			}
+		})
+	}
+}
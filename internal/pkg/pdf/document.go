@@ -0,0 +1,334 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// fallbackAdvance approximates the glyph width of Helvetica when no font is
+// embedded.
+const fallbackAdvance = 556
+
+type Color struct {
+	R, G, B float64
+}
+
+var (
+	Black = Color{0, 0, 0}
+	White = Color{1, 1, 1}
+)
+
+// Document builds a PDF of A4 pages. Coordinates are in points from the top
+// left corner of the page. Text is set in the embedded font, or in Helvetica
+// when the document has none; Helvetica only covers Latin, so other scripts
+// are transliterated where possible. So are the characters the embedded font
+// has no glyph for.
+type Document struct {
+	font  *Font
+	pages []*Page
+	used  map[uint16]rune
+	title string
+}
+
+func NewDocument(font *Font, title string) *Document {
+	return &Document{
+		font:  font,
+		used:  make(map[uint16]rune),
+		title: title,
+	}
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// TextWidth returns the width of the text set in the given size.
+func (d *Document) TextWidth(text string, size float64) float64 {
+	total := 0
+	if d.font == nil {
+		total = len([]rune(transliterate(text))) * fallbackAdvance
+	} else {
+		for _, r := range d.font.cover(text) {
+			total += d.font.advance(d.font.glyph(r))
+		}
+	}
+
+	return float64(total) * size / 1000
+}
+
+// Fit shortens the text with an ellipsis so it is at most width wide.
+func (d *Document) Fit(text string, size, width float64) string {
+	if d.TextWidth(text, size) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if d.TextWidth(candidate, size) <= width {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() ([]byte, error) {
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	catalog := w.reserve()
+	pagesID := w.reserve()
+	fontID, err := d.writeFont(w)
+	if err != nil {
+		return nil, err
+	}
+
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		content, err := deflate(page.content.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		contentID := w.stream(fmt.Sprintf("/Filter /FlateDecode /Length %d", len(content)), content)
+
+		pageID := w.object(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, PageWidth, PageHeight, fontID, contentID,
+		))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+
+	w.set(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	info := w.object(fmt.Sprintf("<< /Title %s /Producer (Trackmus) >>", d.textString(d.title)))
+
+	w.finish(catalog, info)
+
+	return w.buf.Bytes(), nil
+}
+
+func (d *Document) writeFont(w *writer) (int, error) {
+	if d.font == nil {
+		return w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"), nil
+	}
+
+	f := d.font
+	data, err := deflate(f.data)
+	if err != nil {
+		return 0, err
+	}
+	fileID := w.stream(fmt.Sprintf("/Filter /FlateDecode /Length %d /Length1 %d", len(data), len(f.data)), data)
+
+	descriptorID := w.object(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.ascent), fileID,
+	))
+
+	glyphs := make([]int, 0, len(d.used))
+	for glyph := range d.used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, f.advance(uint16(glyph)))
+	}
+
+	cidID := w.object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		f.name, descriptorID, widths.String(),
+	))
+
+	toUnicode := d.toUnicode(glyphs)
+	toUnicodeID := w.stream(fmt.Sprintf("/Length %d", len(toUnicode)), toUnicode)
+
+	return w.object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cidID, toUnicodeID,
+	)), nil
+}
+
+// toUnicode maps the glyphs back to characters, so text can be searched and
+// copied from the document.
+func (d *Document) toUnicode(glyphs []int) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%04X>\n", glyph, d.used[uint16(glyph)])
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	return b.Bytes()
+}
+
+// encode returns the text as a PDF string operand for the document font.
+func (d *Document) encode(text string) string {
+	if d.font == nil {
+		return literal(transliterate(text))
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range d.font.cover(text) {
+		glyph := d.font.glyph(r)
+		if glyph != 0 {
+			d.used[glyph] = r
+		}
+		fmt.Fprintf(&b, "%04X", glyph)
+	}
+	b.WriteByte('>')
+
+	return b.String()
+}
+
+// textString encodes text outside of page content, e.g. document metadata,
+// as UTF-16 with a byte order mark.
+func (d *Document) textString(text string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range text {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteByte('>')
+
+	return b.String()
+}
+
+// Page holds the drawing operations of one page.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// Text draws the text with its baseline at y.
+func (p *Page) Text(x, y, size float64, color Color, text string) {
+	fmt.Fprintf(&p.content, "BT %.3f %.3f %.3f rg /F1 %.2f Tf %.2f %.2f Td %s Tj ET\n",
+		color.R, color.G, color.B, size, x, PageHeight-y, p.doc.encode(text))
+}
+
+// TextRight draws the text so that it ends at x.
+func (p *Page) TextRight(x, y, size float64, color Color, text string) {
+	p.Text(x-p.doc.TextWidth(text, size), y, size, color, text)
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG %.2f w [] 0 d %.2f %.2f m %.2f %.2f l S\n",
+		color.R, color.G, color.B, width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// DashedLine draws a line of dash long strokes with gaps of the same length.
+func (p *Page) DashedLine(x1, y1, x2, y2, width, dash float64, color Color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG %.2f w [%.2f] 0 d %.2f %.2f m %.2f %.2f l S [] 0 d\n",
+		color.R, color.G, color.B, width, dash, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Rect fills the rectangle whose top left corner is at x, y.
+func (p *Page) Rect(x, y, w, h float64, color Color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		color.R, color.G, color.B, x, PageHeight-y-h, w, h)
+}
+
+// Polyline connects the points, given as x, y pairs.
+func (p *Page) Polyline(points [][2]float64, width float64, color Color) {
+	if len(points) == 0 {
+		return
+	}
+
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG %.2f w [] 0 d 1 j", color.R, color.G, color.B, width)
+	for i, point := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&p.content, " %.2f %.2f %s", point[0], PageHeight-point[1], op)
+	}
+	p.content.WriteString(" S\n")
+}
+
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// reserve allocates an object number to be written later with set.
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, -1)
+	return len(w.offsets)
+}
+
+func (w *writer) set(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *writer) object(body string) int {
+	id := w.reserve()
+	w.set(id, body)
+	return id
+}
+
+func (w *writer) stream(dict string, data []byte) int {
+	id := w.reserve()
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s >>\nstream\n", id, dict)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return id
+}
+
+func (w *writer) finish(root, info int) {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, root, info, xref)
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := io.Copy(zw, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
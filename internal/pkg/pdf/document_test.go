@@ -0,0 +1,239 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Etude (no. 5)", want: "Etude (no. 5)"},
+		{text: "Гаммы терциями", want: "Gammy tertsiyami"},
+		{text: "Щука и Ёж", want: "Shchuka i Ezh"},
+		{text: "подъём", want: "podem"},
+		{text: "«Соната» — №2…", want: `"Sonata" - No2...`},
+		{text: "ß ♪", want: "? ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := transliterate(tt.text); got != tt.want {
+				t.Errorf("transliterate(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	font, err := ParseFont("test", testFont{chars: "Gamyг", unitsPerEm: 1000}.build())
+	if err != nil {
+		t.Fatalf("ParseFont: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		font     *Font
+		text     string
+		want     string
+		wantUsed map[uint16]rune
+	}{
+		{
+			name:     "glyphs of the font",
+			font:     font,
+			text:     "Gamг",
+			want:     "<0001000200030005>",
+			wantUsed: map[uint16]rune{1: 'G', 2: 'a', 3: 'm', 5: 'г'},
+		},
+		{
+			name:     "transliterated where the font has no glyph",
+			font:     font,
+			text:     "гаммы",
+			want:     "<00050002000300030004>",
+			wantUsed: map[uint16]rune{2: 'a', 3: 'm', 4: 'y', 5: 'г'},
+		},
+		{
+			name:     "missing glyph when the transliteration is not covered either",
+			font:     font,
+			text:     "aж♪",
+			want:     "<000200000000>",
+			wantUsed: map[uint16]rune{2: 'a'},
+		},
+		{
+			name:     "helvetica",
+			text:     "Гамма (ля) \\ №1",
+			want:     `(Gamma \(lya\) \\ No1)`,
+			wantUsed: map[uint16]rune{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewDocument(tt.font, "test")
+
+			if got := doc.encode(tt.text); got != tt.want {
+				t.Errorf("encode(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+			if fmt.Sprint(doc.used) != fmt.Sprint(tt.wantUsed) {
+				t.Errorf("used glyphs = %v, want %v", doc.used, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	font, err := ParseFont("test", testFont{chars: "ab", unitsPerEm: 1000}.build())
+	if err != nil {
+		t.Fatalf("ParseFont: %v", err)
+	}
+
+	// Glyph advances are 100 for the missing glyph, 200 for a, 300 for b.
+	if got := NewDocument(font, "").TextWidth("abх", 10); got != 6 {
+		t.Errorf("width = %v, want 6 with х set as the missing glyph", got)
+	}
+	if got := NewDocument(font, "").TextWidth("бб", 10); got != 6 {
+		t.Errorf("width = %v, want 6 with б transliterated to b", got)
+	}
+	if got := NewDocument(nil, "").TextWidth("жa", 10); got != 16.68 {
+		t.Errorf("helvetica width = %v, want 16.68 for zha", got)
+	}
+}
+
+func TestDocumentBytes(t *testing.T) {
+	font, err := ParseFont("test", testFont{chars: "Etude", unitsPerEm: 1000}.build())
+	if err != nil {
+		t.Fatalf("ParseFont: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		font      *Font
+		pages     int
+		wantFonts []string
+	}{
+		{name: "embedded font", font: font, pages: 2, wantFonts: []string{"/Type0", "/CIDFontType2", "/FontFile2"}},
+		{name: "helvetica", pages: 1, wantFonts: []string{"/BaseFont /Helvetica"}},
+		{name: "no pages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewDocument(tt.font, "Журнал (test)")
+			for i := 0; i < tt.pages; i++ {
+				page := doc.AddPage()
+				page.Text(10, 20, 12, Black, "Etude")
+				page.Polyline([][2]float64{{0, 0}, {10, 10}}, 1, Black)
+			}
+
+			data, err := doc.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes: %v", err)
+			}
+
+			objects := checkStructure(t, data)
+
+			if got := strings.Count(string(data), "/Type /Page "); got != tt.pages {
+				t.Errorf("pages = %d, want %d", got, tt.pages)
+			}
+			if !strings.Contains(objects[1], "/Type /Catalog") {
+				t.Errorf("object 1 = %q, want the catalog", objects[1])
+			}
+			if !strings.Contains(string(data), fmt.Sprintf("/Count %d", tt.pages)) {
+				t.Errorf("page tree does not count %d pages", tt.pages)
+			}
+			for _, want := range tt.wantFonts {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("output has no %s", want)
+				}
+			}
+		})
+	}
+}
+
+var (
+	startxrefPattern = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	trailerPattern   = regexp.MustCompile(`trailer\n<< /Size (\d+) /Root (\d+) 0 R /Info (\d+) 0 R >>`)
+	streamPattern    = regexp.MustCompile(`/Length (\d+)[^>]*>>\nstream\n`)
+)
+
+// checkStructure checks that every xref entry points at its object, that
+// the trailer refers to existing objects and that every stream is as long as
+// its dictionary says. It returns the objects by number.
+func checkStructure(t *testing.T, data []byte) map[int]string {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.7\n")) {
+		t.Fatalf("output does not start with a PDF header")
+	}
+
+	match := startxrefPattern.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("output does not end with startxref and %%%%EOF")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if xref >= len(data) || !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	lines := strings.Split(string(data[xref:]), "\n")
+	var size int
+	fmt.Sscanf(lines[1], "0 %d", &size)
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("first xref entry = %q, want the free entry", lines[2])
+	}
+
+	objects := make(map[int]string, size)
+	for id := 1; id < size; id++ {
+		entry := lines[2+id]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q", id, entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+
+		header := fmt.Sprintf("%d 0 obj\n", id)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("xref entry %d points at %q", id, data[offset:min(offset+20, len(data))])
+		}
+
+		body := data[offset+len(header):]
+		if loc := streamPattern.FindSubmatchIndex(body); loc != nil && bytes.Index(body, []byte("endobj")) > loc[1] {
+			length, _ := strconv.Atoi(string(body[loc[2]:loc[3]]))
+			if !bytes.HasPrefix(body[loc[1]+length:], []byte("\nendstream\nendobj\n")) {
+				t.Errorf("stream of object %d is not %d bytes long", id, length)
+			}
+			body = body[:loc[1]]
+		} else {
+			end := bytes.Index(body, []byte("\nendobj\n"))
+			if end < 0 {
+				t.Fatalf("object %d has no endobj", id)
+			}
+			body = body[:end]
+		}
+		objects[id] = string(body)
+	}
+
+	if got := bytes.Count(data, []byte(" 0 obj\n")); got != size-1 {
+		t.Errorf("output has %d objects, xref lists %d", got, size-1)
+	}
+
+	trailer := trailerPattern.FindStringSubmatch(string(data[xref:]))
+	if trailer == nil {
+		t.Fatalf("trailer not found")
+	}
+	if trailer[1] != strconv.Itoa(size) {
+		t.Errorf("trailer size = %s, xref has %d entries", trailer[1], size)
+	}
+	for _, ref := range trailer[2:] {
+		if n, _ := strconv.Atoi(ref); n <= 0 || n >= size {
+			t.Errorf("trailer refers to object %d of %d", n, size-1)
+		}
+	}
+
+	return objects
+}
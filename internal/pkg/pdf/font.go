@@ -0,0 +1,257 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Font is a TrueType font embedded into documents as a whole, so any text
+// the font covers, Cyrillic included, can be written.
+type Font struct {
+	name       string
+	data       []byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	bbox       [4]int
+	advances   []int
+	glyphs     map[rune]uint16
+}
+
+// LoadFont reads a TrueType font file.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return ParseFont(name, data)
+}
+
+// ParseFont parses the tables of a TrueType font needed to lay out and embed
+// text: metrics, glyph advances and the Unicode BMP character map.
+func ParseFont(name string, data []byte) (*Font, error) {
+	tables, err := readTables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("font has no %s table", tag)
+		}
+	}
+
+	font := &Font{
+		name: sanitizeName(name),
+		data: data,
+	}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, fmt.Errorf("font head table is too short")
+	}
+	font.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if font.unitsPerEm == 0 {
+		return nil, fmt.Errorf("font has zero units per em")
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, fmt.Errorf("font hhea table is too short")
+	}
+	font.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	font.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	maxp := tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, fmt.Errorf("font maxp table is too short")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	hmtx := tables["hmtx"]
+	if metrics == 0 || len(hmtx) < metrics*4 {
+		return nil, fmt.Errorf("font hmtx table is too short")
+	}
+	font.advances = make([]int, numGlyphs)
+	for i := range font.advances {
+		if i < metrics {
+			font.advances[i] = int(binary.BigEndian.Uint16(hmtx[i*4:]))
+		} else {
+			font.advances[i] = font.advances[metrics-1]
+		}
+	}
+
+	font.glyphs, err = readCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+
+	return font, nil
+}
+
+func (f *Font) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// cover transliterates the characters the font has no glyph for when the
+// font has glyphs for the transliteration. The rest are left to the missing
+// glyph.
+func (f *Font) cover(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if f.glyph(r) != 0 {
+			b.WriteRune(r)
+			continue
+		}
+
+		latin := transliterate(string(r))
+		if latin == "?" || strings.IndexFunc(latin, func(c rune) bool { return f.glyph(c) == 0 }) >= 0 {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString(latin)
+	}
+
+	return b.String()
+}
+
+// advance returns the width of the glyph in thousandths of the font size.
+func (f *Font) advance(glyph uint16) int {
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+
+	return f.scale(f.advances[glyph])
+}
+
+func (f *Font) scale(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+func readTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font file is too short")
+	}
+
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000, 0x74727565:
+	default:
+		return nil, fmt.Errorf("not a TrueType font")
+	}
+
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+count*16 {
+		return nil, fmt.Errorf("font table directory is truncated")
+	}
+
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		record := data[12+i*16:]
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of bounds", record[:4])
+		}
+		tables[string(record[:4])] = data[offset : offset+length]
+	}
+
+	return tables, nil
+}
+
+// readCmap reads a format 4 subtable of the Unicode or Windows Unicode BMP
+// encoding.
+func readCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, fmt.Errorf("font cmap table is too short")
+	}
+
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count && 4+i*8+8 <= len(cmap); i++ {
+		record := cmap[4+i*8:]
+		platform := binary.BigEndian.Uint16(record)
+		encoding := binary.BigEndian.Uint16(record[2:])
+		offset := int(binary.BigEndian.Uint32(record[4:]))
+
+		if !(platform == 3 && encoding == 1) && platform != 0 {
+			continue
+		}
+		if offset+2 > len(cmap) || binary.BigEndian.Uint16(cmap[offset:]) != 4 {
+			continue
+		}
+
+		return readCmapFormat4(cmap[offset:])
+	}
+
+	return nil, fmt.Errorf("font has no Unicode BMP character map")
+}
+
+func readCmapFormat4(table []byte) (map[rune]uint16, error) {
+	if len(table) < 14 {
+		return nil, fmt.Errorf("font cmap subtable is too short")
+	}
+
+	segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segments*2 + 2
+	deltas := startCodes + segments*2
+	rangeOffsets := deltas + segments*2
+	if len(table) < rangeOffsets+segments*2 {
+		return nil, fmt.Errorf("font cmap subtable is truncated")
+	}
+
+	glyphs := make(map[rune]uint16)
+	for i := 0; i < segments; i++ {
+		end := int(binary.BigEndian.Uint16(table[endCodes+i*2:]))
+		start := int(binary.BigEndian.Uint16(table[startCodes+i*2:]))
+		delta := binary.BigEndian.Uint16(table[deltas+i*2:])
+		rangeOffsetPos := rangeOffsets + i*2
+		rangeOffset := int(binary.BigEndian.Uint16(table[rangeOffsetPos:]))
+
+		for code := start; code <= end && code != 0xFFFF; code++ {
+			var glyph uint16
+			if rangeOffset == 0 {
+				glyph = uint16(code) + delta
+			} else {
+				pos := rangeOffsetPos + rangeOffset + (code-start)*2
+				if pos+2 > len(table) {
+					continue
+				}
+				glyph = binary.BigEndian.Uint16(table[pos:])
+				if glyph != 0 {
+					glyph += delta
+				}
+			}
+
+			if glyph != 0 {
+				glyphs[rune(code)] = glyph
+			}
+		}
+	}
+
+	return glyphs, nil
+}
+
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r > ' ' && r < 127 && !strings.ContainsRune("()<>[]{}/%#", r) {
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "EmbeddedFont"
+	}
+
+	return b.String()
+}
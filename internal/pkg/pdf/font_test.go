@@ -0,0 +1,208 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testFont is a font whose glyph i+1 draws the i-th character of chars.
+type testFont struct {
+	chars      string
+	unitsPerEm int
+	// metrics is the number of advances stored in hmtx; the glyphs after
+	// them repeat the last one.
+	metrics int
+	skip    string
+	cmap    []byte
+}
+
+func (tf testFont) build() []byte {
+	chars := []rune(tf.chars)
+	numGlyphs := len(chars) + 1
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], uint16(tf.unitsPerEm))
+	for i, v := range []int16{-100, -200, 1000, 900} {
+		binary.BigEndian.PutUint16(head[36+2*i:], uint16(v))
+	}
+
+	metrics := tf.metrics
+	if metrics == 0 {
+		metrics = numGlyphs
+	}
+	ascent, descent := int16(800), int16(-200)
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[4:], uint16(ascent))
+	binary.BigEndian.PutUint16(hhea[6:], uint16(descent))
+	binary.BigEndian.PutUint16(hhea[34:], uint16(metrics))
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(numGlyphs))
+
+	hmtx := make([]byte, metrics*4)
+	for i := 0; i < metrics; i++ {
+		binary.BigEndian.PutUint16(hmtx[i*4:], uint16(100*(i+1)))
+	}
+
+	cmap := tf.cmap
+	if cmap == nil {
+		cmap = cmapTable(3, 1, cmapFormat4(chars))
+	}
+
+	tables := map[string][]byte{"head": head, "hhea": hhea, "maxp": maxp, "hmtx": hmtx, "cmap": cmap}
+	for _, tag := range strings.Fields(tf.skip) {
+		delete(tables, tag)
+	}
+
+	return sfnt(tables)
+}
+
+func sfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	data := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(data, 0x00010000)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(tags)))
+	for i, tag := range tags {
+		record := data[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(tables[tag])))
+		data = append(data, tables[tag]...)
+	}
+
+	return data
+}
+
+func cmapTable(platform, encoding uint16, subtable []byte) []byte {
+	cmap := make([]byte, 12)
+	binary.BigEndian.PutUint16(cmap[2:], 1)
+	binary.BigEndian.PutUint16(cmap[4:], platform)
+	binary.BigEndian.PutUint16(cmap[6:], encoding)
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	return append(cmap, subtable...)
+}
+
+// cmapFormat4 maps the characters to glyphs 1, 2, ... with one segment per
+// character. Latin segments use an ID delta, the others the glyph array, so
+// both ways of the format are read.
+func cmapFormat4(chars []rune) []byte {
+	type segment struct {
+		code  rune
+		glyph uint16
+	}
+	segments := make([]segment, 0, len(chars)+1)
+	for i, r := range chars {
+		segments = append(segments, segment{code: r, glyph: uint16(i + 1)})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].code < segments[j].code })
+	segments = append(segments, segment{code: 0xFFFF})
+
+	n := len(segments)
+	table := make([]byte, 16+8*n)
+	binary.BigEndian.PutUint16(table, 4)
+	binary.BigEndian.PutUint16(table[6:], uint16(2*n))
+
+	endCodes, startCodes := 14, 16+2*n
+	deltas, rangeOffsets := startCodes+2*n, startCodes+4*n
+	for i, seg := range segments {
+		binary.BigEndian.PutUint16(table[endCodes+2*i:], uint16(seg.code))
+		binary.BigEndian.PutUint16(table[startCodes+2*i:], uint16(seg.code))
+		switch {
+		case seg.code == 0xFFFF:
+			binary.BigEndian.PutUint16(table[deltas+2*i:], 1)
+		case seg.code < 0x80:
+			binary.BigEndian.PutUint16(table[deltas+2*i:], seg.glyph-uint16(seg.code))
+		default:
+			offset := len(table) - (rangeOffsets + 2*i)
+			binary.BigEndian.PutUint16(table[rangeOffsets+2*i:], uint16(offset))
+			table = binary.BigEndian.AppendUint16(table, seg.glyph)
+		}
+	}
+	binary.BigEndian.PutUint16(table[2:], uint16(len(table)))
+
+	return table
+}
+
+func TestParseFont(t *testing.T) {
+	data := testFont{chars: "Aaя", unitsPerEm: 2000, metrics: 2}.build()
+
+	font, err := ParseFont("My Font (Bold)", data)
+	if err != nil {
+		t.Fatalf("ParseFont: %v", err)
+	}
+
+	if font.name != "MyFontBold" {
+		t.Errorf("name = %q, want MyFontBold", font.name)
+	}
+	if font.scale(font.ascent) != 400 || font.scale(font.descent) != -100 {
+		t.Errorf("ascent, descent = %d, %d, want 400, -100", font.scale(font.ascent), font.scale(font.descent))
+	}
+	if font.bbox != [4]int{-100, -200, 1000, 900} {
+		t.Errorf("bbox = %v", font.bbox)
+	}
+
+	glyphs := []struct {
+		r       rune
+		glyph   uint16
+		advance int
+	}{
+		{r: 'A', glyph: 1, advance: 100},
+		{r: 'a', glyph: 2, advance: 100},
+		{r: 'я', glyph: 3, advance: 100},
+		{r: 'b'},
+	}
+	for _, g := range glyphs {
+		if got := font.glyph(g.r); got != g.glyph {
+			t.Errorf("glyph(%q) = %d, want %d", g.r, got, g.glyph)
+		}
+		if g.glyph != 0 {
+			if got := font.advance(g.glyph); got != g.advance {
+				t.Errorf("advance of %q = %d, want %d", g.r, got, g.advance)
+			}
+		}
+	}
+	if got := font.advance(0); got != 50 {
+		t.Errorf("advance of the missing glyph = %d, want 50", got)
+	}
+}
+
+func TestParseFontErrors(t *testing.T) {
+	outOfBounds := testFont{chars: "a", unitsPerEm: 1000}.build()
+	binary.BigEndian.PutUint32(outOfBounds[12+12:], uint32(len(outOfBounds)))
+
+	openType := testFont{chars: "a", unitsPerEm: 1000}.build()
+	copy(openType, "OTTO")
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "too short", data: []byte{0, 1, 0}, want: "too short"},
+		{name: "not truetype", data: openType, want: "not a TrueType font"},
+		{name: "table out of bounds", data: outOfBounds, want: "out of bounds"},
+		{name: "missing table", data: testFont{chars: "a", unitsPerEm: 1000, skip: "hmtx"}.build(), want: "no hmtx table"},
+		{name: "zero units per em", data: testFont{chars: "a"}.build(), want: "zero units per em"},
+		{
+			name: "no unicode cmap",
+			data: testFont{chars: "a", unitsPerEm: 1000, cmap: cmapTable(1, 0, cmapFormat4([]rune("a")))}.build(),
+			want: "no Unicode BMP character map",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFont("test", tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
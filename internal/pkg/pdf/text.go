@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"strings"
+)
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+var punctuation = map[rune]string{
+	'…': "...", '—': "-", '–': "-", '«': "\"", '»': "\"", '→': "->", '·': "-", '№': "No",
+}
+
+// transliterate reduces the text to printable ASCII for the fallback font.
+// Russian letters are romanized, anything else unknown becomes '?'.
+func transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= ' ' && r < 127:
+			b.WriteRune(r)
+		case punctuation[r] != "":
+			b.WriteString(punctuation[r])
+		default:
+			lower := []rune(strings.ToLower(string(r)))[0]
+			latin, ok := cyrillic[lower]
+			if !ok {
+				b.WriteByte('?')
+				continue
+			}
+			if lower != r && latin != "" {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+			b.WriteString(latin)
+		}
+	}
+
+	return b.String()
+}
+
+// literal returns ASCII text as a PDF literal string.
+func literal(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return "(" + replacer.Replace(text) + ")"
+}
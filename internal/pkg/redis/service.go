@@ -3,8 +3,11 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type Service struct {
@@ -36,6 +39,20 @@ func (s *Service) Expire(ctx context.Context, key string, expiration time.Durati
 	return s.client.client.Expire(ctx, key, expiration).Err()
 }
 
+// Incr increments the counter under the key and returns its new value.
+func (s *Service) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.client.Incr(ctx, key).Result()
+}
+
+// GetInt returns the counter under the key, or 0 when it does not exist.
+func (s *Service) GetInt(ctx context.Context, key string) (int64, error) {
+	value, err := s.client.client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
+
 func (s *Service) SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	jsonData, err := json.Marshal(value)
 	if err != nil {
@@ -44,6 +61,17 @@ func (s *Service) SetJSON(ctx context.Context, key string, value interface{}, ex
 	return s.Set(ctx, key, jsonData, expiration)
 }
 
+// SetJSONNX stores the value only when the key does not exist yet and
+// reports whether it was stored. It is used as a lock around work that must
+// run once.
+func (s *Service) SetJSONNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return s.client.client.SetNX(ctx, key, jsonData, expiration).Result()
+}
+
 func (s *Service) GetJSON(ctx context.Context, key string, dest interface{}) error {
 	data, err := s.Get(ctx, key)
 	if err != nil {
@@ -0,0 +1,137 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth   = 480
+	chartHeight  = 240
+	chartLeft    = 44
+	chartRight   = 16
+	chartTop     = 32
+	chartBottom  = 28
+	chartGrid    = 4
+	chartDateFmt = "02.01.06"
+	targetColor  = "#28a745"
+	gridColor    = "#e3e7ee"
+)
+
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// LineChart plots Points in time order. A positive Target is drawn as a
+// dashed horizontal line, e.g. the tempo the student is working towards.
+type LineChart struct {
+	Title  string
+	Points []Point
+	Target float64
+	Unit   string
+}
+
+// RenderLineChart renders the chart as a standalone SVG document.
+func RenderLineChart(chart LineChart) string {
+	var b strings.Builder
+	writeHeader(&b, chartWidth, chartHeight, chart.Title)
+
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="13" fill="%s">%s</text>`, chartLeft, textColor, escape(truncate(chart.Title, 56)))
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	bottom := float64(chartHeight - chartBottom)
+
+	maxValue := chart.Target
+	for _, point := range chart.Points {
+		maxValue = math.Max(maxValue, point.Value)
+	}
+	yMax := niceCeil(maxValue * 1.1)
+
+	y := func(value float64) float64 {
+		return bottom - value/yMax*plotH
+	}
+
+	for i := 0; i <= chartGrid; i++ {
+		value := yMax * float64(i) / chartGrid
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="%s"/>`,
+			chartLeft, y(value), chartWidth-chartRight, y(value), gridColor)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" font-size="10" fill="%s" text-anchor="end">%s</text>`,
+			chartLeft-6, y(value)+3, mutedColor, formatValue(value))
+	}
+
+	if chart.Unit != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="%s" text-anchor="end">%s</text>`,
+			chartLeft-6, chartTop-8, mutedColor, escape(chart.Unit))
+	}
+
+	if chart.Target > 0 {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="%s" stroke-dasharray="6 4"/>`,
+			chartLeft, y(chart.Target), chartWidth-chartRight, y(chart.Target), targetColor)
+	}
+
+	if len(chart.Points) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" font-size="12" fill="%s" text-anchor="middle">нет данных</text>`,
+			chartLeft+int(plotW)/2, bottom-plotH/2, mutedColor)
+		b.WriteString(`</svg>`)
+		return b.String()
+	}
+
+	first, last := chart.Points[0].Time, chart.Points[len(chart.Points)-1].Time
+	span := last.Sub(first).Seconds()
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return chartLeft + plotW/2
+		}
+		return chartLeft + t.Sub(first).Seconds()/span*plotW
+	}
+
+	coords := make([]string, 0, len(chart.Points))
+	for _, point := range chart.Points {
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", x(point.Time), y(point.Value)))
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2" stroke-linejoin="round"/>`,
+		strings.Join(coords, " "), accentColor)
+	for _, point := range chart.Points {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`, x(point.Time), y(point.Value), accentColor)
+	}
+
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="%s">%s</text>`,
+		chartLeft, chartHeight-10, mutedColor, first.Format(chartDateFmt))
+	if span > 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" fill="%s" text-anchor="end">%s</text>`,
+			chartWidth-chartRight, chartHeight-10, mutedColor, last.Format(chartDateFmt))
+	}
+
+	b.WriteString(`</svg>`)
+
+	return b.String()
+}
+
+// niceCeil rounds the value up to 1, 2 or 5 times a power of ten, so the grid
+// labels stay round.
+func niceCeil(value float64) float64 {
+	if value <= 0 {
+		return 10
+	}
+
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if step*magnitude >= value {
+			return step * magnitude
+		}
+	}
+
+	return 10 * magnitude
+}
+
+func formatValue(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+
+	return fmt.Sprintf("%.1f", value)
+}
@@ -0,0 +1,113 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"time"
+)
+
+// RenderLineChartPNG draws the chart as a PNG image, for mail clients that do
+// not show SVG. It has the same layout as RenderLineChart but no text, so the
+// title and the labels are left to the surrounding document.
+func RenderLineChartPNG(chart LineChart) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	fillRect(img, img.Bounds(), color.White)
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	bottom := float64(chartHeight - chartBottom)
+
+	maxValue := chart.Target
+	for _, point := range chart.Points {
+		maxValue = math.Max(maxValue, point.Value)
+	}
+	yMax := niceCeil(maxValue * 1.1)
+
+	y := func(value float64) float64 {
+		return bottom - value/yMax*plotH
+	}
+
+	grid := parseColor(gridColor)
+	for i := 0; i <= chartGrid; i++ {
+		value := y(yMax * float64(i) / chartGrid)
+		drawLine(img, chartLeft, value, chartWidth-chartRight, value, 0.5, grid, 0)
+	}
+
+	if chart.Target > 0 {
+		drawLine(img, chartLeft, y(chart.Target), chartWidth-chartRight, y(chart.Target), 0.5, parseColor(targetColor), 6)
+	}
+
+	if len(chart.Points) > 0 {
+		first, last := chart.Points[0].Time, chart.Points[len(chart.Points)-1].Time
+		span := last.Sub(first).Seconds()
+		x := func(t time.Time) float64 {
+			if span <= 0 {
+				return chartLeft + plotW/2
+			}
+			return chartLeft + t.Sub(first).Seconds()/span*plotW
+		}
+
+		accent := parseColor(accentColor)
+		for i := 1; i < len(chart.Points); i++ {
+			prev, point := chart.Points[i-1], chart.Points[i]
+			drawLine(img, x(prev.Time), y(prev.Value), x(point.Time), y(point.Value), 1, accent, 0)
+		}
+		for _, point := range chart.Points {
+			drawDot(img, x(point.Time), y(point.Value), 3, accent)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			img.Set(px, py, c)
+		}
+	}
+}
+
+// drawLine strokes the segment with dots of the given radius. A positive dash
+// leaves gaps of that many pixels, like stroke-dasharray.
+func drawLine(img *image.RGBA, x1, y1, x2, y2, radius float64, c color.Color, dash float64) {
+	length := math.Hypot(x2-x1, y2-y1)
+	steps := int(math.Ceil(length * 2))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		if dash > 0 && math.Mod(t*length, 2*dash) >= dash {
+			continue
+		}
+		drawDot(img, x1+(x2-x1)*t, y1+(y2-y1)*t, radius, c)
+	}
+}
+
+func drawDot(img *image.RGBA, cx, cy, radius float64, c color.Color) {
+	for py := int(math.Floor(cy - radius)); py <= int(math.Ceil(cy+radius)); py++ {
+		for px := int(math.Floor(cx - radius)); px <= int(math.Ceil(cx+radius)); px++ {
+			if math.Hypot(float64(px)+0.5-cx, float64(py)+0.5-cy) <= radius+0.5 {
+				img.Set(px, py, c)
+			}
+		}
+	}
+}
+
+// parseColor reads a "#rrggbb" color of the SVG palette.
+func parseColor(hex string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}
+}
@@ -0,0 +1,157 @@
+package svg
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNiceCeil(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  float64
+	}{
+		{value: 0, want: 10},
+		{value: -3, want: 10},
+		{value: 0.3, want: 0.5},
+		{value: 1, want: 1},
+		{value: 132, want: 200},
+		{value: 420, want: 500},
+		{value: 501, want: 1000},
+	}
+
+	for _, tt := range tests {
+		if got := niceCeil(tt.value); got != tt.want {
+			t.Errorf("niceCeil(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLineChart(t *testing.T) {
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		chart     LineChart
+		want      []string
+		wantNot   []string
+		wantDates int
+	}{
+		{
+			name:    "empty series",
+			chart:   LineChart{Title: "Etude", Target: 120, Unit: "BPM"},
+			want:    []string{"нет данных", `stroke-dasharray="6 4"`},
+			wantNot: []string{"<polyline", "<circle"},
+		},
+		{
+			name:      "single point",
+			chart:     LineChart{Title: "Etude", Points: []Point{{Time: day, Value: 100}}},
+			want:      []string{`<polyline points="254.0,122.0"`, `<circle cx="254.0" cy="122.0"`},
+			wantNot:   []string{"нет данных", "stroke-dasharray"},
+			wantDates: 1,
+		},
+		{
+			name: "series",
+			chart: LineChart{Title: "Etude", Target: 120, Points: []Point{
+				{Time: day, Value: 80},
+				{Time: day.AddDate(0, 0, 1), Value: 100},
+				{Time: day.AddDate(0, 0, 4), Value: 90},
+			}},
+			want:      []string{`<polyline points="44.0,140.0 149.0,122.0 464.0,131.0"`, "01.05.26", "05.05.26"},
+			wantDates: 2,
+		},
+		{
+			name:  "markup in the title and unit",
+			chart: LineChart{Title: `Tom & Jerry <"live">`, Unit: "<b>"},
+			want: []string{
+				`aria-label="Tom &amp; Jerry &lt;&#34;live&#34;&gt;"`,
+				`<title>Tom &amp; Jerry &lt;&#34;live&#34;&gt;</title>`,
+				`>&lt;b&gt;</text>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := RenderLineChart(tt.chart)
+			checkXML(t, doc)
+
+			for _, want := range tt.want {
+				if !strings.Contains(doc, want) {
+					t.Errorf("no %s in %s", want, doc)
+				}
+			}
+			for _, want := range tt.wantNot {
+				if strings.Contains(doc, want) {
+					t.Errorf("unexpected %s in %s", want, doc)
+				}
+			}
+			if got := strings.Count(doc, `y="230"`); got != tt.wantDates {
+				t.Errorf("date labels = %d, want %d", got, tt.wantDates)
+			}
+		})
+	}
+}
+
+func TestRenderLineChartPNG(t *testing.T) {
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	accent := parseColor(accentColor)
+
+	tests := []struct {
+		name  string
+		chart LineChart
+		// wantAccent is a pixel drawn in the accent color, if any.
+		wantAccent []int
+	}{
+		{name: "empty series", chart: LineChart{Target: 120}},
+		{name: "single point", chart: LineChart{Points: []Point{{Time: day, Value: 100}}}, wantAccent: []int{254, 122}},
+		{
+			name: "series",
+			chart: LineChart{Points: []Point{
+				{Time: day, Value: 80},
+				{Time: day.AddDate(0, 0, 4), Value: 90},
+			}},
+			wantAccent: []int{464, 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := RenderLineChartPNG(tt.chart)
+			if err != nil {
+				t.Fatalf("RenderLineChartPNG: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("invalid PNG: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != chartWidth || size.Y != chartHeight {
+				t.Errorf("size = %v, want %dx%d", size, chartWidth, chartHeight)
+			}
+			if got := color.RGBAModel.Convert(img.At(0, 0)); got != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+				t.Errorf("background = %v, want white", got)
+			}
+
+			accents := 0
+			for py := 0; py < chartHeight; py++ {
+				for px := 0; px < chartWidth; px++ {
+					if color.RGBAModel.Convert(img.At(px, py)) == accent {
+						accents++
+					}
+				}
+			}
+			if tt.wantAccent == nil && accents != 0 {
+				t.Errorf("empty chart has %d accent pixels", accents)
+			}
+			if tt.wantAccent != nil {
+				if got := color.RGBAModel.Convert(img.At(tt.wantAccent[0], tt.wantAccent[1])); got != accent {
+					t.Errorf("pixel %v = %v, want the accent color", tt.wantAccent, got)
+				}
+			}
+		})
+	}
+}
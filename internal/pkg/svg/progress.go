@@ -0,0 +1,72 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+const (
+	progressWidth  = 320
+	progressHeight = 52
+	progressBarY   = 26
+	progressBarH   = 18
+	fontFamily     = "Verdana,DejaVu Sans,sans-serif"
+	accentColor    = "#007bff"
+	trackColor     = "#e9eef5"
+	textColor      = "#333"
+	mutedColor     = "#888"
+)
+
+// ProgressBar is a labeled bar filled to Percent, a value from 0 to 100.
+type ProgressBar struct {
+	Label   string
+	Percent float64
+}
+
+// RenderProgressBar renders the bar as a standalone SVG document.
+func RenderProgressBar(bar ProgressBar) string {
+	percent := math.Max(0, math.Min(bar.Percent, 100))
+	if math.IsNaN(percent) {
+		percent = 0
+	}
+	fill := math.Round(float64(progressWidth-2) * percent / 100)
+
+	var b strings.Builder
+	writeHeader(&b, progressWidth, progressHeight, fmt.Sprintf("%s: %.0f%%", bar.Label, percent))
+
+	fmt.Fprintf(&b, `<text x="1" y="16" font-size="13" fill="%s">%s</text>`, textColor, escape(truncate(bar.Label, 36)))
+	fmt.Fprintf(&b, `<rect x="1" y="%d" width="%d" height="%d" rx="4" fill="%s"/>`,
+		progressBarY, progressWidth-2, progressBarH, trackColor)
+	if fill > 0 {
+		fmt.Fprintf(&b, `<rect x="1" y="%d" width="%.0f" height="%d" rx="4" fill="%s"/>`,
+			progressBarY, fill, progressBarH, accentColor)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" font-weight="bold" fill="%s" text-anchor="middle">%.0f%%</text>`,
+		progressWidth/2, progressBarY+13, textColor, percent)
+
+	b.WriteString(`</svg>`)
+
+	return b.String()
+}
+
+func writeHeader(b *strings.Builder, width, height int, title string) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="%s" role="img" aria-label="%s">`,
+		width, height, width, height, fontFamily, escape(title))
+	fmt.Fprintf(b, `<title>%s</title>`, escape(title))
+}
+
+func escape(text string) string {
+	return html.EscapeString(text)
+}
+
+// truncate shortens the text to at most max runes so it fits the image.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max-1]) + "…"
+}
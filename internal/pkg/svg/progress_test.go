@@ -0,0 +1,108 @@
+package svg
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// checkXML fails the test unless the document is well-formed XML.
+func checkXML(t *testing.T, doc string) {
+	t.Helper()
+
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, doc)
+		}
+	}
+}
+
+func TestRenderProgressBar(t *testing.T) {
+	tests := []struct {
+		name      string
+		bar       ProgressBar
+		wantTitle string
+		wantFill  string
+	}{
+		{
+			name:      "in range",
+			bar:       ProgressBar{Label: "Etude", Percent: 42.4},
+			wantTitle: "<title>Etude: 42%</title>",
+			wantFill:  `width="135"`,
+		},
+		{
+			name:      "full",
+			bar:       ProgressBar{Label: "Etude", Percent: 100},
+			wantTitle: "<title>Etude: 100%</title>",
+			wantFill:  `width="318"`,
+		},
+		{
+			name:      "above 100",
+			bar:       ProgressBar{Label: "Etude", Percent: 250},
+			wantTitle: "<title>Etude: 100%</title>",
+			wantFill:  `width="318"`,
+		},
+		{
+			name:      "negative",
+			bar:       ProgressBar{Label: "Etude", Percent: -5},
+			wantTitle: "<title>Etude: 0%</title>",
+		},
+		{
+			name:      "not a number",
+			bar:       ProgressBar{Label: "Etude", Percent: math.NaN()},
+			wantTitle: "<title>Etude: 0%</title>",
+		},
+		{
+			name:      "markup in the label",
+			bar:       ProgressBar{Label: `<script>"Tom & Jerry's"</script>`, Percent: 50},
+			wantTitle: "<title>&lt;script&gt;&#34;Tom &amp; Jerry&#39;s&#34;&lt;/script&gt;: 50%</title>",
+			wantFill:  `width="159"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := RenderProgressBar(tt.bar)
+			checkXML(t, doc)
+
+			if !strings.Contains(doc, tt.wantTitle) {
+				t.Errorf("title not %s in %s", tt.wantTitle, doc)
+			}
+
+			fills := strings.Count(doc, `fill="`+accentColor+`"`)
+			if tt.wantFill == "" && fills != 0 {
+				t.Errorf("empty bar has a fill: %s", doc)
+			}
+			if tt.wantFill != "" && (fills != 1 || !strings.Contains(doc, tt.wantFill)) {
+				t.Errorf("fill not %s in %s", tt.wantFill, doc)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{text: "Etude", max: 5, want: "Etude"},
+		{text: "Etude No. 5", max: 5, want: "Etud…"},
+		{text: "Гаммы терциями", max: 6, want: "Гаммы…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := truncate(tt.text, tt.max); got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+			}
+		})
+	}
+}
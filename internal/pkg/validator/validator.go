@@ -46,6 +46,10 @@ func getValidationMessage(err validator.FieldError) string {
 		return "Неверный формат идентификатора"
 	case "number":
 		return "Должно быть числом"
+	case "timezone":
+		return "Неизвестный часовой пояс"
+	case "gtfield":
+		return fmt.Sprintf("Значение должно быть больше поля %s", strings.ToLower(err.Param()))
 	default:
 		return fmt.Sprintf("Некорректное значение для поля %s", err.Field())
 	}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+ALTER TABLE "links" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "users" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "links" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "version";
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "ensembles" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "owner_id" UUID REFERENCES users(id) ON DELETE CASCADE,
+    "title" VARCHAR(50),
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "ensemble_members" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "ensemble_id" UUID REFERENCES ensembles(id) ON DELETE CASCADE,
+    "user_id" UUID REFERENCES users(id) ON DELETE CASCADE,
+    "task_id" UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    "part_name" VARCHAR(50),
+    "joined_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE ("ensemble_id", "user_id")
+);
+
+CREATE TABLE IF NOT EXISTS "ensemble_invitations" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "ensemble_id" UUID REFERENCES ensembles(id) ON DELETE CASCADE,
+    "invited_by" UUID REFERENCES users(id) ON DELETE CASCADE,
+    "email" VARCHAR(100),
+    "part_name" VARCHAR(50),
+    "target_bpm" INT,
+    "token" VARCHAR(64) UNIQUE NOT NULL,
+    "status" VARCHAR(20) DEFAULT 'pending',
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "ensemble_invitations";
+DROP TABLE IF EXISTS "ensemble_members";
+DROP TABLE IF EXISTS "ensembles";
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "composer" VARCHAR(100);
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "time_signature" VARCHAR(10);
+
+CREATE TABLE IF NOT EXISTS "task_sections" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "task_id" UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    "name" VARCHAR(50),
+    "start_bar" INT,
+    "end_bar" INT,
+    "position" INT
+);
+
+CREATE TABLE IF NOT EXISTS "task_tempo_marks" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "task_id" UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    "bar" INT,
+    "bpm" NUMERIC(6, 2),
+    "text" VARCHAR(100)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "task_tempo_marks";
+DROP TABLE IF EXISTS "task_sections";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "time_signature";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "composer";
+-- +goose StatementEnd
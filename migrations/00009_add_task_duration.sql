@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "duration" INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "duration";
+-- +goose StatementEnd
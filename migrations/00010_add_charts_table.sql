@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "charts" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "task_id" UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    "title" VARCHAR(100) NOT NULL,
+    "key" VARCHAR(10),
+    "content" TEXT NOT NULL,
+    "version" INT NOT NULL DEFAULT 1,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "charts";
+-- +goose StatementEnd
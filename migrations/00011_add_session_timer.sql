@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "status" VARCHAR(20) NOT NULL DEFAULT 'finished';
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "last_heartbeat_at" TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS "session_pauses" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "session_id" UUID REFERENCES sessions(id) ON DELETE CASCADE,
+    "started_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "ended_at" TIMESTAMP WITH TIME ZONE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "session_pauses";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "last_heartbeat_at";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "status";
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "session_segments" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "session_id" UUID REFERENCES sessions(id) ON DELETE CASCADE,
+    "section_id" UUID REFERENCES task_sections(id) ON DELETE SET NULL,
+    "position" INT NOT NULL,
+    "bpm" INT NOT NULL,
+    "duration" INT,
+    "reps" INT,
+    "confidence" INT NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "session_segments";
+-- +goose StatementEnd
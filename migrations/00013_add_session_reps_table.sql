@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "clean_reps_goal" INT;
+
+CREATE TABLE IF NOT EXISTS "session_reps" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "session_id" UUID REFERENCES sessions(id) ON DELETE CASCADE,
+    "position" INT NOT NULL,
+    "clean" BOOLEAN NOT NULL,
+    "played_at" TIMESTAMP WITH TIME ZONE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "session_reps";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "clean_reps_goal";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "client_id" VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS "sessions_task_id_client_id_key" ON "sessions"("task_id", "client_id") WHERE "client_id" IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "sessions_task_id_client_id_key";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "client_id";
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+ALTER TABLE "medias" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+ALTER TABLE "links" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+UPDATE "tasks" SET "updated_at" = "created_at";
+UPDATE "sessions" SET "updated_at" = "created_at";
+UPDATE "medias" SET "updated_at" = "created_at";
+UPDATE "links" SET "updated_at" = "created_at";
+
+CREATE INDEX IF NOT EXISTS "tasks_user_id_updated_at_idx" ON "tasks"("user_id", "updated_at");
+CREATE INDEX IF NOT EXISTS "sessions_updated_at_idx" ON "sessions"("updated_at");
+CREATE INDEX IF NOT EXISTS "medias_updated_at_idx" ON "medias"("updated_at");
+CREATE INDEX IF NOT EXISTS "links_updated_at_idx" ON "links"("updated_at");
+
+CREATE TABLE IF NOT EXISTS "tombstones" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "entity_type" VARCHAR(20) NOT NULL,
+    "entity_id" UUID NOT NULL,
+    "task_id" UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    "deleted_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "tombstones_task_id_deleted_at_idx" ON "tombstones"("task_id", "deleted_at");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "tombstones";
+
+DROP INDEX IF EXISTS "links_updated_at_idx";
+DROP INDEX IF EXISTS "medias_updated_at_idx";
+DROP INDEX IF EXISTS "sessions_updated_at_idx";
+DROP INDEX IF EXISTS "tasks_user_id_updated_at_idx";
+
+ALTER TABLE "links" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "medias" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "updated_at";
+-- +goose StatementEnd
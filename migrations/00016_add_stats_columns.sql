@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "time_zone" VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "tags" TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS "sessions_task_id_start_time_idx" ON "sessions"("task_id", "start_time");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "sessions_task_id_start_time_idx";
+
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "tags";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "time_zone";
+-- +goose StatementEnd
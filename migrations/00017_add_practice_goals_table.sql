@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "practice_goals" (
+    "user_id" UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    "minutes_per_day" INT NOT NULL DEFAULT 0,
+    "days_per_week" INT NOT NULL DEFAULT 0,
+    "sessions_per_week" INT NOT NULL DEFAULT 0,
+    "grace_day" BOOLEAN NOT NULL DEFAULT FALSE,
+    "version" INT NOT NULL DEFAULT 1,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "practice_goals";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "completed_at" TIMESTAMP WITH TIME ZONE;
+
+UPDATE "tasks" SET "completed_at" = "updated_at" WHERE "is_completed" AND "completed_at" IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "completed_at";
+-- +goose StatementEnd
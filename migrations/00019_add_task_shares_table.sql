@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "task_shares" (
+    "task_id" UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    "token" VARCHAR(64) NOT NULL UNIQUE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "task_shares";
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "calendar_feeds" (
+    "user_id" UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    "token" VARCHAR(64) NOT NULL UNIQUE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "calendar_feeds";
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "imports" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "user_id" UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'pending',
+    "filename" VARCHAR(255) NOT NULL,
+    "mapping" JSONB NOT NULL,
+    "data" BYTEA,
+    "total_rows" INT NOT NULL DEFAULT 0,
+    "processed_rows" INT NOT NULL DEFAULT 0,
+    "imported_sessions" INT NOT NULL DEFAULT 0,
+    "skipped_sessions" INT NOT NULL DEFAULT 0,
+    "failed_rows" INT NOT NULL DEFAULT 0,
+    "created_tasks" INT NOT NULL DEFAULT 0,
+    "errors" JSONB NOT NULL DEFAULT '[]',
+    "message" TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    "finished_at" TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS "imports_status_created_at_idx" ON "imports"("status", "created_at");
+CREATE INDEX IF NOT EXISTS "imports_user_id_idx" ON "imports"("user_id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "imports";
+-- +goose StatementEnd
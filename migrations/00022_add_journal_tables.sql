@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "journal_entries" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "user_id" UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "entry_date" DATE NOT NULL,
+    "title" VARCHAR(100) NOT NULL DEFAULT '',
+    "body" TEXT NOT NULL,
+    "mood" SMALLINT,
+    "energy" SMALLINT,
+    "version" INT NOT NULL DEFAULT 1,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    "updated_at" TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "journal_entries_user_id_entry_date_idx" ON "journal_entries"("user_id", "entry_date");
+CREATE INDEX IF NOT EXISTS "journal_entries_search_idx" ON "journal_entries"
+    USING GIN (to_tsvector('russian', "title" || ' ' || "body"));
+
+CREATE TABLE IF NOT EXISTS "journal_entry_tasks" (
+    "entry_id" UUID NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    "task_id" UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    PRIMARY KEY ("entry_id", "task_id")
+);
+
+CREATE INDEX IF NOT EXISTS "journal_entry_tasks_task_id_idx" ON "journal_entry_tasks"("task_id");
+
+CREATE TABLE IF NOT EXISTS "journal_entry_sessions" (
+    "entry_id" UUID NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    "session_id" UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    PRIMARY KEY ("entry_id", "session_id")
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "journal_entry_sessions";
+DROP TABLE IF EXISTS "journal_entry_tasks";
+DROP TABLE IF EXISTS "journal_entries";
+-- +goose StatementEnd
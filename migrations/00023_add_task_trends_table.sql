@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "task_trends" (
+    "task_id" UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    "status" VARCHAR(20) NOT NULL,
+    "reason" TEXT NOT NULL DEFAULT '',
+    "best_bpm" INT NOT NULL DEFAULT 0,
+    "previous_best_bpm" INT NOT NULL DEFAULT 0,
+    "confidence" NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    "previous_confidence" NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    "detected_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    "checked_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    "notified_at" TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS "task_trends_checked_at_idx" ON "task_trends"("checked_at");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "task_trends";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "tempo_aggressiveness" VARCHAR(20) NOT NULL DEFAULT 'moderate';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "users" DROP COLUMN IF EXISTS "tempo_aggressiveness";
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "pain" SMALLINT;
+ALTER TABLE "sessions" ADD COLUMN IF NOT EXISTS "tension" SMALLINT;
+
+CREATE TABLE IF NOT EXISTS "workload_alerts" (
+    "user_id" UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "kind" VARCHAR(20) NOT NULL,
+    "notified_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY ("user_id", "kind")
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "workload_alerts";
+
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "tension";
+ALTER TABLE "sessions" DROP COLUMN IF EXISTS "pain";
+-- +goose StatementEnd
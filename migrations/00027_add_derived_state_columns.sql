@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "completed_by_rule" BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE "task_trends" ADD COLUMN IF NOT EXISTS "window_days" INT NOT NULL DEFAULT 21;
+ALTER TABLE "task_trends" ADD COLUMN IF NOT EXISTS "min_sessions" INT NOT NULL DEFAULT 5;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "task_trends" DROP COLUMN IF EXISTS "min_sessions";
+ALTER TABLE "task_trends" DROP COLUMN IF EXISTS "window_days";
+
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "completed_by_rule";
+-- +goose StatementEnd